@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiKeyCompanyID string
+	apiKeyName      string
+	apiKeyScopes    []string
+	apiKeyTTL       time.Duration
+	apiKeyID        string
+)
+
+func init() {
+	apiKeyCmd.PersistentFlags().StringVar(&apiKeyCompanyID, "company-id", "", "ID of the company the API keys belong to")
+	apiKeyCmd.MarkPersistentFlagRequired("company-id")
+
+	apiKeyIssueCmd.Flags().StringVar(&apiKeyName, "name", "", "Name telling what the API key is used for")
+	apiKeyIssueCmd.Flags().StringSliceVar(&apiKeyScopes, "scope", nil, "Scopes granted to the API key, one of [invoices:read, invoices:write, invoices:approve]")
+	apiKeyIssueCmd.Flags().DurationVar(&apiKeyTTL, "ttl", 90*24*time.Hour, "How long the API key is valid for")
+	apiKeyIssueCmd.MarkFlagRequired("name")
+	apiKeyIssueCmd.MarkFlagRequired("scope")
+
+	apiKeyRevokeCmd.Flags().StringVar(&apiKeyID, "id", "", "ID of the API key to revoke")
+	apiKeyRevokeCmd.MarkFlagRequired("id")
+
+	apiKeyCmd.AddCommand(apiKeyIssueCmd, apiKeyListCmd, apiKeyRevokeCmd)
+	app.AddCommand(apiKeyCmd)
+}
+
+var apiKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Manage API keys for machine clients",
+}
+
+var apiKeyIssueCmd = &cobra.Command{
+	Use:   "issue",
+	Short: "Issue an API key. The key is shown only once",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scopes := make([]domain.Scope, 0, len(apiKeyScopes))
+		for _, scope := range apiKeyScopes {
+			scopes = append(scopes, domain.Scope(scope))
+		}
+		return withAPIKeyService(func(s *internal.APIKeyService) error {
+			key, plain, err := s.Issue(cmd.Context(), apiKeyCompanyID, apiKeyName, scopes, apiKeyTTL)
+			if errors.Is(err, internal.ErrCompanyNotFound) {
+				return fmt.Errorf("company %v doesn't exist", apiKeyCompanyID)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "Issued API key %v (id: %v) expiring at %v\n", key.Name, key.KeyID, key.ExpiresAt.In(s.Clock.Now().Location()).Format(time.RFC3339))
+			fmt.Fprintln(os.Stdout, "Store the key now. It can't be shown again:")
+			fmt.Fprintln(os.Stdout, plain)
+			return nil
+		})
+	},
+}
+
+var apiKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List API keys of the company",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAPIKeyService(func(s *internal.APIKeyService) error {
+			keys, err := s.List(cmd.Context(), apiKeyCompanyID)
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tPREFIX\tSCOPES\tEXPIRES AT\tSTATUS")
+			now := s.Clock.Now()
+			for _, key := range keys {
+				status := "active"
+				switch {
+				case key.RevokedAt != nil:
+					status = "revoked"
+				case !key.Active(now):
+					status = "expired"
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v...\t%v\t%v\t%v\n", key.KeyID, key.Name, key.Prefix, key.Scopes, key.ExpiresAt.In(now.Location()).Format(time.RFC3339), status)
+			}
+			return w.Flush()
+		})
+	},
+}
+
+var apiKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Revoke an API key immediately",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withAPIKeyService(func(s *internal.APIKeyService) error {
+			err := s.Revoke(cmd.Context(), apiKeyCompanyID, apiKeyID)
+			if errors.Is(err, internal.ErrAPIKeyNotFound) {
+				return fmt.Errorf("active API key %v doesn't exist in company %v", apiKeyID, apiKeyCompanyID)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stdout, "Revoked API key %v\n", apiKeyID)
+			return nil
+		})
+	},
+}
+
+func withAPIKeyService(f func(*internal.APIKeyService) error) error {
+	clock, err := loadClock()
+	if err != nil {
+		return err
+	}
+	return withMySQL(func(mysqlClient *internal.MySQL) error {
+		return f(&internal.APIKeyService{Repository: mysqlClient, Clock: clock})
+	})
+}
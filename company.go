@@ -0,0 +1,115 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	companyRequest internal.CompanyRequest
+	companyID      string
+)
+
+func init() {
+	addOutputFlag(companyCmd)
+
+	companyCreateCmd.Flags().StringVar(&companyRequest.Name, "name", "", "Name of the company")
+	companyCreateCmd.Flags().StringVar(&companyRequest.Representative, "representative", "", "Name of the representative")
+	companyCreateCmd.Flags().StringVar(&companyRequest.Phone, "phone", "", "Phone number")
+	companyCreateCmd.Flags().StringVar(&companyRequest.PostalCode, "postal-code", "", "Postal code (NNN-NNNN)")
+	companyCreateCmd.Flags().StringVar(&companyRequest.Address, "address", "", "Address")
+	companyCreateCmd.Flags().StringVar(&companyRequest.RegistrationNumber, "registration-number", "", "Registration number as a qualified invoice issuer (T followed by 13 digits)")
+	companyCreateCmd.Flags().BoolVar(&companyRequest.QualifiedIssuer, "qualified-issuer", false, "Issue qualified invoices carrying the registration number")
+	companyCreateCmd.Flags().StringVar(&companyRequest.DueDatePolicy, "due-date-policy", string(domain.NextBusinessDay), "Policy moving the due dates falling on bank holidays, one of [next, previous]")
+	companyCreateCmd.MarkFlagRequired("name")
+
+	companyShowCmd.Flags().StringVar(&companyID, "id", "", "ID of the company to show")
+	companyShowCmd.MarkFlagRequired("id")
+
+	companyCmd.AddCommand(companyCreateCmd, companyListCmd, companyShowCmd)
+	app.AddCommand(companyCmd)
+}
+
+var companyCmd = &cobra.Command{
+	Use:   "company",
+	Short: "Create and find companies",
+}
+
+var companyHeader = []string{"company_id", "name", "representative", "phone", "postal_code", "address", "registration_number", "qualified_issuer", "due_date_policy"}
+
+func companyRow(company *domain.Company) []string {
+	return []string{
+		company.CompanyID,
+		company.Name,
+		company.Representative,
+		company.Phone,
+		company.PostalCode,
+		company.Address,
+		company.RegistrationNumber,
+		strconv.FormatBool(company.QualifiedIssuer),
+		string(company.DueDatePolicy),
+	}
+}
+
+var companyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a company with the validation of POST /api/companies",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.CompanyService{Repository: mysqlClient}
+			company, err := s.Create(cmd.Context(), companyRequest.Company(""))
+			if err != nil {
+				return err
+			}
+			return printResult(os.Stdout, result{header: companyHeader, rows: [][]string{companyRow(company)}, value: internal.NewCompanyResponse(company)})
+		})
+	},
+}
+
+var companyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the companies",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.CompanyService{Repository: mysqlClient}
+			companies, err := s.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(companies))
+			resp := internal.CompanyListResponse{Companies: make([]internal.CompanyResponse, 0, len(companies))}
+			for _, company := range companies {
+				rows = append(rows, companyRow(&company))
+				resp.Companies = append(resp.Companies, internal.NewCompanyResponse(&company))
+			}
+			return printResult(os.Stdout, result{header: companyHeader, rows: rows, value: resp})
+		})
+	},
+}
+
+var companyShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show a company",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.CompanyService{Repository: mysqlClient}
+			company, err := s.Get(cmd.Context(), companyID)
+			if errors.Is(err, internal.ErrCompanyNotFound) {
+				return fmt.Errorf("company %v doesn't exist", companyID)
+			}
+			if err != nil {
+				return err
+			}
+			return printResult(os.Stdout, result{header: companyHeader, rows: [][]string{companyRow(company)}, value: internal.NewCompanyResponse(company)})
+		})
+	},
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+)
+
+var _ APIKeyRepository = (*MySQL)(nil)
+
+type APIKeyRow struct {
+	KeyID     string
+	CompanyID string
+	Name      string
+	Prefix    string
+	Hash      []byte
+	Scopes    []domain.Scope
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (r *APIKeyRow) apiKey() *domain.APIKey {
+	return &domain.APIKey{
+		KeyID:     r.KeyID,
+		CompanyID: r.CompanyID,
+		Name:      r.Name,
+		Prefix:    r.Prefix,
+		Hash:      r.Hash,
+		Scopes:    r.Scopes,
+		ExpiresAt: r.ExpiresAt,
+		RevokedAt: r.RevokedAt,
+		CreatedAt: r.CreatedAt,
+	}
+}
+
+const apiKeyColumns = "key_id, company_id, name, prefix, key_hash, scopes, expires_at, revoked_at, created_at"
+
+type rowScanner interface {
+	Scan(...any) error
+}
+
+// scanAPIKey scans the apiKeyColumns. Datetimes are stored in UTC and scanned as strings like dates in the invoice table.
+func scanAPIKey(scanner rowScanner) (*APIKeyRow, error) {
+	var (
+		row                  APIKeyRow
+		scopes               string
+		expiresAt, createdAt string
+		revokedAt            sql.NullString
+	)
+	if err := scanner.Scan(&row.KeyID, &row.CompanyID, &row.Name, &row.Prefix, &row.Hash, &scopes, &expiresAt, &revokedAt, &createdAt); err != nil {
+		return nil, err
+	}
+	var err error
+	row.Scopes, err = domain.ParseScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+	row.ExpiresAt, err = time.ParseInLocation(time.DateTime, expiresAt, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	row.CreatedAt, err = time.ParseInLocation(time.DateTime, createdAt, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		t, err := time.ParseInLocation(time.DateTime, revokedAt.String, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+		row.RevokedAt = &t
+	}
+	return &row, nil
+}
+
+// SelectAPIKeyByHash returns the API key whose hash is hash, or nil if it doesn't exist.
+func (s *MySQL) SelectAPIKeyByHash(ctx context.Context, hash []byte) (*APIKeyRow, error) {
+	row, err := scanAPIKey(s.DB.QueryRowContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?;", hash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return row, err
+}
+
+func (s *MySQL) SelectAPIKeys(ctx context.Context, companyID string) ([]APIKeyRow, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE company_id = ? ORDER BY key_id;", companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]APIKeyRow, 0)
+	for rows.Next() {
+		row, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *row)
+	}
+	return results, rows.Err()
+}
+
+// InsertAPIKey returns ErrCompanyNotFound when the owning company doesn't exist.
+func (s *MySQL) InsertAPIKey(ctx context.Context, key *domain.APIKey) (*APIKeyRow, error) {
+	scopes := make([]string, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+	result, err := s.DB.ExecContext(ctx, "INSERT INTO api_keys (company_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?);",
+		key.CompanyID, key.Name, key.Prefix, key.Hash, strings.Join(scopes, ","), key.ExpiresAt.UTC().Format(time.DateTime), key.CreatedAt.UTC().Format(time.DateTime))
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoReferencedRow {
+		return nil, ErrCompanyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &APIKeyRow{
+		KeyID:     strconv.FormatInt(keyID, 10),
+		CompanyID: key.CompanyID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Hash:      key.Hash,
+		Scopes:    key.Scopes,
+		ExpiresAt: key.ExpiresAt,
+		CreatedAt: key.CreatedAt,
+	}, nil
+}
+
+// RevokeAPIKey revokes the API key of the company and reports false if it doesn't exist or is already revoked.
+func (s *MySQL) RevokeAPIKey(ctx context.Context, companyID, keyID string, revokedAt time.Time) (bool, error) {
+	result, err := s.DB.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ? WHERE company_id = ? AND key_id = ? AND revoked_at IS NULL;", revokedAt.UTC().Format(time.DateTime), companyID, keyID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var apiKeyColumnNames = []string{"key_id", "company_id", "name", "prefix", "key_hash", "scopes", "expires_at", "revoked_at", "created_at"}
+
+func TestMySQL_SelectAPIKeyByHash(t *testing.T) {
+	revokedAt := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want *APIKeyRow
+	}{
+		{
+			name: "active key",
+			rows: sqlmock.NewRows(apiKeyColumnNames).AddRow("1", "2", "batch", "sik_abcdefgh", []byte("HASH"), "invoices:read,invoices:write", "2025-01-01 00:00:00", nil, "2024-10-01 09:30:00"),
+			want: &APIKeyRow{
+				KeyID:     "1",
+				CompanyID: "2",
+				Name:      "batch",
+				Prefix:    "sik_abcdefgh",
+				Hash:      []byte("HASH"),
+				Scopes:    []domain.Scope{domain.ScopeInvoicesRead, domain.ScopeInvoicesWrite},
+				ExpiresAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				CreatedAt: time.Date(2024, 10, 1, 9, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "revoked key",
+			rows: sqlmock.NewRows(apiKeyColumnNames).AddRow("1", "2", "batch", "sik_abcdefgh", []byte("HASH"), "invoices:read", "2025-01-01 00:00:00", "2024-11-01 00:00:00", "2024-10-01 09:30:00"),
+			want: &APIKeyRow{
+				KeyID:     "1",
+				CompanyID: "2",
+				Name:      "batch",
+				Prefix:    "sik_abcdefgh",
+				Hash:      []byte("HASH"),
+				Scopes:    []domain.Scope{domain.ScopeInvoicesRead},
+				ExpiresAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+				RevokedAt: &revokedAt,
+				CreatedAt: time.Date(2024, 10, 1, 9, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "key doesn't exist",
+			rows: sqlmock.NewRows(apiKeyColumnNames),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT key_id, company_id, name, prefix, key_hash, scopes, expires_at, revoked_at, created_at FROM api_keys WHERE key_hash = ?;")).WithArgs([]byte("HASH")).WillReturnRows(tt.rows)
+
+			s := &MySQL{DB: db}
+			got, err := s.SelectAPIKeyByHash(context.Background(), []byte("HASH"))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQL_InsertAPIKey(t *testing.T) {
+	key := &domain.APIKey{
+		CompanyID: "1",
+		Name:      "batch",
+		Prefix:    "sik_abcdefgh",
+		Hash:      []byte("HASH"),
+		Scopes:    []domain.Scope{domain.ScopeInvoicesRead, domain.ScopeInvoicesWrite},
+		ExpiresAt: time.Date(2025, 1, 1, 9, 0, 0, 0, time.FixedZone("Asia/Tokyo", 9*60*60)),
+		CreatedAt: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+	}
+	tests := []struct {
+		name    string
+		execErr error
+		want    *APIKeyRow
+		wantErr error
+	}{
+		{
+			name: "no error",
+			want: &APIKeyRow{KeyID: "3", CompanyID: "1", Name: "batch", Prefix: "sik_abcdefgh", Hash: []byte("HASH"), Scopes: key.Scopes, ExpiresAt: key.ExpiresAt, CreatedAt: key.CreatedAt},
+		},
+		{
+			name:    "company doesn't exist",
+			execErr: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"},
+			wantErr: ErrCompanyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO api_keys (company_id, name, prefix, key_hash, scopes, expires_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?);")).
+				WithArgs("1", "batch", "sik_abcdefgh", []byte("HASH"), "invoices:read,invoices:write", "2025-01-01 00:00:00", "2024-10-01 00:00:00")
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(3, 1))
+			}
+
+			s := &MySQL{DB: db}
+			got, err := s.InsertAPIKey(context.Background(), key)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQL_RevokeAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE api_keys SET revoked_at = ? WHERE company_id = ? AND key_id = ? AND revoked_at IS NULL;")).
+		WithArgs("2024-11-01 00:00:00", "1", "3").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := &MySQL{DB: db}
+	got, err := s.RevokeAPIKey(context.Background(), "1", "3", time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.True(t, got)
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+var ErrAPIKeyNotFound = errors.New("api key not found")
+
+type APIKeyRepository interface {
+	SelectAPIKeyByHash(context.Context, []byte) (*APIKeyRow, error)
+	SelectAPIKeys(context.Context, string) ([]APIKeyRow, error)
+	InsertAPIKey(context.Context, *domain.APIKey) (*APIKeyRow, error)
+	RevokeAPIKey(context.Context, string, string, time.Time) (bool, error)
+}
+
+type APIKeyService struct {
+	Repository APIKeyRepository
+	// Clock tells when the keys are issued, revoked and expired. Nil means the system clock.
+	Clock Clock
+}
+
+// Issue creates an API key of the company valid for ttl. The returned plain key can't be recovered later.
+func (s *APIKeyService) Issue(ctx context.Context, companyID, name string, scopes []domain.Scope, ttl time.Duration) (*domain.APIKey, string, error) {
+	now := currentTime(s.Clock)
+	key, plain, err := domain.NewAPIKey(companyID, name, scopes, now, now.Add(ttl))
+	if err != nil {
+		return nil, "", err
+	}
+	row, err := s.Repository.InsertAPIKey(ctx, key)
+	if errors.Is(err, ErrCompanyNotFound) {
+		return nil, "", err
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("insert error: %w", err)
+	}
+	return row.apiKey(), plain, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, companyID string) ([]domain.APIKey, error) {
+	rows, err := s.Repository.SelectAPIKeys(ctx, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("api key service error: %w", err)
+	}
+	keys := make([]domain.APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, *row.apiKey())
+	}
+	return keys, nil
+}
+
+// Revoke disables the API key of the company immediately. ErrAPIKeyNotFound is returned when it doesn't exist
+// or has already been revoked.
+func (s *APIKeyService) Revoke(ctx context.Context, companyID, keyID string) error {
+	revoked, err := s.Repository.RevokeAPIKey(ctx, companyID, keyID, currentTime(s.Clock))
+	if err != nil {
+		return fmt.Errorf("revoke error: %w", err)
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate returns the API key matching plain, or ErrUnauthorized when it's unknown, revoked or expired.
+func (s *APIKeyService) Authenticate(ctx context.Context, plain string) (*domain.APIKey, error) {
+	row, err := s.Repository.SelectAPIKeyByHash(ctx, domain.HashAPIKey(plain))
+	if err != nil {
+		return nil, fmt.Errorf("api key service error: %w", err)
+	}
+	if row == nil {
+		return nil, ErrUnauthorized
+	}
+	key := row.apiKey()
+	if !key.Active(currentTime(s.Clock)) {
+		return nil, ErrUnauthorized
+	}
+	return key, nil
+}
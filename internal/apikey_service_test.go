@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeAPIKeyRepository struct {
+	APIKeyRepository
+	rows     map[string]*APIKeyRow
+	inserted *domain.APIKey
+	revoked  bool
+	err      error
+}
+
+func (r *fakeAPIKeyRepository) SelectAPIKeyByHash(_ context.Context, hash []byte) (*APIKeyRow, error) {
+	return r.rows[string(hash)], r.err
+}
+
+func (r *fakeAPIKeyRepository) InsertAPIKey(_ context.Context, key *domain.APIKey) (*APIKeyRow, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.inserted = key
+	return &APIKeyRow{KeyID: "1", CompanyID: key.CompanyID, Name: key.Name, Prefix: key.Prefix, Hash: key.Hash, Scopes: key.Scopes, ExpiresAt: key.ExpiresAt, CreatedAt: key.CreatedAt}, nil
+}
+
+func (r *fakeAPIKeyRepository) RevokeAPIKey(context.Context, string, string, time.Time) (bool, error) {
+	return r.revoked, r.err
+}
+
+func TestAPIKeyService_Issue(t *testing.T) {
+	repository := &fakeAPIKeyRepository{}
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	s := &APIKeyService{Repository: repository, Clock: fixedClock(now)}
+	key, plain, err := s.Issue(context.Background(), "1", "batch", []domain.Scope{domain.ScopeInvoicesWrite}, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, "1", key.KeyID)
+	assert.Equal(t, domain.HashAPIKey(plain), repository.inserted.Hash)
+	assert.Equal(t, now, key.CreatedAt)
+	assert.Equal(t, now.Add(time.Hour), key.ExpiresAt)
+
+	_, _, err = s.Issue(context.Background(), "1", "batch", []domain.Scope{"invoices:delete"}, time.Hour)
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+
+	s = &APIKeyService{Repository: &fakeAPIKeyRepository{err: ErrCompanyNotFound}}
+	_, _, err = s.Issue(context.Background(), "999", "batch", []domain.Scope{domain.ScopeInvoicesWrite}, time.Hour)
+	assert.ErrorIs(t, err, ErrCompanyNotFound)
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	revokedAt := now.Add(-time.Minute)
+	rows := map[string]*APIKeyRow{
+		string(domain.HashAPIKey("ACTIVE")):  {KeyID: "1", CompanyID: "1", ExpiresAt: now.Add(time.Hour)},
+		string(domain.HashAPIKey("EXPIRED")): {KeyID: "2", CompanyID: "1", ExpiresAt: now},
+		string(domain.HashAPIKey("REVOKED")): {KeyID: "3", CompanyID: "1", ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt},
+	}
+	tests := []struct {
+		name    string
+		plain   string
+		wantID  string
+		wantErr error
+	}{
+		{name: "active key", plain: "ACTIVE", wantID: "1"},
+		{name: "expired key", plain: "EXPIRED", wantErr: ErrUnauthorized},
+		{name: "revoked key", plain: "REVOKED", wantErr: ErrUnauthorized},
+		{name: "unknown key", plain: "UNKNOWN", wantErr: ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &APIKeyService{Repository: &fakeAPIKeyRepository{rows: rows}, Clock: fixedClock(now)}
+			got, err := s.Authenticate(context.Background(), tt.plain)
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.wantID, got.KeyID)
+			}
+		})
+	}
+
+	s := &APIKeyService{Repository: &fakeAPIKeyRepository{err: errors.New("this is test")}}
+	_, err := s.Authenticate(context.Background(), "ACTIVE")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUnauthorized)
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	s := &APIKeyService{Repository: &fakeAPIKeyRepository{revoked: true}}
+	assert.NoError(t, s.Revoke(context.Background(), "1", "1"))
+
+	s = &APIKeyService{Repository: &fakeAPIKeyRepository{revoked: false}}
+	assert.ErrorIs(t, s.Revoke(context.Background(), "1", "1"), ErrAPIKeyNotFound)
+}
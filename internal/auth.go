@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+type (
+	userKey   struct{}
+	apiKeyKey struct{}
+)
+
+// WithUser returns a copy of ctx that carries the authenticated user.
+func WithUser(ctx context.Context, user *domain.User) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// UserFromContext returns the authenticated user stored by WithUser.
+func UserFromContext(ctx context.Context) (*domain.User, bool) {
+	user, ok := ctx.Value(userKey{}).(*domain.User)
+	return user, ok
+}
+
+// WithAPIKey returns a copy of ctx that carries the API key the request was authenticated with.
+func WithAPIKey(ctx context.Context, key *domain.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, key)
+}
+
+// APIKeyFromContext returns the API key stored by WithAPIKey.
+func APIKeyFromContext(ctx context.Context) (*domain.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyKey{}).(*domain.APIKey)
+	return key, ok
+}
+
+// CompanyIDFromContext returns the company of the authenticated user or API key.
+func CompanyIDFromContext(ctx context.Context) (string, bool) {
+	if user, ok := UserFromContext(ctx); ok {
+		return user.CompanyID, true
+	}
+	if key, ok := APIKeyFromContext(ctx); ok {
+		return key.CompanyID, true
+	}
+	return "", false
+}
+
+// scopeAllowed reports whether the request may use scope. Scopes restrict API keys only; users aren't restricted by them.
+func scopeAllowed(ctx context.Context, scope domain.Scope) bool {
+	key, ok := APIKeyFromContext(ctx)
+	return !ok || key.HasScope(scope)
+}
+
+// authorizeCompany returns ErrForbidden when the authenticated user belongs to a company other than companyID.
+// Requests without an authenticated user, e.g. when authentication is disabled, are not restricted.
+func authorizeCompany(ctx context.Context, companyID string) error {
+	authenticated, ok := CompanyIDFromContext(ctx)
+	if ok && authenticated != companyID {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// companyIDOrDefault returns companyID, or the company of the authenticated user when it's empty.
+func companyIDOrDefault(ctx context.Context, companyID string) string {
+	if companyID != "" {
+		return companyID
+	}
+	authenticated, _ := CompanyIDFromContext(ctx)
+	return authenticated
+}
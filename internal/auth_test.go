@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthorizeCompany(t *testing.T) {
+	authenticated := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1"})
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		companyID string
+		wantErr   error
+	}{
+		{
+			name:      "same company",
+			ctx:       authenticated,
+			companyID: "1",
+		},
+		{
+			name:      "another company",
+			ctx:       authenticated,
+			companyID: "2",
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "empty company",
+			ctx:       authenticated,
+			companyID: "",
+			wantErr:   ErrForbidden,
+		},
+		{
+			name:      "unauthenticated",
+			ctx:       context.Background(),
+			companyID: "2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantErr, authorizeCompany(tt.ctx, tt.companyID))
+		})
+	}
+}
+
+func TestCompanyIDOrDefault(t *testing.T) {
+	authenticated := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1"})
+	assert.Equal(t, "1", companyIDOrDefault(authenticated, ""))
+	assert.Equal(t, "2", companyIDOrDefault(authenticated, "2"))
+	assert.Equal(t, "", companyIDOrDefault(context.Background(), ""))
+}
@@ -0,0 +1,145 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var _ BatchInserter = (*MySQL)(nil)
+
+// batchInsertRows is the number of rows inserted by a statement, which keeps the placeholders of a statement
+// well below the limit of 65535.
+const batchInsertRows = 1000
+
+// The errors of the values of a row, which reject the row alone in BestEffort mode.
+const (
+	errDataTooLong             = 1406
+	errDataOutOfRange          = 1264
+	errCheckConstraintViolated = 3819
+)
+
+// rejectedRow returns the error of the row rejected by err, or nil when err isn't caused by the values of the row.
+func rejectedRow(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return nil
+	}
+	switch mysqlErr.Number {
+	case errNoReferencedRow, errDataTooLong, errDataOutOfRange, errCheckConstraintViolated:
+		return fmt.Errorf("%w: %v", ErrInvoiceRejected, mysqlErr.Message)
+	}
+	return nil
+}
+
+// InsertBatch inserts the rows and their lines in a single transaction with multi-row inserts and returns them
+// with their IDs in the order of rows.
+// In BestEffort mode, the rows are inserted one by one after a multi-row insert is rejected by a constraint, so that
+// only the rows violating it fail. Their errors wrapping ErrInvoiceRejected are set at their indexes of errs, and
+// the other rows are committed.
+// A multi-row insert allocates consecutive IDs starting from LastInsertId, which CheckBatchInsert verifies.
+func (s *MySQL) InsertBatch(ctx context.Context, rows []Row, mode BatchMode) ([]Row, []error, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	if mode == BestEffort {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT batch;"); err != nil {
+			return nil, nil, err
+		}
+	}
+	var errs []error
+	inserted, err := insertInvoices(ctx, tx, rows)
+	if mode == BestEffort && rejectedRow(err) != nil {
+		if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT batch;"); err != nil {
+			return nil, nil, err
+		}
+		inserted, errs, err = insertEach(ctx, tx, rows)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+	return inserted, errs, nil
+}
+
+// CheckBatchInsert returns an error unless the server allocates consecutive IDs to the rows of a multi-row insert,
+// which InsertBatch relies on. That requires innodb_autoinc_lock_mode to be 0 or 1, since the default 2 of MySQL 8
+// may interleave the IDs of concurrent inserts, and auto_increment_increment to be 1.
+func (s *MySQL) CheckBatchInsert(ctx context.Context) error {
+	var lockMode, increment int
+	if err := s.DB.QueryRowContext(ctx, "SELECT @@innodb_autoinc_lock_mode, @@auto_increment_increment;").Scan(&lockMode, &increment); err != nil {
+		return err
+	}
+	if lockMode != 0 && lockMode != 1 {
+		return fmt.Errorf("innodb_autoinc_lock_mode must be 0 or 1 for batch inserts, but got %d", lockMode)
+	}
+	if increment != 1 {
+		return fmt.Errorf("auto_increment_increment must be 1 for batch inserts, but got %d", increment)
+	}
+	return nil
+}
+
+// insertInvoices inserts the rows and their lines with multi-row inserts in tx.
+func insertInvoices(ctx context.Context, tx *sql.Tx, rows []Row) ([]Row, error) {
+	inserted := make([]Row, 0, len(rows))
+	for start := 0; start < len(rows); start += batchInsertRows {
+		chunk := rows[start:min(start+batchInsertRows, len(rows))]
+		placeholders := make([]string, 0, len(chunk))
+		args := make([]any, 0, len(chunk)*14)
+		for _, row := range chunk {
+			placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+			args = append(args, row.CompanyID, row.PartnerID, row.IssueDate, row.Amount, row.Fee, row.FeeRate, row.Tax, row.TaxRate, row.WithholdingTax, row.Total, row.DueDate, row.Status, row.TaxAmounts, row.RegistrationNumber)
+		}
+		result, err := tx.ExecContext(ctx, "INSERT INTO invoice (company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number) VALUES "+strings.Join(placeholders, ", ")+";", args...)
+		if err != nil {
+			return nil, err
+		}
+		firstID, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range chunk {
+			row.InvoiceID = strconv.FormatInt(firstID+int64(i), 10)
+			inserted = append(inserted, row)
+		}
+	}
+	if err := insertLines(ctx, tx, inserted); err != nil {
+		return nil, err
+	}
+	return inserted, nil
+}
+
+// insertEach inserts the rows one by one in their own savepoints in tx. A row rejected by a constraint is rolled back
+// with its lines, and its error is set at its index of errs instead of failing the others.
+func insertEach(ctx context.Context, tx *sql.Tx, rows []Row) ([]Row, []error, error) {
+	inserted := make([]Row, len(rows))
+	errs := make([]error, len(rows))
+	for i := range rows {
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT invoice;"); err != nil {
+			return nil, nil, err
+		}
+		row, err := insertInvoices(ctx, tx, rows[i:i+1])
+		if rejected := rejectedRow(err); rejected != nil {
+			if _, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT invoice;"); err != nil {
+				return nil, nil, err
+			}
+			errs[i] = rejected
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		inserted[i] = row[0]
+	}
+	return inserted, errs, nil
+}
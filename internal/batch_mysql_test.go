@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQL_InsertBatch(t *testing.T) {
+	issueDate := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	dueDate := time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+	rows := []Row{
+		{CompanyID: "1", PartnerID: "1", IssueDate: issueDate, Amount: 10000, Fee: 400, FeeRate: domain.MustParseRate("0.04"), Tax: 40, TaxRate: domain.MustParseRate("0.1"), Total: 10440, DueDate: dueDate, Status: "unprocessed", TaxAmounts: domain.TaxAmounts{{Rate: domain.MustParseRate("0.1"), Taxable: 400, Tax: 40}}, RegistrationNumber: "T7000012050002"},
+		{CompanyID: "1", PartnerID: "2", IssueDate: issueDate, Amount: 5000, Fee: 200, FeeRate: domain.MustParseRate("0.04"), Tax: 20, TaxRate: domain.MustParseRate("0.1"), WithholdingTax: 510, Total: 4710, DueDate: dueDate, Status: "paid", TaxAmounts: domain.TaxAmounts{{Rate: domain.MustParseRate("0.1"), Taxable: 200, Tax: 20}}},
+	}
+	const query = "INSERT INTO invoice (company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+
+	t.Run("rows get consecutive IDs", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(query)).
+			WithArgs("1", "1", issueDate, 10000, 400, "0.04", 40, "0.1", 0, 10440, dueDate, "unprocessed", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002", "1", "2", issueDate, 5000, 200, "0.04", 20, "0.1", 510, 4710, dueDate, "paid", `[{"rate":0.1,"taxable":200,"tax":20}]`, "").
+			WillReturnResult(sqlmock.NewResult(7, 2))
+		mock.ExpectCommit()
+
+		s := &MySQL{DB: db}
+		got, errs, err := s.InsertBatch(context.Background(), rows, AllOrNothing)
+		require.NoError(t, err)
+		assert.Nil(t, errs)
+		require.Len(t, got, 2)
+		assert.Equal(t, "7", got[0].InvoiceID)
+		assert.Equal(t, "8", got[1].InvoiceID)
+		assert.Equal(t, 4710, got[1].Total)
+		assert.Empty(t, rows[0].InvoiceID, "the given rows shouldn't be modified")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lines are inserted with the IDs of their invoices", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		withLines := []Row{rows[0], rows[1]}
+		withLines[1].Lines = []domain.InvoiceLine{
+			{Description: "お弁当", Quantity: 2, UnitPrice: 1000, TaxCategory: domain.ReducedTax},
+			{Description: "配送料", Quantity: 1, UnitPrice: 3000, TaxCategory: domain.StandardTax},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnResult(sqlmock.NewResult(7, 2))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO invoice_lines (invoice_id, line_no, description, quantity, unit_price, tax_category) VALUES (?, ?, ?, ?, ?, ?), (?, ?, ?, ?, ?, ?);")).
+			WithArgs("8", 1, "お弁当", 2, 1000, "reduced", "8", 2, "配送料", 1, 3000, "standard").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		s := &MySQL{DB: db}
+		got, _, err := s.InsertBatch(context.Background(), withLines, AllOrNothing)
+		require.NoError(t, err)
+		assert.Equal(t, withLines[1].Lines, got[1].Lines)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed insert is rolled back", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(errors.New("this is test"))
+		mock.ExpectRollback()
+
+		s := &MySQL{DB: db}
+		_, _, err = s.InsertBatch(context.Background(), rows, AllOrNothing)
+		assert.EqualError(t, err, "this is test")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	const rowQuery = "INSERT INTO invoice (company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);"
+	checkViolated := &mysql.MySQLError{Number: 3819, Message: "Check constraint 'total_check' is violated."}
+
+	t.Run("best effort rejects only the rows violating constraints", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT batch;")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(checkViolated)
+		mock.ExpectExec(regexp.QuoteMeta("ROLLBACK TO SAVEPOINT batch;")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT invoice;")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(rowQuery)).WithArgs("1", "1", issueDate, 10000, 400, "0.04", 40, "0.1", 0, 10440, dueDate, "unprocessed", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002").
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT invoice;")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(rowQuery)).WithArgs("1", "2", issueDate, 5000, 200, "0.04", 20, "0.1", 510, 4710, dueDate, "paid", `[{"rate":0.1,"taxable":200,"tax":20}]`, "").
+			WillReturnError(checkViolated)
+		mock.ExpectExec(regexp.QuoteMeta("ROLLBACK TO SAVEPOINT invoice;")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		s := &MySQL{DB: db}
+		got, errs, err := s.InsertBatch(context.Background(), rows, BestEffort)
+		require.NoError(t, err)
+		require.Len(t, got, 2)
+		require.Len(t, errs, 2)
+		assert.Equal(t, "7", got[0].InvoiceID)
+		assert.NoError(t, errs[0])
+		assert.ErrorIs(t, errs[1], ErrInvoiceRejected)
+		assert.EqualError(t, errs[1], "invoice was rejected by the database: Check constraint 'total_check' is violated.")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("best effort fails on errors other than constraint violations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("SAVEPOINT batch;")).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(errors.New("this is test"))
+		mock.ExpectRollback()
+
+		s := &MySQL{DB: db}
+		_, _, err = s.InsertBatch(context.Background(), rows, BestEffort)
+		assert.EqualError(t, err, "this is test")
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("all or nothing fails on constraint violations", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(query)).WillReturnError(checkViolated)
+		mock.ExpectRollback()
+
+		s := &MySQL{DB: db}
+		_, _, err = s.InsertBatch(context.Background(), rows, AllOrNothing)
+		assert.Equal(t, checkViolated, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestMySQL_CheckBatchInsert(t *testing.T) {
+	tests := []struct {
+		name      string
+		lockMode  int
+		increment int
+		wantErr   string
+	}{
+		{name: "consecutive", lockMode: 1, increment: 1},
+		{name: "traditional", lockMode: 0, increment: 1},
+		{name: "interleaved", lockMode: 2, increment: 1, wantErr: "innodb_autoinc_lock_mode must be 0 or 1 for batch inserts, but got 2"},
+		{name: "increment", lockMode: 1, increment: 2, wantErr: "auto_increment_increment must be 1 for batch inserts, but got 2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT @@innodb_autoinc_lock_mode, @@auto_increment_increment;")).
+				WillReturnRows(sqlmock.NewRows([]string{"@@innodb_autoinc_lock_mode", "@@auto_increment_increment"}).AddRow(tt.lockMode, tt.increment))
+
+			s := &MySQL{DB: db}
+			err = s.CheckBatchInsert(context.Background())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+// BatchMode decides what happens to the valid invoices of a batch containing invalid ones.
+type BatchMode string
+
+const (
+	// AllOrNothing creates no invoice unless every invoice of the batch is valid.
+	AllOrNothing BatchMode = "all_or_nothing"
+	// BestEffort creates the valid invoices and reports the invalid ones.
+	BestEffort BatchMode = "best_effort"
+)
+
+func (m BatchMode) Valid() bool {
+	return m == AllOrNothing || m == BestEffort
+}
+
+// ErrInvoiceRejected is the error of an invoice of a BestEffort batch violating a constraint of the database.
+var ErrInvoiceRejected = errors.New("invoice was rejected by the database")
+
+// BatchInserter inserts the rows of a batch in a single transaction and returns them in their order. In BestEffort
+// mode, the errors of the rows rejected by the database are set at their indexes of errs instead of failing the batch.
+// errs is nil when no row is rejected.
+type BatchInserter interface {
+	InsertBatch(context.Context, []Row, BatchMode) ([]Row, []error, error)
+}
+
+type BatchInserterFunc func(context.Context, []Row, BatchMode) ([]Row, []error, error)
+
+func (f BatchInserterFunc) InsertBatch(ctx context.Context, rows []Row, mode BatchMode) ([]Row, []error, error) {
+	return f(ctx, rows, mode)
+}
+
+// BatchItem is an invoice to create in a batch. Err is set by the caller when the request of the item is invalid,
+// so that it's reported in its place.
+type BatchItem struct {
+	CompanyID string
+	PartnerID string
+	IssueDate time.Time
+	Amount    int
+	Lines     []domain.InvoiceLine
+	DueDate   time.Time
+	Status    string
+	Err       error
+}
+
+// BatchResult is the outcome of a BatchItem. Invoice is set when it has been created and Err when it's invalid.
+// Neither is set for the valid items of a failed AllOrNothing batch.
+type BatchResult struct {
+	Invoice *domain.Invoice
+	Err     error
+}
+
+// isItemError reports whether err is caused by the item itself rather than by the server.
+func isItemError(err error) bool {
+	return errors.Is(err, ErrForbidden) || errors.Is(err, ErrCompanyNotFound) || errors.Is(err, ErrPartnerNotFound) ||
+		errors.Is(err, domain.ErrNonQualifiedInvoice)
+}
+
+// RegisterBatch creates the invoices of the items in a single transaction and returns the results in the order of
+// the items. An item fails with the errors of Register, or ErrInvoiceRejected in BestEffort mode. An error is
+// returned only when the batch couldn't be processed at all, in which case no invoice is created.
+func (s *RegisterService) RegisterBatch(ctx context.Context, items []BatchItem, mode BatchMode) ([]BatchResult, error) {
+	results, rows, indexes, err := s.prepareBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 || (len(rows) < len(items) && mode == AllOrNothing) {
+		return results, nil
+	}
+	inserted, errs, err := s.BatchInserter.InsertBatch(ctx, rows, mode)
+	if err != nil {
+		return nil, fmt.Errorf("insert error: %w", err)
+	}
+	for j, row := range inserted {
+		if errs != nil && errs[j] != nil {
+			results[indexes[j]].Err = errs[j]
+			continue
+		}
+		results[indexes[j]].Invoice = row.invoice()
+	}
+	return results, nil
+}
+
+// ValidateBatch checks the items like RegisterBatch without creating the invoices. Only Err is set in the results.
+func (s *RegisterService) ValidateBatch(ctx context.Context, items []BatchItem) ([]BatchResult, error) {
+	results, _, _, err := s.prepareBatch(ctx, items)
+	return results, err
+}
+
+// prepareBatch returns the results of the invalid items, and the rows to insert for the valid ones with the indexes
+// of their items.
+func (s *RegisterService) prepareBatch(ctx context.Context, items []BatchItem) ([]BatchResult, []Row, []int, error) {
+	results := make([]BatchResult, len(items))
+	lookup := &invoiceLookup{}
+	rows := make([]Row, 0, len(items))
+	indexes := make([]int, 0, len(items))
+	for i, item := range items {
+		if item.Err != nil {
+			results[i].Err = item.Err
+			continue
+		}
+		invoice, err := s.prepare(ctx, lookup, item.CompanyID, item.PartnerID, item.IssueDate, item.Amount, item.Lines, item.DueDate, item.Status)
+		if isItemError(err) {
+			results[i].Err = err
+			continue
+		}
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		rows = append(rows, Row{
+			CompanyID:          item.CompanyID,
+			PartnerID:          invoice.PartnerID,
+			IssueDate:          invoice.IssueDate,
+			Amount:             invoice.Amount,
+			Fee:                invoice.Fee,
+			FeeRate:            invoice.FeeRate,
+			Tax:                invoice.Tax,
+			TaxRate:            invoice.TaxRate,
+			WithholdingTax:     invoice.WithholdingTax,
+			Total:              invoice.Total,
+			DueDate:            invoice.DueDate,
+			Status:             string(invoice.Status),
+			TaxAmounts:         invoice.TaxAmounts,
+			RegistrationNumber: invoice.RegistrationNumber,
+			Lines:              invoice.Lines,
+		})
+		indexes = append(indexes, i)
+	}
+	return results, rows, indexes, nil
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterService_RegisterBatch(t *testing.T) {
+	issueDate := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	dueDate := time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+	invalid := errors.New("'partner_id' mustn't be empty")
+	items := []BatchItem{
+		{CompanyID: "1", PartnerID: "1", IssueDate: issueDate, Amount: 10000, DueDate: dueDate, Status: "unprocessed"},
+		{CompanyID: "1", PartnerID: "2", IssueDate: issueDate, Amount: 5000, DueDate: dueDate, Status: "unprocessed"},
+		{Err: invalid},
+		{CompanyID: "1", PartnerID: "3", IssueDate: issueDate, Amount: 5000, DueDate: dueDate, Status: "unprocessed"},
+	}
+	partners := PartnerSelectorFunc(func(_ context.Context, partnerID string) (*PartnerRow, error) {
+		if partnerID == "3" {
+			return &PartnerRow{PartnerID: partnerID, CompanyID: "2"}, nil
+		}
+		return &PartnerRow{PartnerID: partnerID, CompanyID: "1"}, nil
+	})
+	inserter := BatchInserterFunc(func(_ context.Context, rows []Row, _ BatchMode) ([]Row, []error, error) {
+		for i := range rows {
+			rows[i].InvoiceID = strconv.Itoa(i + 10)
+		}
+		return rows, nil, nil
+	})
+	rates := RateSelectorFunc(func(context.Context, string, time.Time) (*RateRow, error) {
+		return nil, nil
+	})
+
+	t.Run("best effort creates the valid invoices", func(t *testing.T) {
+		s := &RegisterService{BatchInserter: inserter, RateSelector: rates, CompanySelector: existingCompany, PartnerSelector: partners}
+		got, err := s.RegisterBatch(context.Background(), items, BestEffort)
+		require.NoError(t, err)
+		require.Len(t, got, 4)
+		assert.Equal(t, "10", got[0].Invoice.InvoiceID)
+		assert.Equal(t, 10440, got[0].Invoice.Total)
+		assert.Equal(t, domain.TaxAmounts{{Rate: domain.MustParseRate("0.1"), Taxable: 400, Tax: 40}}, got[0].Invoice.TaxAmounts)
+		assert.Equal(t, "11", got[1].Invoice.InvoiceID)
+		assert.Equal(t, "2", got[1].Invoice.PartnerID)
+		assert.Equal(t, BatchResult{Err: invalid}, got[2])
+		assert.Equal(t, BatchResult{Err: ErrPartnerNotFound}, got[3])
+	})
+
+	t.Run("all or nothing creates nothing when an invoice is invalid", func(t *testing.T) {
+		s := &RegisterService{BatchInserter: BatchInserterFunc(func(context.Context, []Row, BatchMode) ([]Row, []error, error) {
+			t.Error("invoices mustn't be inserted")
+			return nil, nil, nil
+		}), RateSelector: rates, CompanySelector: existingCompany, PartnerSelector: partners}
+		got, err := s.RegisterBatch(context.Background(), items, AllOrNothing)
+		require.NoError(t, err)
+		assert.Equal(t, []BatchResult{{}, {}, {Err: invalid}, {Err: ErrPartnerNotFound}}, got)
+	})
+
+	t.Run("all or nothing creates every invoice when they are valid", func(t *testing.T) {
+		s := &RegisterService{BatchInserter: inserter, RateSelector: rates, CompanySelector: existingCompany, PartnerSelector: partners}
+		got, err := s.RegisterBatch(context.Background(), items[:2], AllOrNothing)
+		require.NoError(t, err)
+		assert.Equal(t, "10", got[0].Invoice.InvoiceID)
+		assert.Equal(t, "11", got[1].Invoice.InvoiceID)
+	})
+
+	t.Run("company is read once", func(t *testing.T) {
+		calls := 0
+		companies := CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+			calls++
+			return &CompanyRow{CompanyID: companyID}, nil
+		})
+		s := &RegisterService{BatchInserter: inserter, RateSelector: rates, CompanySelector: companies, PartnerSelector: partners}
+		_, err := s.RegisterBatch(context.Background(), items, BestEffort)
+		require.NoError(t, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("another company is forbidden", func(t *testing.T) {
+		ctx := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "2"})
+		s := &RegisterService{BatchInserter: inserter, RateSelector: rates, CompanySelector: existingCompany, PartnerSelector: partners}
+		got, err := s.RegisterBatch(ctx, items[:1], BestEffort)
+		require.NoError(t, err)
+		assert.Equal(t, []BatchResult{{Err: ErrForbidden}}, got)
+	})
+
+	t.Run("best effort reports the invoices rejected by the database", func(t *testing.T) {
+		rejected := fmt.Errorf("%w: Check constraint 'total_check' is violated.", ErrInvoiceRejected)
+		s := &RegisterService{BatchInserter: BatchInserterFunc(func(_ context.Context, rows []Row, mode BatchMode) ([]Row, []error, error) {
+			assert.Equal(t, BestEffort, mode)
+			rows[1].InvoiceID = "11"
+			return []Row{{}, rows[1]}, []error{rejected, nil}, nil
+		}), RateSelector: rates, CompanySelector: existingCompany, PartnerSelector: partners}
+		got, err := s.RegisterBatch(context.Background(), items[:2], BestEffort)
+		require.NoError(t, err)
+		assert.Equal(t, BatchResult{Err: rejected}, got[0])
+		assert.Equal(t, "11", got[1].Invoice.InvoiceID)
+	})
+
+	t.Run("inserter returns error", func(t *testing.T) {
+		s := &RegisterService{BatchInserter: BatchInserterFunc(func(context.Context, []Row, BatchMode) ([]Row, []error, error) {
+			return nil, nil, errors.New("this is test")
+		}), RateSelector: rates, CompanySelector: existingCompany, PartnerSelector: partners}
+		_, err := s.RegisterBatch(context.Background(), items, BestEffort)
+		assert.Equal(t, fmt.Errorf("insert error: %w", errors.New("this is test")), err)
+	})
+}
@@ -0,0 +1,128 @@
+package calendar
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultHolidays is the national holidays (国民の祝日) in the format of syukujitsu.csv published by the Cabinet Office
+// at https://www8.cao.go.jp/chosei/shukujitsu/gaiyou.html, converted to UTF-8. Update it when the holidays of the
+// following year are announced every February: download syukujitsu.csv, convert it with
+// `iconv -f SHIFT_JIS -t UTF-8 syukujitsu.csv > holidays.csv` and rebuild, or pass the converted file to --holidays.
+//
+//go:embed holidays.csv
+var defaultHolidays []byte
+
+var ErrInvalidHolidays = errors.New("invalid holidays")
+
+// Calendar is the set of the holidays of Japanese banks besides weekends.
+type Calendar struct {
+	holidays map[string]string
+	// firstYear and lastYear are the years the holidays are known for. The holidays are announced a year ahead,
+	// so a later date may fall on a holiday that isn't in the dataset yet.
+	firstYear, lastYear int
+}
+
+// Default returns the calendar of the embedded holidays. It's parsed once and shared.
+var Default = sync.OnceValue(func() *Calendar {
+	c, err := Parse(bytes.NewReader(defaultHolidays))
+	if err != nil {
+		panic(err)
+	}
+	return c
+})
+
+// Load reads a holidays file in the format of the embedded one, which replaces the embedded holidays.
+func Load(path string) (*Calendar, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	c, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", path, err)
+	}
+	return c, nil
+}
+
+// Parse reads the CSV of the holidays whose first column is the date formatted as YYYY/M/D and second one is
+// the name. The first line is the header.
+func Parse(r io.Reader) (*Calendar, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 2
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidHolidays, err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: header is missing", ErrInvalidHolidays)
+	}
+	c := &Calendar{holidays: make(map[string]string, len(records)-1)}
+	for i, record := range records[1:] {
+		date, err := time.Parse("2006/1/2", strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: date must be formatted as YYYY/M/D, but got %v", ErrInvalidHolidays, i+2, record[0])
+		}
+		c.holidays[date.Format(time.DateOnly)] = strings.TrimSpace(record[1])
+		if c.firstYear == 0 || date.Year() < c.firstYear {
+			c.firstYear = date.Year()
+		}
+		c.lastYear = max(c.lastYear, date.Year())
+	}
+	return c, nil
+}
+
+// Holiday returns the name of the national holiday on date.
+func (c *Calendar) Holiday(date time.Time) (string, bool) {
+	name, ok := c.holidays[date.Format(time.DateOnly)]
+	return name, ok
+}
+
+// IsBusinessDay reports whether banks are open on date. They're closed on weekends, national holidays and
+// from December 31 to January 3 (年末年始).
+func (c *Calendar) IsBusinessDay(date time.Time) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	if _, ok := c.Holiday(date); ok {
+		return false
+	}
+	_, month, day := date.Date()
+	return !(month == time.December && day == 31) && !(month == time.January && day <= 3)
+}
+
+// Covers reports whether the holidays of the year of date are known. Outside of them, only weekends and
+// the year-end holidays are closed days.
+func (c *Calendar) Covers(date time.Time) bool {
+	return c.firstYear <= date.Year() && date.Year() <= c.lastYear
+}
+
+// Years returns the first and last years the holidays are known for.
+func (c *Calendar) Years() (int, int) {
+	return c.firstYear, c.lastYear
+}
+
+// BusinessDayOnOrBefore returns date if it's a business day, or the previous business day otherwise (前営業日).
+func (c *Calendar) BusinessDayOnOrBefore(date time.Time) time.Time {
+	for !c.IsBusinessDay(date) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
+// BusinessDayOnOrAfter returns date if it's a business day, or the next business day otherwise (翌営業日).
+func (c *Calendar) BusinessDayOnOrAfter(date time.Time) time.Time {
+	for !c.IsBusinessDay(date) {
+		date = date.AddDate(0, 0, 1)
+	}
+	return date
+}
@@ -0,0 +1,127 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestCalendar_IsBusinessDay(t *testing.T) {
+	tests := []struct {
+		name string
+		date time.Time
+		want bool
+	}{
+		{name: "weekday", date: date(2024, 10, 30), want: true},
+		{name: "saturday", date: date(2024, 11, 2)},
+		{name: "sunday", date: date(2024, 11, 3)},
+		{name: "national holiday", date: date(2025, 3, 20)},
+		{name: "holiday on saturday", date: date(2024, 11, 23)},
+		{name: "substitute holiday", date: date(2024, 2, 12)},
+		{name: "citizens' holiday between two holidays", date: date(2026, 9, 22)},
+		{name: "december 30", date: date(2024, 12, 30), want: true},
+		{name: "december 31", date: date(2024, 12, 31)},
+		{name: "january 2", date: date(2025, 1, 2)},
+		{name: "january 3", date: date(2025, 1, 3)},
+		{name: "january 4", date: date(2027, 1, 4), want: true},
+		{name: "year-end out of the dataset", date: date(2099, 12, 31)},
+	}
+	c := Default()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, c.IsBusinessDay(tt.date))
+		})
+	}
+}
+
+func TestCalendar_BusinessDay(t *testing.T) {
+	tests := []struct {
+		name           string
+		date           time.Time
+		wantOnOrBefore time.Time
+		wantOnOrAfter  time.Time
+	}{
+		{name: "business day", date: date(2024, 10, 30), wantOnOrBefore: date(2024, 10, 30), wantOnOrAfter: date(2024, 10, 30)},
+		{name: "weekend", date: date(2024, 11, 30), wantOnOrBefore: date(2024, 11, 29), wantOnOrAfter: date(2024, 12, 2)},
+		{name: "golden week", date: date(2025, 5, 3), wantOnOrBefore: date(2025, 5, 2), wantOnOrAfter: date(2025, 5, 7)},
+		{name: "silver week", date: date(2026, 9, 21), wantOnOrBefore: date(2026, 9, 18), wantOnOrAfter: date(2026, 9, 24)},
+		{name: "end of year", date: date(2024, 12, 31), wantOnOrBefore: date(2024, 12, 30), wantOnOrAfter: date(2025, 1, 6)},
+		{name: "end of year before weekend", date: date(2026, 12, 31), wantOnOrBefore: date(2026, 12, 30), wantOnOrAfter: date(2027, 1, 4)},
+		{name: "substitute holiday of equinox", date: date(2027, 3, 22), wantOnOrBefore: date(2027, 3, 19), wantOnOrAfter: date(2027, 3, 23)},
+	}
+	c := Default()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantOnOrBefore, c.BusinessDayOnOrBefore(tt.date))
+			assert.Equal(t, tt.wantOnOrAfter, c.BusinessDayOnOrAfter(tt.date))
+		})
+	}
+}
+
+func TestCalendar_BusinessDay_OutOfRange(t *testing.T) {
+	tests := []struct {
+		name           string
+		date           time.Time
+		wantCovered    bool
+		wantOnOrBefore time.Time
+		wantOnOrAfter  time.Time
+	}{
+		{name: "last day of the dataset", date: date(2027, 12, 30), wantCovered: true, wantOnOrBefore: date(2027, 12, 30), wantOnOrAfter: date(2027, 12, 30)},
+		{name: "year-end beyond the dataset", date: date(2027, 12, 31), wantCovered: true, wantOnOrBefore: date(2027, 12, 30), wantOnOrAfter: date(2028, 1, 4)},
+		{name: "unknown holiday after the dataset", date: date(2028, 1, 10), wantOnOrBefore: date(2028, 1, 10), wantOnOrAfter: date(2028, 1, 10)},
+		{name: "weekend before the dataset", date: date(2023, 12, 30), wantOnOrBefore: date(2023, 12, 29), wantOnOrAfter: date(2024, 1, 4)},
+	}
+	c := Default()
+	first, last := c.Years()
+	assert.Equal(t, 2024, first)
+	assert.Equal(t, 2027, last)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantCovered, c.Covers(tt.date))
+			assert.Equal(t, tt.wantOnOrBefore, c.BusinessDayOnOrBefore(tt.date))
+			assert.Equal(t, tt.wantOnOrAfter, c.BusinessDayOnOrAfter(tt.date))
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Run("dataset replaces the embedded one", func(t *testing.T) {
+		c, err := Parse(strings.NewReader("国民の祝日・休日月日,国民の祝日・休日名称\n2028/1/10,成人の日\n"))
+		require.NoError(t, err)
+		name, ok := c.Holiday(date(2028, 1, 10))
+		assert.True(t, ok)
+		assert.Equal(t, "成人の日", name)
+		assert.True(t, c.IsBusinessDay(date(2025, 3, 20)), "holidays not in the dataset are business days")
+	})
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{name: "empty", csv: ""},
+		{name: "invalid date", csv: "date,name\n2028-01-10,成人の日\n"},
+		{name: "missing name", csv: "date,name\n2028/1/10\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.csv))
+			assert.True(t, errors.Is(err, ErrInvalidHolidays), err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	c, err := Load("holidays.csv")
+	require.NoError(t, err)
+	assert.Equal(t, Default(), c)
+
+	_, err = Load("missing.csv")
+	assert.Error(t, err)
+}
@@ -0,0 +1,31 @@
+package calendar
+
+import (
+	"time"
+)
+
+// DueDateShorthands are the due dates given relative to the issue date instead of as YYYY-MM-DD, each followed by
+// its Japanese notation.
+var DueDateShorthands = []string{
+	"end_of_month", "当月末",
+	"end_of_next_month", "翌月末",
+	"end_of_month_after_next", "翌々月末",
+}
+
+// monthsAfterIssue maps the shorthands to the number of months from the issue date to the month ending on the due date.
+var monthsAfterIssue = map[string]int{
+	"end_of_month": 0, "当月末": 0,
+	"end_of_next_month": 1, "翌月末": 1,
+	"end_of_month_after_next": 2, "翌々月末": 2,
+}
+
+// ParseDueDate parses s formatted as YYYY-MM-DD or one of DueDateShorthands relative to issueDate.
+// The due date isn't adjusted to a business day.
+func ParseDueDate(s string, issueDate time.Time) (time.Time, error) {
+	if months, ok := monthsAfterIssue[s]; ok {
+		year, month, _ := issueDate.Date()
+		// Day 0 of a month is the last day of the previous month.
+		return time.Date(year, month+time.Month(months)+1, 0, 0, 0, 0, 0, time.UTC), nil
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.UTC)
+}
@@ -0,0 +1,35 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDueDate(t *testing.T) {
+	tests := []struct {
+		name      string
+		s         string
+		issueDate time.Time
+		want      time.Time
+		wantErr   bool
+	}{
+		{name: "date", s: "2024-10-30", issueDate: date(2024, 10, 1), want: date(2024, 10, 30)},
+		{name: "end of month", s: "end_of_month", issueDate: date(2024, 12, 15), want: date(2024, 12, 31)},
+		{name: "当月末", s: "当月末", issueDate: date(2024, 4, 30), want: date(2024, 4, 30)},
+		{name: "end of next month in leap year", s: "end_of_next_month", issueDate: date(2024, 1, 31), want: date(2024, 2, 29)},
+		{name: "翌月末 over the year", s: "翌月末", issueDate: date(2024, 12, 1), want: date(2025, 1, 31)},
+		{name: "end of month after next", s: "end_of_month_after_next", issueDate: date(2024, 11, 30), want: date(2025, 1, 31)},
+		{name: "翌々月末", s: "翌々月末", issueDate: date(2025, 1, 31), want: date(2025, 3, 31)},
+		{name: "unknown shorthand", s: "end_of_year", issueDate: date(2024, 10, 1), wantErr: true},
+		{name: "empty", s: "", issueDate: date(2024, 10, 1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDueDate(tt.s, tt.issueDate)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
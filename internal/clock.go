@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"time"
+
+	// The containers have no zoneinfo, so the timezone database is embedded to load the business timezone.
+	_ "time/tzdata"
+)
+
+// DefaultTimezone is the business timezone when none is configured. The dates of invoices, like "today" of
+// the due_date filter, are the dates in it rather than in the timezone of the server.
+const DefaultTimezone = "Asia/Tokyo"
+
+// Clock tells the current time. Tests inject a fixed one to freeze time.
+type Clock interface {
+	Now() time.Time
+}
+
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock returns the clock of the system telling the time in loc, the business timezone.
+func SystemClock(loc *time.Location) Clock {
+	return ClockFunc(func() time.Time {
+		return time.Now().In(loc)
+	})
+}
+
+// Today returns the current date in the timezone of the clock. It's midnight UTC like the dates read from the database,
+// so it compares with them and is formatted as the same date.
+func Today(clock Clock) time.Time {
+	y, m, d := clock.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// currentTime returns the current time of clock, falling back to the system clock when clock is nil.
+func currentTime(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fixedClock returns the clock frozen at t.
+func fixedClock(t time.Time) Clock {
+	return ClockFunc(func() time.Time {
+		return t
+	})
+}
+
+// testClock is the clock of the tests which don't depend on the current time.
+var testClock = fixedClock(time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC))
+
+func TestToday(t *testing.T) {
+	jst, err := time.LoadLocation(DefaultTimezone)
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "morning in JST is still the previous day in UTC",
+			now:  time.Date(2024, 10, 1, 8, 59, 59, 0, jst),
+			want: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "midnight in JST",
+			now:  time.Date(2024, 10, 1, 0, 0, 0, 0, jst),
+			want: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "last moment of the day in JST",
+			now:  time.Date(2024, 9, 30, 23, 59, 59, 0, jst),
+			want: time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "UTC clock",
+			now:  time.Date(2024, 9, 30, 23, 0, 0, 0, time.UTC),
+			want: time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Today(fixedClock(tt.now)))
+		})
+	}
+}
+
+func TestSystemClock(t *testing.T) {
+	jst, err := time.LoadLocation(DefaultTimezone)
+	require.NoError(t, err)
+	now := SystemClock(jst).Now()
+	assert.Equal(t, jst, now.Location())
+	assert.WithinDuration(t, time.Now(), now, time.Minute)
+}
@@ -0,0 +1,220 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+type CompanyRequest struct {
+	Name               string `json:"name"`
+	Representative     string `json:"representative"`
+	Phone              string `json:"phone"`
+	PostalCode         string `json:"postal_code"`
+	Address            string `json:"address"`
+	RegistrationNumber string `json:"registration_number"`
+	QualifiedIssuer    bool   `json:"qualified_issuer"`
+	// DueDatePolicy defaults to next.
+	DueDatePolicy string `json:"due_date_policy"`
+}
+
+type CompanyResponse struct {
+	CompanyID          string `json:"company_id"`
+	Name               string `json:"name"`
+	Representative     string `json:"representative"`
+	Phone              string `json:"phone"`
+	PostalCode         string `json:"postal_code"`
+	Address            string `json:"address"`
+	RegistrationNumber string `json:"registration_number"`
+	QualifiedIssuer    bool   `json:"qualified_issuer"`
+	DueDatePolicy      string `json:"due_date_policy"`
+}
+
+// NewCompanyResponse is the representation of a company shared by the API and the CLI.
+func NewCompanyResponse(company *domain.Company) CompanyResponse {
+	return CompanyResponse{
+		CompanyID:          company.CompanyID,
+		Name:               company.Name,
+		Representative:     company.Representative,
+		Phone:              company.Phone,
+		PostalCode:         company.PostalCode,
+		Address:            company.Address,
+		RegistrationNumber: company.RegistrationNumber,
+		QualifiedIssuer:    company.QualifiedIssuer,
+		DueDatePolicy:      string(company.DueDatePolicy),
+	}
+}
+
+type CompanyListResponse struct {
+	Companies []CompanyResponse `json:"companies"`
+}
+
+type CompanyManager interface {
+	List(context.Context) ([]domain.Company, error)
+	Get(context.Context, string) (*domain.Company, error)
+	Create(context.Context, *domain.Company) (*domain.Company, error)
+	Update(context.Context, *domain.Company) (*domain.Company, error)
+	Delete(context.Context, string) error
+}
+
+func CompanyListHandler(manager CompanyManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companies, err := manager.List(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to list companies", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to list companies"}`))
+			return
+		}
+		resp := make([]CompanyResponse, 0, len(companies))
+		for _, company := range companies {
+			resp = append(resp, NewCompanyResponse(&company))
+		}
+		writeJSON(w, r, CompanyListResponse{Companies: resp}, logger)
+	}
+}
+
+func CompanyGetHandler(manager CompanyManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID := r.PathValue("id")
+		company, err := manager.Get(r.Context(), companyID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Another company was requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrCompanyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Company not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get company", "company_id", companyID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to get company"}`))
+			return
+		}
+		writeJSON(w, r, NewCompanyResponse(company), logger)
+	}
+}
+
+func CompanyCreateHandler(manager CompanyManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body CompanyRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode company request", "body", body, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to decode company request"}`))
+			return
+		}
+		company, err := manager.Create(r.Context(), body.Company(""))
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Company creation was requested by a user of a company", "name", body.Name)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidCompany) {
+			writeValidationError(w, err)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to create company", "body", body, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to create company"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, r, NewCompanyResponse(company), logger)
+	}
+}
+
+func CompanyUpdateHandler(manager CompanyManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID := r.PathValue("id")
+		var body CompanyRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode company request", "body", body, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to decode company request"}`))
+			return
+		}
+		company, err := manager.Update(r.Context(), body.Company(companyID))
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Another company was requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidCompany) {
+			writeValidationError(w, err)
+			return
+		}
+		if errors.Is(err, ErrCompanyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Company not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to update company", "company_id", companyID, "body", body, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to update company"}`))
+			return
+		}
+		writeJSON(w, r, NewCompanyResponse(company), logger)
+	}
+}
+
+func CompanyDeleteHandler(manager CompanyManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID := r.PathValue("id")
+		err := manager.Delete(r.Context(), companyID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Another company was requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrCompanyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Company not found"}`))
+			return
+		}
+		if errors.Is(err, ErrCompanyInUse) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Company is referenced by invoices"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to delete company", "company_id", companyID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to delete company"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// Company returns the company of the request, with the due date policy defaulting to next.
+func (b *CompanyRequest) Company(companyID string) *domain.Company {
+	policy := domain.DueDatePolicy(b.DueDatePolicy)
+	if policy == "" {
+		policy = domain.NextBusinessDay
+	}
+	return &domain.Company{
+		CompanyID:          companyID,
+		Name:               b.Name,
+		Representative:     b.Representative,
+		Phone:              b.Phone,
+		PostalCode:         b.PostalCode,
+		Address:            b.Address,
+		RegistrationNumber: b.RegistrationNumber,
+		QualifiedIssuer:    b.QualifiedIssuer,
+		DueDatePolicy:      policy,
+	}
+}
@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCompanyManager struct {
+	list   func(context.Context) ([]domain.Company, error)
+	get    func(context.Context, string) (*domain.Company, error)
+	create func(context.Context, *domain.Company) (*domain.Company, error)
+	update func(context.Context, *domain.Company) (*domain.Company, error)
+	delete func(context.Context, string) error
+}
+
+func (f *fakeCompanyManager) List(ctx context.Context) ([]domain.Company, error) {
+	return f.list(ctx)
+}
+
+func (f *fakeCompanyManager) Get(ctx context.Context, companyID string) (*domain.Company, error) {
+	return f.get(ctx, companyID)
+}
+
+func (f *fakeCompanyManager) Create(ctx context.Context, company *domain.Company) (*domain.Company, error) {
+	return f.create(ctx, company)
+}
+
+func (f *fakeCompanyManager) Update(ctx context.Context, company *domain.Company) (*domain.Company, error) {
+	return f.update(ctx, company)
+}
+
+func (f *fakeCompanyManager) Delete(ctx context.Context, companyID string) error {
+	return f.delete(ctx, companyID)
+}
+
+func serveCompany(t *testing.T, h http.HandlerFunc, method, body string) (int, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, "http://localhost/api/companies/1", strings.NewReader(body))
+	r.SetPathValue("id", "1")
+	h(w, r)
+	b, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return w.Code, string(b)
+}
+
+func TestCompanyListHandler(t *testing.T) {
+	manager := &fakeCompanyManager{list: func(context.Context) ([]domain.Company, error) {
+		return []domain.Company{{CompanyID: "1", Name: "Example Inc.", PostalCode: "100-0001", DueDatePolicy: domain.NextBusinessDay}}, nil
+	}}
+	code, body := serveCompany(t, CompanyListHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, `{"companies":[{"company_id":"1","name":"Example Inc.","representative":"","phone":"","postal_code":"100-0001","address":"","registration_number":"","qualified_issuer":false,"due_date_policy":"next"}]}`+"\n", body)
+}
+
+func TestCompanyGetHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		company  *domain.Company
+		err      error
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "200 ok with company",
+			company:  &domain.Company{CompanyID: "1", Name: "Example Inc.", DueDatePolicy: domain.PreviousBusinessDay},
+			wantBody: `{"company_id":"1","name":"Example Inc.","representative":"","phone":"","postal_code":"","address":"","registration_number":"","qualified_issuer":false,"due_date_policy":"previous"}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "404 not found",
+			err:      ErrCompanyNotFound,
+			wantBody: `{"message":"Company not found"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "403 forbidden for another company",
+			err:      ErrForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "500 internal server error",
+			err:      errors.New("this is test"),
+			wantBody: `{"message":"Failed to get company"}`,
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeCompanyManager{get: func(_ context.Context, companyID string) (*domain.Company, error) {
+				assert.Equal(t, "1", companyID)
+				return tt.company, tt.err
+			}}
+			code, body := serveCompany(t, CompanyGetHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodGet, "")
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestCompanyCreateHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "201 created",
+			body:     `{"name":"Example Inc.","representative":"Taro Yamada","phone":"03-1234-5678","postal_code":"100-0001","address":"Tokyo"}`,
+			wantBody: `{"company_id":"1","name":"Example Inc.","representative":"Taro Yamada","phone":"03-1234-5678","postal_code":"100-0001","address":"Tokyo","registration_number":"","qualified_issuer":false,"due_date_policy":"next"}` + "\n",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "400 bad request when failed request body decode",
+			body:     `INVALID`,
+			wantBody: `{"message":"Failed to decode company request"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request with invalid company",
+			body:     `{}`,
+			err:      fmt.Errorf("%w: 'name' mustn't be empty", domain.ErrInvalidCompany),
+			wantBody: `{"message":"invalid company: 'name' mustn't be empty"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "500 internal server error",
+			body:     `{"name":"Example Inc."}`,
+			err:      errors.New("this is test"),
+			wantBody: `{"message":"Failed to create company"}`,
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeCompanyManager{create: func(_ context.Context, company *domain.Company) (*domain.Company, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				company.CompanyID = "1"
+				return company, nil
+			}}
+			code, body := serveCompany(t, CompanyCreateHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodPost, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestCompanyUpdateHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "200 ok with updated company",
+			wantBody: `{"company_id":"1","name":"Example Inc.","representative":"","phone":"","postal_code":"","address":"","registration_number":"","qualified_issuer":false,"due_date_policy":"previous"}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "404 not found",
+			err:      ErrCompanyNotFound,
+			wantBody: `{"message":"Company not found"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "403 forbidden for another company",
+			err:      ErrForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeCompanyManager{update: func(_ context.Context, company *domain.Company) (*domain.Company, error) {
+				assert.Equal(t, "1", company.CompanyID)
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return company, nil
+			}}
+			code, body := serveCompany(t, CompanyUpdateHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodPut, `{"name":"Example Inc.","due_date_policy":"previous"}`)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestCompanyDeleteHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "204 no content",
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "404 not found",
+			err:      ErrCompanyNotFound,
+			wantBody: `{"message":"Company not found"}`,
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "403 forbidden for another company",
+			err:      ErrForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "409 conflict when invoices reference the company",
+			err:      ErrCompanyInUse,
+			wantBody: `{"message":"Company is referenced by invoices"}`,
+			wantCode: http.StatusConflict,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakeCompanyManager{delete: func(context.Context, string) error {
+				return tt.err
+			}}
+			code, body := serveCompany(t, CompanyDeleteHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodDelete, "")
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestCompanyTenantIsolation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := &CompanyService{Repository: &fakeCompanyRepository{
+		CompanySelectorFunc: func(_ context.Context, companyID string) (*CompanyRow, error) {
+			require.Equal(t, "2", companyID, "company of another tenant must not be looked up")
+			return &CompanyRow{CompanyID: "2", Name: "Own Inc."}, nil
+		},
+		selectCompanies: func(context.Context) ([]CompanyRow, error) {
+			require.FailNow(t, "companies of other tenants must not be listed")
+			return nil, nil
+		},
+		updateCompany: func(context.Context, *domain.Company) (bool, error) {
+			require.FailNow(t, "company of another tenant must not be updated")
+			return false, nil
+		},
+		deleteCompany: func(context.Context, string) (bool, error) {
+			require.FailNow(t, "company of another tenant must not be deleted")
+			return false, nil
+		},
+		insertCompany: func(context.Context, *domain.Company) (*CompanyRow, error) {
+			require.FailNow(t, "admin of a company must not create another tenant")
+			return nil, nil
+		},
+	}}
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		method   string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "list only the company of the admin",
+			handler:  CompanyListHandler(manager, logger),
+			method:   http.MethodGet,
+			wantCode: http.StatusOK,
+			wantBody: `{"companies":[{"company_id":"2","name":"Own Inc.","representative":"","phone":"","postal_code":"","address":"","registration_number":"","qualified_issuer":false,"due_date_policy":""}]}` + "\n",
+		},
+		{
+			name:     "403 forbidden getting another company",
+			handler:  CompanyGetHandler(manager, logger),
+			method:   http.MethodGet,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+		},
+		{
+			name:     "403 forbidden updating another company",
+			handler:  CompanyUpdateHandler(manager, logger),
+			method:   http.MethodPut,
+			body:     `{"name":"Example Inc."}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+		},
+		{
+			name:     "403 forbidden deleting another company",
+			handler:  CompanyDeleteHandler(manager, logger),
+			method:   http.MethodDelete,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+		},
+		{
+			name:     "403 forbidden creating a company",
+			handler:  CompanyCreateHandler(manager, logger),
+			method:   http.MethodPost,
+			body:     `{"name":"Another Inc."}`,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// serveCompany requests company 1, which the admin of company 2 doesn't belong to.
+			h := func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(w, r.WithContext(WithUser(r.Context(), &domain.User{UserID: "1", CompanyID: "2", Username: "foo", Role: domain.Admin})))
+			}
+			code, body := serveCompany(t, h, tt.method, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+)
+
+// See https://dev.mysql.com/doc/mysql-errors/8.4/en/server-error-reference.html.
+const (
+	errDupEntry        = 1062
+	errRowIsReferenced = 1451
+	errNoReferencedRow = 1452
+)
+
+var _ CompanyRepository = (*MySQL)(nil)
+
+type CompanyRow struct {
+	CompanyID          string
+	Name               string
+	Representative     string
+	Phone              string
+	PostalCode         string
+	Address            string
+	RegistrationNumber string
+	QualifiedIssuer    bool
+	DueDatePolicy      string
+}
+
+func (r *CompanyRow) company() *domain.Company {
+	return &domain.Company{
+		CompanyID:          r.CompanyID,
+		Name:               r.Name,
+		Representative:     r.Representative,
+		Phone:              r.Phone,
+		PostalCode:         r.PostalCode,
+		Address:            r.Address,
+		RegistrationNumber: r.RegistrationNumber,
+		QualifiedIssuer:    r.QualifiedIssuer,
+		DueDatePolicy:      domain.DueDatePolicy(r.DueDatePolicy),
+	}
+}
+
+func (s *MySQL) SelectCompanies(ctx context.Context) ([]CompanyRow, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT company_id, name, representative, phone, postal_code, address, registration_number, qualified_issuer, due_date_policy FROM companies ORDER BY company_id;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]CompanyRow, 0)
+	for rows.Next() {
+		var row CompanyRow
+		if err := rows.Scan(&row.CompanyID, &row.Name, &row.Representative, &row.Phone, &row.PostalCode, &row.Address, &row.RegistrationNumber, &row.QualifiedIssuer, &row.DueDatePolicy); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// SelectCompany returns the company identified by companyID, or nil if it doesn't exist.
+func (s *MySQL) SelectCompany(ctx context.Context, companyID string) (*CompanyRow, error) {
+	var row CompanyRow
+	err := s.DB.QueryRowContext(ctx, "SELECT company_id, name, representative, phone, postal_code, address, registration_number, qualified_issuer, due_date_policy FROM companies WHERE company_id = ?;", companyID).
+		Scan(&row.CompanyID, &row.Name, &row.Representative, &row.Phone, &row.PostalCode, &row.Address, &row.RegistrationNumber, &row.QualifiedIssuer, &row.DueDatePolicy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (s *MySQL) InsertCompany(ctx context.Context, company *domain.Company) (*CompanyRow, error) {
+	result, err := s.DB.ExecContext(ctx, "INSERT INTO companies (name, representative, phone, postal_code, address, registration_number, qualified_issuer, due_date_policy) VALUES (?, ?, ?, ?, ?, ?, ?, ?);", company.Name, company.Representative, company.Phone, company.PostalCode, company.Address, company.RegistrationNumber, company.QualifiedIssuer, company.DueDatePolicy)
+	if err != nil {
+		return nil, err
+	}
+	companyID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &CompanyRow{
+		CompanyID:          strconv.FormatInt(companyID, 10),
+		Name:               company.Name,
+		Representative:     company.Representative,
+		Phone:              company.Phone,
+		PostalCode:         company.PostalCode,
+		Address:            company.Address,
+		RegistrationNumber: company.RegistrationNumber,
+		QualifiedIssuer:    company.QualifiedIssuer,
+		DueDatePolicy:      string(company.DueDatePolicy),
+	}, nil
+}
+
+// UpdateCompany overwrites the company and reports false if it doesn't exist.
+func (s *MySQL) UpdateCompany(ctx context.Context, company *domain.Company) (bool, error) {
+	// RowsAffected is 0 when nothing changed, so check existence in the same transaction instead.
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // The rollback will be ignored if the tx has been committed later in the function.
+
+	var id string
+	err = tx.QueryRowContext(ctx, "SELECT company_id FROM companies WHERE company_id = ? FOR UPDATE;", company.CompanyID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE companies SET name = ?, representative = ?, phone = ?, postal_code = ?, address = ?, registration_number = ?, qualified_issuer = ?, due_date_policy = ? WHERE company_id = ?;", company.Name, company.Representative, company.Phone, company.PostalCode, company.Address, company.RegistrationNumber, company.QualifiedIssuer, company.DueDatePolicy, company.CompanyID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// DeleteCompany deletes the company and reports false if it doesn't exist.
+// ErrCompanyInUse is returned when invoices still reference the company.
+func (s *MySQL) DeleteCompany(ctx context.Context, companyID string) (bool, error) {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM companies WHERE company_id = ?;", companyID)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errRowIsReferenced {
+		return false, ErrCompanyInUse
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var companyColumns = []string{"company_id", "name", "representative", "phone", "postal_code", "address", "registration_number", "qualified_issuer", "due_date_policy"}
+
+func TestMySQL_SelectCompanies(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT company_id, name, representative, phone, postal_code, address, registration_number, qualified_issuer, due_date_policy FROM companies ORDER BY company_id;")).WillReturnRows(
+		sqlmock.NewRows(companyColumns).
+			AddRow("1", "Example Inc.", "Taro Yamada", "03-1234-5678", "100-0001", "Tokyo", "T7000012050002", true, "next").
+			AddRow("2", "Sample LLC", "Hanako Sato", "06-1234-5678", "530-0001", "Osaka", "", false, "previous"))
+
+	s := &MySQL{DB: db}
+	got, err := s.SelectCompanies(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []CompanyRow{
+		{CompanyID: "1", Name: "Example Inc.", Representative: "Taro Yamada", Phone: "03-1234-5678", PostalCode: "100-0001", Address: "Tokyo", RegistrationNumber: "T7000012050002", QualifiedIssuer: true, DueDatePolicy: "next"},
+		{CompanyID: "2", Name: "Sample LLC", Representative: "Hanako Sato", Phone: "06-1234-5678", PostalCode: "530-0001", Address: "Osaka", DueDatePolicy: "previous"},
+	}, got)
+}
+
+func TestMySQL_SelectCompany(t *testing.T) {
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want *CompanyRow
+	}{
+		{
+			name: "company exists",
+			rows: sqlmock.NewRows(companyColumns).AddRow("1", "Example Inc.", "Taro Yamada", "03-1234-5678", "100-0001", "Tokyo", "T7000012050002", true, "next"),
+			want: &CompanyRow{CompanyID: "1", Name: "Example Inc.", Representative: "Taro Yamada", Phone: "03-1234-5678", PostalCode: "100-0001", Address: "Tokyo", RegistrationNumber: "T7000012050002", QualifiedIssuer: true, DueDatePolicy: "next"},
+		},
+		{
+			name: "company doesn't exist",
+			rows: sqlmock.NewRows(companyColumns),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT company_id, name, representative, phone, postal_code, address, registration_number, qualified_issuer, due_date_policy FROM companies WHERE company_id = ?;")).WithArgs("1").WillReturnRows(tt.rows)
+
+			s := &MySQL{DB: db}
+			got, err := s.SelectCompany(context.Background(), "1")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQL_InsertCompany(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO companies (name, representative, phone, postal_code, address, registration_number, qualified_issuer, due_date_policy) VALUES (?, ?, ?, ?, ?, ?, ?, ?);")).
+		WithArgs("Example Inc.", "Taro Yamada", "03-1234-5678", "100-0001", "Tokyo", "T7000012050002", true, "previous").WillReturnResult(sqlmock.NewResult(3, 1))
+
+	s := &MySQL{DB: db}
+	got, err := s.InsertCompany(context.Background(), &domain.Company{Name: "Example Inc.", Representative: "Taro Yamada", Phone: "03-1234-5678", PostalCode: "100-0001", Address: "Tokyo", RegistrationNumber: "T7000012050002", QualifiedIssuer: true, DueDatePolicy: domain.PreviousBusinessDay})
+	require.NoError(t, err)
+	assert.Equal(t, &CompanyRow{CompanyID: "3", Name: "Example Inc.", Representative: "Taro Yamada", Phone: "03-1234-5678", PostalCode: "100-0001", Address: "Tokyo", RegistrationNumber: "T7000012050002", QualifiedIssuer: true, DueDatePolicy: "previous"}, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMySQL_UpdateCompany(t *testing.T) {
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want bool
+	}{
+		{
+			name: "company exists",
+			rows: sqlmock.NewRows([]string{"company_id"}).AddRow("1"),
+			want: true,
+		},
+		{
+			name: "company doesn't exist",
+			rows: sqlmock.NewRows([]string{"company_id"}),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT company_id FROM companies WHERE company_id = ? FOR UPDATE;")).WithArgs("1").WillReturnRows(tt.rows)
+			if tt.want {
+				mock.ExpectExec(regexp.QuoteMeta("UPDATE companies SET name = ?, representative = ?, phone = ?, postal_code = ?, address = ?, registration_number = ?, qualified_issuer = ?, due_date_policy = ? WHERE company_id = ?;")).
+					WithArgs("Example Inc.", "", "", "", "", "", false, "next", "1").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			s := &MySQL{DB: db}
+			got, err := s.UpdateCompany(context.Background(), &domain.Company{CompanyID: "1", Name: "Example Inc.", DueDatePolicy: domain.NextBusinessDay})
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMySQL_DeleteCompany(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		execErr  error
+		want     bool
+		wantErr  error
+	}{
+		{
+			name:     "company deleted",
+			affected: 1,
+			want:     true,
+		},
+		{
+			name:     "company doesn't exist",
+			affected: 0,
+			want:     false,
+		},
+		{
+			name:    "company referenced by invoices",
+			execErr: &mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"},
+			wantErr: ErrCompanyInUse,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta("DELETE FROM companies WHERE company_id = ?;")).WithArgs("1")
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(0, tt.affected))
+			}
+
+			s := &MySQL{DB: db}
+			got, err := s.DeleteCompany(context.Background(), "1")
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
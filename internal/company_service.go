@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+var (
+	ErrCompanyNotFound = errors.New("company not found")
+	ErrCompanyInUse    = errors.New("company is referenced by invoices")
+)
+
+type CompanySelector interface {
+	SelectCompany(context.Context, string) (*CompanyRow, error)
+}
+
+type CompanySelectorFunc func(context.Context, string) (*CompanyRow, error)
+
+func (f CompanySelectorFunc) SelectCompany(ctx context.Context, s string) (*CompanyRow, error) {
+	return f(ctx, s)
+}
+
+type CompanyRepository interface {
+	CompanySelector
+	SelectCompanies(context.Context) ([]CompanyRow, error)
+	InsertCompany(context.Context, *domain.Company) (*CompanyRow, error)
+	UpdateCompany(context.Context, *domain.Company) (bool, error)
+	DeleteCompany(context.Context, string) (bool, error)
+}
+
+// CompanyService manages the companies. Authenticated users only see and change their own company, and
+// ErrForbidden is returned for the other ones. Companies are only created without an authenticated user, i.e.
+// by the CLI, since every user belongs to a company.
+type CompanyService struct {
+	Repository CompanyRepository
+}
+
+// List returns every company, or only the company of the authenticated user.
+func (s *CompanyService) List(ctx context.Context) ([]domain.Company, error) {
+	if companyID, ok := CompanyIDFromContext(ctx); ok {
+		company, err := s.Get(ctx, companyID)
+		if errors.Is(err, ErrCompanyNotFound) {
+			return []domain.Company{}, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []domain.Company{*company}, nil
+	}
+	rows, err := s.Repository.SelectCompanies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("company service error: %w", err)
+	}
+	companies := make([]domain.Company, 0, len(rows))
+	for _, row := range rows {
+		companies = append(companies, *row.company())
+	}
+	return companies, nil
+}
+
+func (s *CompanyService) Get(ctx context.Context, companyID string) (*domain.Company, error) {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	row, err := s.Repository.SelectCompany(ctx, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("company service error: %w", err)
+	}
+	if row == nil {
+		return nil, ErrCompanyNotFound
+	}
+	return row.company(), nil
+}
+
+func (s *CompanyService) Create(ctx context.Context, company *domain.Company) (*domain.Company, error) {
+	if _, ok := CompanyIDFromContext(ctx); ok {
+		return nil, ErrForbidden
+	}
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+	row, err := s.Repository.InsertCompany(ctx, company)
+	if err != nil {
+		return nil, fmt.Errorf("insert error: %w", err)
+	}
+	return row.company(), nil
+}
+
+func (s *CompanyService) Update(ctx context.Context, company *domain.Company) (*domain.Company, error) {
+	if err := authorizeCompany(ctx, company.CompanyID); err != nil {
+		return nil, err
+	}
+	if err := company.Validate(); err != nil {
+		return nil, err
+	}
+	updated, err := s.Repository.UpdateCompany(ctx, company)
+	if err != nil {
+		return nil, fmt.Errorf("update error: %w", err)
+	}
+	if !updated {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+func (s *CompanyService) Delete(ctx context.Context, companyID string) error {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return err
+	}
+	deleted, err := s.Repository.DeleteCompany(ctx, companyID)
+	if errors.Is(err, ErrCompanyInUse) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("delete error: %w", err)
+	}
+	if !deleted {
+		return ErrCompanyNotFound
+	}
+	return nil
+}
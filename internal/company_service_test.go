@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCompanyRepository stubs every CompanyRepository method with its own function so each test sets only what it needs.
+type fakeCompanyRepository struct {
+	CompanySelectorFunc
+	selectCompanies func(context.Context) ([]CompanyRow, error)
+	insertCompany   func(context.Context, *domain.Company) (*CompanyRow, error)
+	updateCompany   func(context.Context, *domain.Company) (bool, error)
+	deleteCompany   func(context.Context, string) (bool, error)
+}
+
+func (f *fakeCompanyRepository) SelectCompanies(ctx context.Context) ([]CompanyRow, error) {
+	return f.selectCompanies(ctx)
+}
+
+func (f *fakeCompanyRepository) InsertCompany(ctx context.Context, company *domain.Company) (*CompanyRow, error) {
+	return f.insertCompany(ctx, company)
+}
+
+func (f *fakeCompanyRepository) UpdateCompany(ctx context.Context, company *domain.Company) (bool, error) {
+	return f.updateCompany(ctx, company)
+}
+
+func (f *fakeCompanyRepository) DeleteCompany(ctx context.Context, companyID string) (bool, error) {
+	return f.deleteCompany(ctx, companyID)
+}
+
+func TestCompanyService_Get(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     *CompanyRow
+		err     error
+		want    *domain.Company
+		wantErr error
+	}{
+		{
+			name: "company exists",
+			row:  &CompanyRow{CompanyID: "1", Name: "Example Inc."},
+			want: &domain.Company{CompanyID: "1", Name: "Example Inc."},
+		},
+		{
+			name:    "company doesn't exist",
+			wantErr: ErrCompanyNotFound,
+		},
+		{
+			name:    "selector returns error",
+			err:     errors.New("this is test"),
+			wantErr: fmt.Errorf("company service error: %w", errors.New("this is test")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := CompanyService{Repository: &fakeCompanyRepository{
+				CompanySelectorFunc: func(context.Context, string) (*CompanyRow, error) {
+					return tt.row, tt.err
+				},
+			}}
+			got, err := s.Get(context.Background(), "1")
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompanyService_Create(t *testing.T) {
+	tests := []struct {
+		name    string
+		company *domain.Company
+		want    *domain.Company
+		wantErr bool
+	}{
+		{
+			name:    "valid company",
+			company: &domain.Company{Name: "Example Inc."},
+			want:    &domain.Company{CompanyID: "1", Name: "Example Inc."},
+		},
+		{
+			name:    "invalid company isn't inserted",
+			company: &domain.Company{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := CompanyService{Repository: &fakeCompanyRepository{
+				insertCompany: func(_ context.Context, company *domain.Company) (*CompanyRow, error) {
+					return &CompanyRow{CompanyID: "1", Name: company.Name}, nil
+				},
+			}}
+			got, err := s.Create(context.Background(), tt.company)
+			assert.Equal(t, tt.wantErr, errors.Is(err, domain.ErrInvalidCompany))
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompanyService_Update(t *testing.T) {
+	tests := []struct {
+		name    string
+		updated bool
+		want    *domain.Company
+		wantErr error
+	}{
+		{
+			name:    "company updated",
+			updated: true,
+			want:    &domain.Company{CompanyID: "1", Name: "Example Inc."},
+		},
+		{
+			name:    "company doesn't exist",
+			wantErr: ErrCompanyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := CompanyService{Repository: &fakeCompanyRepository{
+				updateCompany: func(context.Context, *domain.Company) (bool, error) {
+					return tt.updated, nil
+				},
+			}}
+			got, err := s.Update(context.Background(), &domain.Company{CompanyID: "1", Name: "Example Inc."})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCompanyService_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		err     error
+		wantErr error
+	}{
+		{
+			name:    "company deleted",
+			deleted: true,
+		},
+		{
+			name:    "company doesn't exist",
+			wantErr: ErrCompanyNotFound,
+		},
+		{
+			name:    "company in use",
+			err:     ErrCompanyInUse,
+			wantErr: ErrCompanyInUse,
+		},
+		{
+			name:    "repository returns error",
+			err:     errors.New("this is test"),
+			wantErr: fmt.Errorf("delete error: %w", errors.New("this is test")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := CompanyService{Repository: &fakeCompanyRepository{
+				deleteCompany: func(context.Context, string) (bool, error) {
+					return tt.deleted, tt.err
+				},
+			}}
+			assert.Equal(t, tt.wantErr, s.Delete(context.Background(), "1"))
+		})
+	}
+}
+
+func TestCompanyService_TenantIsolation(t *testing.T) {
+	ctx := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1", Username: "foo"})
+	s := CompanyService{Repository: &fakeCompanyRepository{
+		CompanySelectorFunc: func(_ context.Context, companyID string) (*CompanyRow, error) {
+			assert.Equal(t, "1", companyID)
+			return &CompanyRow{CompanyID: "1", Name: "Example Inc."}, nil
+		},
+		selectCompanies: func(context.Context) ([]CompanyRow, error) {
+			require.FailNow(t, "companies of other tenants must not be listed")
+			return nil, nil
+		},
+		updateCompany: func(context.Context, *domain.Company) (bool, error) {
+			require.FailNow(t, "company of another tenant must not be updated")
+			return false, nil
+		},
+		deleteCompany: func(context.Context, string) (bool, error) {
+			require.FailNow(t, "company of another tenant must not be deleted")
+			return false, nil
+		},
+		insertCompany: func(context.Context, *domain.Company) (*CompanyRow, error) {
+			require.FailNow(t, "user of a company must not create another tenant")
+			return nil, nil
+		},
+	}}
+
+	companies, err := s.List(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Company{{CompanyID: "1", Name: "Example Inc."}}, companies)
+
+	_, err = s.Get(ctx, "2")
+	assert.Equal(t, ErrForbidden, err)
+	_, err = s.Update(ctx, &domain.Company{CompanyID: "2", Name: "Example Inc."})
+	assert.Equal(t, ErrForbidden, err)
+	assert.Equal(t, ErrForbidden, s.Delete(ctx, "2"))
+	_, err = s.Create(ctx, &domain.Company{Name: "Example Inc."})
+	assert.Equal(t, ErrForbidden, err)
+}
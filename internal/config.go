@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/go-sql-driver/mysql"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// DBConfig is every setting of the MySQL connection. It is resolved from, in increasing order of precedence,
+// DefaultDBConfig, a config file, environment variables and command line flags.
+type DBConfig struct {
+	Host            string        `yaml:"host" toml:"host"`
+	Port            int           `yaml:"port" toml:"port"`
+	User            string        `yaml:"user" toml:"user"`
+	Password        string        `yaml:"password" toml:"password"`
+	PasswordFile    string        `yaml:"password_file" toml:"password_file"`
+	Database        string        `yaml:"database" toml:"database"`
+	TLS             string        `yaml:"tls" toml:"tls"`
+	TLSCAFile       string        `yaml:"tls_ca_file" toml:"tls_ca_file"`
+	ConnectTimeout  time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	MaxOpenConns    int           `yaml:"max_open_conns" toml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" toml:"conn_max_lifetime"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" toml:"conn_max_idle_time"`
+}
+
+// DBSettings lists the keys of DBConfig. A key is the name in the config file, "mysql." + key is the flag and
+// the upper-cased key prefixed with MYSQL_ is the environment variable, except that user is read from
+// MYSQL_USERNAME for compatibility.
+var DBSettings = []string{
+	"host", "port", "user", "password", "password_file", "database", "tls", "tls_ca_file",
+	"connect_timeout", "read_timeout", "write_timeout",
+	"max_open_conns", "max_idle_conns", "conn_max_lifetime", "conn_max_idle_time",
+}
+
+// tlsModes are the values of the tls setting understood by the driver as is. "custom" verifies the server
+// with the CA in tls_ca_file.
+var tlsModes = []string{"false", "true", "skip-verify", "preferred", "custom"}
+
+// DefaultDBConfig connects to the database of compose.yaml. The pool settings follow
+// https://github.com/go-sql-driver/mysql?tab=readme-ov-file#important-settings.
+func DefaultDBConfig() DBConfig {
+	return DBConfig{
+		Host:            "upsider-db-1",
+		Port:            3306,
+		Database:        "invoice_db",
+		TLS:             "false",
+		ConnectTimeout:  10 * time.Second,
+		ReadTimeout:     30 * time.Second,
+		WriteTimeout:    30 * time.Second,
+		MaxOpenConns:    10,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 3 * time.Minute,
+	}
+}
+
+func DBEnv(key string) string {
+	if key == "user" {
+		return "MYSQL_USERNAME"
+	}
+	return "MYSQL_" + strings.ToUpper(key)
+}
+
+// LoadFile overrides the settings written in the mysql table of a YAML or TOML file. The format is told by
+// the extension of path.
+func (c *DBConfig) LoadFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	file := struct {
+		MySQL *DBConfig `yaml:"mysql" toml:"mysql"`
+	}{MySQL: c}
+	switch ext := filepath.Ext(path); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(b))
+		dec.KnownFields(true)
+		err = dec.Decode(&file)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(b), &file)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys %v", md.Undecoded())
+		}
+	default:
+		return fmt.Errorf("%w: config file must be .yaml, .yml or .toml, but got %v", ErrInvalidConfig, path)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v: %w", ErrInvalidConfig, path, err)
+	}
+	return nil
+}
+
+// LoadEnv overrides the settings whose environment variable is set.
+func (c *DBConfig) LoadEnv(lookup func(string) (string, bool)) error {
+	for _, key := range DBSettings {
+		if v, ok := lookup(DBEnv(key)); ok {
+			if err := c.Set(key, v); err != nil {
+				return fmt.Errorf("%w (from %v)", err, DBEnv(key))
+			}
+		}
+	}
+	return nil
+}
+
+// Set overrides a setting with its string representation, as given by a flag or an environment variable.
+func (c *DBConfig) Set(key, value string) error {
+	texts := map[string]*string{
+		"host": &c.Host, "user": &c.User, "password": &c.Password, "password_file": &c.PasswordFile,
+		"database": &c.Database, "tls": &c.TLS, "tls_ca_file": &c.TLSCAFile,
+	}
+	ints := map[string]*int{
+		"port": &c.Port, "max_open_conns": &c.MaxOpenConns, "max_idle_conns": &c.MaxIdleConns,
+	}
+	durations := map[string]*time.Duration{
+		"connect_timeout": &c.ConnectTimeout, "read_timeout": &c.ReadTimeout, "write_timeout": &c.WriteTimeout,
+		"conn_max_lifetime": &c.ConnMaxLifetime, "conn_max_idle_time": &c.ConnMaxIdleTime,
+	}
+	if dst, ok := texts[key]; ok {
+		*dst = value
+		return nil
+	}
+	if dst, ok := ints[key]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%w: '%v' must be an integer, but got %v", ErrInvalidConfig, key, value)
+		}
+		*dst = n
+		return nil
+	}
+	if dst, ok := durations[key]; ok {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%w: '%v' must be a duration such as 30s, but got %v", ErrInvalidConfig, key, value)
+		}
+		*dst = d
+		return nil
+	}
+	return fmt.Errorf("%w: unknown setting %v", ErrInvalidConfig, key)
+}
+
+func (c *DBConfig) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("%w: 'host' mustn't be empty", ErrInvalidConfig)
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("%w: 'port' must be between 1 and 65535, but got %d", ErrInvalidConfig, c.Port)
+	}
+	if c.Database == "" {
+		return fmt.Errorf("%w: 'database' mustn't be empty", ErrInvalidConfig)
+	}
+	valid := false
+	for _, mode := range tlsModes {
+		valid = valid || c.TLS == mode
+	}
+	if !valid {
+		return fmt.Errorf("%w: 'tls' must be one of [%v], but got %v", ErrInvalidConfig, strings.Join(tlsModes, ", "), c.TLS)
+	}
+	if c.TLS == "custom" && c.TLSCAFile == "" {
+		return fmt.Errorf("%w: 'tls_ca_file' is required when 'tls' is custom", ErrInvalidConfig)
+	}
+	if c.Password != "" && c.PasswordFile != "" {
+		return fmt.Errorf("%w: 'password' and 'password_file' are mutually exclusive", ErrInvalidConfig)
+	}
+	if c.MaxOpenConns < 0 || c.MaxIdleConns < 0 {
+		return fmt.Errorf("%w: pool sizes mustn't be negative", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// MySQLConfig returns the driver config. It reads password_file and registers the TLS config of tls_ca_file,
+// so it is called once at startup.
+func (c *DBConfig) MySQLConfig() (*mysql.Config, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	password := c.Password
+	if c.PasswordFile != "" {
+		b, err := os.ReadFile(c.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		password = strings.TrimSpace(string(b))
+	}
+	if c.TLS == "custom" {
+		pem, err := os.ReadFile(c.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: no certificate found in %v", ErrInvalidConfig, c.TLSCAFile)
+		}
+		if err := mysql.RegisterTLSConfig("custom", &tls.Config{RootCAs: pool, ServerName: c.Host, MinVersion: tls.VersionTLS12}); err != nil {
+			return nil, err
+		}
+	}
+	mc := mysql.NewConfig()
+	mc.User = c.User
+	mc.Passwd = password
+	mc.Net = "tcp"
+	mc.Addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+	mc.DBName = c.Database
+	mc.TLSConfig = c.TLS
+	mc.Timeout = c.ConnectTimeout
+	mc.ReadTimeout = c.ReadTimeout
+	mc.WriteTimeout = c.WriteTimeout
+	mc.AllowNativePasswords = true
+	return mc, nil
+}
+
+// LogValue leaves out the password so that the config can be logged as is.
+func (c DBConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("addr", net.JoinHostPort(c.Host, strconv.Itoa(c.Port))),
+		slog.String("user", c.User),
+		slog.String("database", c.Database),
+		slog.String("tls", c.TLS),
+		slog.Duration("connect_timeout", c.ConnectTimeout),
+		slog.Int("max_open_conns", c.MaxOpenConns),
+		slog.Int("max_idle_conns", c.MaxIdleConns),
+		slog.Duration("conn_max_lifetime", c.ConnMaxLifetime),
+	)
+}
@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDBConfig_LoadFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    func(*DBConfig)
+		wantErr bool
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `mysql:
+  host: db.example.com
+  port: 13306
+  tls: "true"
+  read_timeout: 5s
+  max_open_conns: 20
+`,
+			want: func(c *DBConfig) {
+				c.Host, c.Port, c.TLS, c.ReadTimeout, c.MaxOpenConns = "db.example.com", 13306, "true", 5*time.Second, 20
+			},
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `[mysql]
+host = "db.example.com"
+database = "invoice"
+conn_max_lifetime = "1h"
+`,
+			want: func(c *DBConfig) {
+				c.Host, c.Database, c.ConnMaxLifetime = "db.example.com", "invoice", time.Hour
+			},
+		},
+		{name: "unknown yaml key", file: "config.yml", content: "mysql:\n  hostname: db\n", wantErr: true},
+		{name: "unknown toml key", file: "config.toml", content: "[mysql]\nhostname = \"db\"\n", wantErr: true},
+		{name: "malformed duration", file: "config.yaml", content: "mysql:\n  read_timeout: soon\n", wantErr: true},
+		{name: "unknown extension", file: "config.json", content: "{}", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			got := DefaultDBConfig()
+			err := got.LoadFile(path)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidConfig)
+				return
+			}
+			require.NoError(t, err)
+			want := DefaultDBConfig()
+			tt.want(&want)
+			assert.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDBConfig_LoadEnv(t *testing.T) {
+	env := map[string]string{
+		"MYSQL_USERNAME":           "app",
+		"MYSQL_PASSWORD":           "secret",
+		"MYSQL_HOST":               "localhost",
+		"MYSQL_PORT":               "3307",
+		"MYSQL_CONN_MAX_IDLE_TIME": "1m",
+	}
+	lookup := func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}
+	got := DefaultDBConfig()
+	require.NoError(t, got.LoadEnv(lookup))
+	want := DefaultDBConfig()
+	want.User, want.Password, want.Host, want.Port, want.ConnMaxIdleTime = "app", "secret", "localhost", 3307, time.Minute
+	assert.Equal(t, want, got)
+
+	env["MYSQL_PORT"] = "mysql"
+	assert.ErrorIs(t, got.LoadEnv(lookup), ErrInvalidConfig)
+}
+
+func TestDBConfig_Set(t *testing.T) {
+	c := DefaultDBConfig()
+	for _, key := range DBSettings {
+		if err := c.Set(key, "1"); err != nil {
+			assert.NotContains(t, err.Error(), "unknown setting", "every setting must be settable")
+		}
+	}
+	assert.ErrorIs(t, c.Set("hostname", "db"), ErrInvalidConfig)
+	assert.ErrorIs(t, c.Set("max_open_conns", "many"), ErrInvalidConfig)
+	assert.ErrorIs(t, c.Set("write_timeout", "10"), ErrInvalidConfig)
+}
+
+func TestDBConfig_MySQLConfig(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(passwordFile, []byte("from-file\n"), 0o600))
+
+	c := DefaultDBConfig()
+	c.User = "app"
+	c.PasswordFile = passwordFile
+	c.Host = "::1"
+	c.TLS = "skip-verify"
+	got, err := c.MySQLConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "app", got.User)
+	assert.Equal(t, "from-file", got.Passwd)
+	assert.Equal(t, "[::1]:3306", got.Addr)
+	assert.Equal(t, "invoice_db", got.DBName)
+	assert.Equal(t, "skip-verify", got.TLSConfig)
+	assert.Equal(t, 10*time.Second, got.Timeout)
+	assert.Equal(t, 30*time.Second, got.ReadTimeout)
+
+	tests := []struct {
+		name   string
+		modify func(*DBConfig)
+	}{
+		{"empty host", func(c *DBConfig) { c.Host = "" }},
+		{"port out of range", func(c *DBConfig) { c.Port = 70000 }},
+		{"empty database", func(c *DBConfig) { c.Database = "" }},
+		{"unknown tls mode", func(c *DBConfig) { c.TLS = "required" }},
+		{"custom tls without ca", func(c *DBConfig) { c.TLS = "custom" }},
+		{"both password and password_file", func(c *DBConfig) { c.Password, c.PasswordFile = "secret", passwordFile }},
+		{"negative pool size", func(c *DBConfig) { c.MaxIdleConns = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultDBConfig()
+			tt.modify(&c)
+			_, err := c.MySQLConfig()
+			assert.ErrorIs(t, err, ErrInvalidConfig)
+		})
+	}
+}
+
+func TestDBConfig_LogValue(t *testing.T) {
+	c := DefaultDBConfig()
+	c.User = "app"
+	c.Password = "secret"
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("Connecting to mysql", "config", c)
+	assert.Contains(t, buf.String(), "config.addr=upsider-db-1:3306")
+	assert.Contains(t, buf.String(), "config.user=app")
+	assert.NotContains(t, buf.String(), "secret")
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+type BankAccountSelector interface {
+	SelectBankAccounts(context.Context, string, string) ([]BankAccountRow, error)
+}
+
+type BankAccountSelectorFunc func(context.Context, string, string) ([]BankAccountRow, error)
+
+func (f BankAccountSelectorFunc) SelectBankAccounts(ctx context.Context, s1, s2 string) ([]BankAccountRow, error) {
+	return f(ctx, s1, s2)
+}
+
+// DocumentService gathers what is printed on an invoice.
+type DocumentService struct {
+	IDFinder            IDFinder
+	CompanySelector     CompanySelector
+	PartnerSelector     PartnerSelector
+	BankAccountSelector BankAccountSelector
+}
+
+// Document returns the document of the invoice identified by invoiceID with the errors of FindService.FindByID.
+func (s *DocumentService) Document(ctx context.Context, companyID, invoiceID string) (*InvoiceDocument, error) {
+	invoice, err := s.IDFinder.FindByID(ctx, companyID, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	company, err := s.CompanySelector.SelectCompany(ctx, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("document service error: %w", err)
+	}
+	if company == nil {
+		return nil, fmt.Errorf("document service error: %w", ErrCompanyNotFound)
+	}
+	partner, err := s.PartnerSelector.SelectPartner(ctx, invoice.PartnerID)
+	if err != nil {
+		return nil, fmt.Errorf("document service error: %w", err)
+	}
+	if partner == nil {
+		return nil, fmt.Errorf("document service error: %w", ErrPartnerNotFound)
+	}
+	rows, err := s.BankAccountSelector.SelectBankAccounts(ctx, companyID, invoice.PartnerID)
+	if err != nil {
+		return nil, fmt.Errorf("document service error: %w", err)
+	}
+	accounts := make([]domain.BankAccount, 0, len(rows))
+	for _, row := range rows {
+		accounts = append(accounts, *row.bankAccount())
+	}
+	return &InvoiceDocument{
+		Invoice:      invoice,
+		Issuer:       partner.partner(),
+		Customer:     company.company(),
+		BankAccounts: accounts,
+	}, nil
+}
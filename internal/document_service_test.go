@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentService_Document(t *testing.T) {
+	invoice := &domain.Invoice{InvoiceID: "1", CompanyID: "1", PartnerID: "2", Amount: 10000}
+	finder := IDFinderFunc(func(context.Context, string, string) (*domain.Invoice, error) {
+		return invoice, nil
+	})
+	accounts := BankAccountSelectorFunc(func(_ context.Context, companyID, partnerID string) ([]BankAccountRow, error) {
+		assert.Equal(t, "1", companyID)
+		assert.Equal(t, "2", partnerID)
+		return []BankAccountRow{{BankAccountID: "3", PartnerID: partnerID, CompanyID: companyID, BankName: "みずほ銀行", BranchName: "渋谷支店", AccountType: "ordinary", AccountNumber: "1234567", HolderNameKana: "ｶ)ｻﾝﾌﾟﾙ"}}, nil
+	})
+
+	t.Run("gathers the invoice, customer, issuer and bank accounts", func(t *testing.T) {
+		s := &DocumentService{IDFinder: finder, CompanySelector: existingCompany, PartnerSelector: partnerOfCompany1, BankAccountSelector: accounts}
+		got, err := s.Document(context.Background(), "1", "1")
+		require.NoError(t, err)
+		assert.Equal(t, invoice, got.Invoice)
+		assert.Equal(t, "1", got.Customer.CompanyID)
+		assert.Equal(t, "2", got.Issuer.PartnerID)
+		assert.Equal(t, []domain.BankAccount{{BankAccountID: "3", PartnerID: "2", CompanyID: "1", BankName: "みずほ銀行", BranchName: "渋谷支店", AccountType: domain.Ordinary, AccountNumber: "1234567", HolderNameKana: "ｶ)ｻﾝﾌﾟﾙ"}}, got.BankAccounts)
+	})
+
+	t.Run("errors of the finder are returned as is", func(t *testing.T) {
+		s := &DocumentService{IDFinder: IDFinderFunc(func(context.Context, string, string) (*domain.Invoice, error) {
+			return nil, ErrNotFound
+		})}
+		_, err := s.Document(context.Background(), "1", "1")
+		assert.Equal(t, ErrNotFound, err)
+	})
+
+	t.Run("missing partner", func(t *testing.T) {
+		s := &DocumentService{IDFinder: finder, CompanySelector: existingCompany, PartnerSelector: PartnerSelectorFunc(func(context.Context, string) (*PartnerRow, error) {
+			return nil, nil
+		}), BankAccountSelector: accounts}
+		_, err := s.Document(context.Background(), "1", "1")
+		assert.ErrorIs(t, err, ErrPartnerNotFound)
+	})
+
+	t.Run("bank account selector returns error", func(t *testing.T) {
+		s := &DocumentService{IDFinder: finder, CompanySelector: existingCompany, PartnerSelector: partnerOfCompany1, BankAccountSelector: BankAccountSelectorFunc(func(context.Context, string, string) ([]BankAccountRow, error) {
+			return nil, errors.New("this is test")
+		})}
+		_, err := s.Document(context.Background(), "1", "1")
+		assert.Equal(t, fmt.Errorf("document service error: %w", errors.New("this is test")), err)
+	})
+}
@@ -0,0 +1,108 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAPIKey(t *testing.T) {
+	now := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		companyID string
+		keyName   string
+		scopes    []Scope
+		expiresAt time.Time
+		wantErr   bool
+	}{
+		{
+			name:      "valid key",
+			companyID: "1",
+			keyName:   "batch",
+			scopes:    []Scope{ScopeInvoicesRead, ScopeInvoicesWrite},
+			expiresAt: now.Add(time.Hour),
+		},
+		{
+			name:      "empty company",
+			keyName:   "batch",
+			scopes:    []Scope{ScopeInvoicesRead},
+			expiresAt: now.Add(time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "empty name",
+			companyID: "1",
+			scopes:    []Scope{ScopeInvoicesRead},
+			expiresAt: now.Add(time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "no scopes",
+			companyID: "1",
+			keyName:   "batch",
+			expiresAt: now.Add(time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "unknown scope",
+			companyID: "1",
+			keyName:   "batch",
+			scopes:    []Scope{"companies:write"},
+			expiresAt: now.Add(time.Hour),
+			wantErr:   true,
+		},
+		{
+			name:      "already expired",
+			companyID: "1",
+			keyName:   "batch",
+			scopes:    []Scope{ScopeInvoicesRead},
+			expiresAt: now,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, plain, err := NewAPIKey(tt.companyID, tt.keyName, tt.scopes, now, tt.expiresAt)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidAPIKey)
+				return
+			}
+			require.NoError(t, err)
+			assert.True(t, strings.HasPrefix(plain, key.Prefix))
+			assert.Equal(t, HashAPIKey(plain), key.Hash)
+			assert.NotContains(t, string(key.Hash), plain)
+			assert.Equal(t, tt.scopes, key.Scopes)
+		})
+	}
+
+	_, plain1, err := NewAPIKey("1", "batch", []Scope{ScopeInvoicesRead}, now, now.Add(time.Hour))
+	require.NoError(t, err)
+	_, plain2, err := NewAPIKey("1", "batch", []Scope{ScopeInvoicesRead}, now, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.NotEqual(t, plain1, plain2)
+}
+
+func TestAPIKey_Active(t *testing.T) {
+	now := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	revokedAt := now.Add(-time.Minute)
+	assert.True(t, (&APIKey{ExpiresAt: now.Add(time.Second)}).Active(now))
+	assert.False(t, (&APIKey{ExpiresAt: now}).Active(now))
+	assert.False(t, (&APIKey{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}).Active(now))
+}
+
+func TestParseScopes(t *testing.T) {
+	got, err := ParseScopes("invoices:read, invoices:write,invoices:approve")
+	require.NoError(t, err)
+	assert.Equal(t, []Scope{ScopeInvoicesRead, ScopeInvoicesWrite, ScopeInvoicesApprove}, got)
+
+	got, err = ParseScopes("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = ParseScopes("invoices:delete")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
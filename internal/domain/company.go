@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// Company is a customer company (取引先) that invoices are issued for.
+type Company struct {
+	CompanyID      string
+	Name           string
+	Representative string
+	Phone          string
+	PostalCode     string
+	Address        string
+	// RegistrationNumber is the number of the company registered as a qualified invoice issuer (適格請求書発行事業者).
+	RegistrationNumber string
+	// QualifiedIssuer requires the invoices of the company to be qualified invoices, see Invoice.Qualify.
+	QualifiedIssuer bool
+	// DueDatePolicy moves the due dates of the invoices falling on bank holidays. Empty means NextBusinessDay.
+	DueDatePolicy DueDatePolicy
+}
+
+// DueDatePolicy is how a due date falling on a bank holiday is moved to a business day.
+type DueDatePolicy string
+
+const (
+	NextBusinessDay     = DueDatePolicy("next")     // 翌営業日
+	PreviousBusinessDay = DueDatePolicy("previous") // 前営業日
+)
+
+func (p DueDatePolicy) Valid() bool {
+	return p == NextBusinessDay || p == PreviousBusinessDay
+}
+
+var ErrInvalidCompany = errors.New("invalid company")
+
+var (
+	postalCodePattern = regexp.MustCompile(`^\d{3}-?\d{4}$`)
+	phonePattern      = regexp.MustCompile(`^\+?[\d-]{10,15}$`)
+)
+
+// Validate reports the first field that doesn't satisfy the rules as an error wrapping ErrInvalidCompany.
+func (c *Company) Validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("%w: 'name' mustn't be empty", ErrInvalidCompany)
+	}
+	if c.PostalCode != "" && !postalCodePattern.MatchString(c.PostalCode) {
+		return fmt.Errorf("%w: 'postal_code' must be formatted as NNN-NNNN, but got %v", ErrInvalidCompany, c.PostalCode)
+	}
+	if c.Phone != "" && !phonePattern.MatchString(c.Phone) {
+		return fmt.Errorf("%w: 'phone' must consist of digits and hyphens, but got %v", ErrInvalidCompany, c.Phone)
+	}
+	if c.RegistrationNumber != "" && ValidateRegistrationNumber(c.RegistrationNumber) != nil {
+		return fmt.Errorf("%w: 'registration_number' must be T followed by 13 digits with a valid check digit, but got %v", ErrInvalidCompany, c.RegistrationNumber)
+	}
+	if c.QualifiedIssuer && c.RegistrationNumber == "" {
+		return fmt.Errorf("%w: 'registration_number' mustn't be empty for a qualified issuer", ErrInvalidCompany)
+	}
+	if c.DueDatePolicy != "" && !c.DueDatePolicy.Valid() {
+		return fmt.Errorf("%w: 'due_date_policy' must be one of [next, previous], but got %v", ErrInvalidCompany, c.DueDatePolicy)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompany_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		company Company
+		wantErr bool
+	}{
+		{name: "valid", company: Company{Name: "Example Inc.", PostalCode: "100-0001", Phone: "03-1234-5678"}},
+		{name: "valid without optional fields", company: Company{Name: "Example Inc."}},
+		{name: "postal code without hyphen", company: Company{Name: "Example Inc.", PostalCode: "1000001"}},
+		{name: "empty name", company: Company{}, wantErr: true},
+		{name: "invalid postal code", company: Company{Name: "Example Inc.", PostalCode: "100-01"}, wantErr: true},
+		{name: "invalid phone", company: Company{Name: "Example Inc.", Phone: "phone"}, wantErr: true},
+		{name: "qualified issuer", company: Company{Name: "Example Inc.", RegistrationNumber: "T7000012050002", QualifiedIssuer: true}},
+		{name: "registration number with wrong check digit", company: Company{Name: "Example Inc.", RegistrationNumber: "T1234567890123"}, wantErr: true},
+		{name: "qualified issuer without registration number", company: Company{Name: "Example Inc.", QualifiedIssuer: true}, wantErr: true},
+		{name: "due dates moved to previous business days", company: Company{Name: "Example Inc.", DueDatePolicy: PreviousBusinessDay}},
+		{name: "unknown due date policy", company: Company{Name: "Example Inc.", DueDatePolicy: "nearest"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.company.Validate()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidCompany), err)
+		})
+	}
+}
@@ -7,44 +7,70 @@ import (
 type Invoice struct {
 	InvoiceID string
 	CompanyID string
+	PartnerID string
 	IssueDate time.Time
 	Amount    int
 	Fee       int
-	FeeRate   float32
+	FeeRate   Rate
 	Tax       int
-	TaxRate   float32
-	Total     int
-	DueDate   time.Time
-	Status    Status
+	TaxRate   Rate
+	// WithholdingTax is the income tax the company withholds from the payment and pays to the tax office instead,
+	// which is deducted from Total. It's set by Withhold.
+	WithholdingTax int
+	Total          int
+	DueDate        time.Time
+	Status         Status
+	// TaxAmounts is the breakdown of Tax per tax rate.
+	TaxAmounts TaxAmounts
+	// RegistrationNumber is set by Qualify when the invoice is a qualified invoice.
+	RegistrationNumber string
+	// Lines are the items of an itemized invoice. Invoices of a single amount have none.
+	Lines []InvoiceLine
 }
 
-type Status string
-
 const (
-	Unprocessed = Status("unprocessed")
-	Processing  = Status("processing")
-	Paid        = Status("paid")
-	Error       = Status("error")
+	feeRate = Rate(400)  // 4%
+	taxRate = Rate(1000) // 10%
 )
 
-const (
-	feeRate = 0.04
-	taxRate = 0.1
-)
+// Rates are the fee and tax rates applied to an invoice, and how their results are rounded to yen.
+type Rates struct {
+	FeeRate  Rate
+	TaxRate  Rate
+	Rounding RoundingMode
+}
+
+// DefaultRates are used for companies without a negotiated contract.
+var DefaultRates = Rates{FeeRate: feeRate, TaxRate: taxRate, Rounding: Floor}
 
-func NewInvoice(issueDate, dueDate time.Time, amount int, status string) *Invoice {
-	fee := int(float32(amount) * feeRate)
-	tax := int(float32(fee) * taxRate)
+// NewInvoice computes the fee and the tax of an invoice. When lines are given, amount is ignored and derived from
+// them instead: Amount is the sum of the lines before tax, and the lines are taxed per tax category together with
+// the fee. Otherwise amount is paid as is and only the fee is taxed.
+func NewInvoice(issueDate, dueDate time.Time, amount int, lines []InvoiceLine, status string, rates Rates) *Invoice {
+	taxable := make(map[Rate]int)
+	if len(lines) > 0 {
+		amount = 0
+		for _, line := range lines {
+			amount += line.Amount()
+			taxable[rates.TaxRateOf(line.TaxCategory)] += line.Amount()
+		}
+	}
+	fee := rates.FeeRate.Apply(amount, rates.Rounding)
+	taxable[rates.TaxRate] += fee
+	taxAmounts := NewTaxAmounts(taxable, rates.Rounding)
+	tax := taxAmounts.Total()
 	total := amount + fee + tax
 	return &Invoice{
-		IssueDate: issueDate,
-		Amount:    amount,
-		Fee:       fee,
-		FeeRate:   feeRate,
-		Tax:       tax,
-		TaxRate:   taxRate,
-		Total:     total,
-		DueDate:   dueDate,
-		Status:    Status(status),
+		IssueDate:  issueDate,
+		Amount:     amount,
+		Fee:        fee,
+		FeeRate:    rates.FeeRate,
+		Tax:        tax,
+		TaxRate:    rates.TaxRate,
+		Total:      total,
+		DueDate:    dueDate,
+		Status:     Status(status),
+		TaxAmounts: taxAmounts,
+		Lines:      lines,
 	}
 }
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"testing"
+	"testing/quick"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewInvoice(t *testing.T) {
+	issueDate := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	dueDate := time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		amount    int
+		rates     Rates
+		wantFee   int
+		wantTax   int
+		wantTotal int
+	}{
+		{name: "default rates", amount: 10000, rates: DefaultRates, wantFee: 400, wantTax: 40, wantTotal: 10440},
+		{name: "floor", amount: 10001, rates: DefaultRates, wantFee: 400, wantTax: 40, wantTotal: 10441},
+		{name: "ceil", amount: 10001, rates: Rates{FeeRate: 400, TaxRate: 1000, Rounding: Ceil}, wantFee: 401, wantTax: 41, wantTotal: 10443},
+		{name: "half up", amount: 12345, rates: Rates{FeeRate: 400, TaxRate: 1000, Rounding: RoundHalfUp}, wantFee: 494, wantTax: 49, wantTotal: 12888},
+		{name: "large amount", amount: 987654321, rates: DefaultRates, wantFee: 39506172, wantTax: 3950617, wantTotal: 1031111110},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewInvoice(issueDate, dueDate, tt.amount, nil, "unprocessed", tt.rates)
+			assert.Equal(t, &Invoice{
+				IssueDate:  issueDate,
+				Amount:     tt.amount,
+				Fee:        tt.wantFee,
+				FeeRate:    tt.rates.FeeRate,
+				Tax:        tt.wantTax,
+				TaxRate:    tt.rates.TaxRate,
+				Total:      tt.wantTotal,
+				DueDate:    dueDate,
+				Status:     Unprocessed,
+				TaxAmounts: TaxAmounts{{Rate: tt.rates.TaxRate, Taxable: tt.wantFee, Tax: tt.wantTax}},
+			}, got)
+		})
+	}
+}
+
+func TestNewInvoice_Lines(t *testing.T) {
+	date := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	lines := []InvoiceLine{
+		{Description: "お弁当", Quantity: 3, UnitPrice: 1001, TaxCategory: ReducedTax},
+		{Description: "飲料", Quantity: 1, UnitPrice: 149, TaxCategory: ReducedTax},
+		{Description: "配送料", Quantity: 1, UnitPrice: 1000, TaxCategory: StandardTax},
+	}
+	got := NewInvoice(date, date, 99999, lines, "unprocessed", DefaultRates)
+	// The amount is derived from the lines, and the fee of 4% is taxed at the standard rate together with the delivery.
+	assert.Equal(t, 4152, got.Amount)
+	assert.Equal(t, 166, got.Fee)
+	assert.Equal(t, TaxAmounts{
+		{Rate: 1000, Taxable: 1166, Tax: 116},
+		// 8% of 3152 is 252.16, rounded once rather than per line.
+		{Rate: 800, Taxable: 3152, Tax: 252},
+	}, got.TaxAmounts)
+	assert.Equal(t, 368, got.Tax)
+	assert.Equal(t, 4152+166+368, got.Total)
+	assert.Equal(t, lines, got.Lines)
+}
+
+// TestNewInvoice_Property checks the invariants the invoice table's CHECK constraints rely on for arbitrary inputs.
+func TestNewInvoice_Property(t *testing.T) {
+	modes := []RoundingMode{Floor, RoundHalfUp, Ceil}
+	f := func(amount uint32, feeRate, taxRate uint16, mode uint8) bool {
+		rates := Rates{
+			FeeRate:  Rate(feeRate % (RateScale + 1)),
+			TaxRate:  Rate(taxRate % (RateScale + 1)),
+			Rounding: modes[int(mode)%len(modes)],
+		}
+		invoice := NewInvoice(time.Time{}, time.Time{}, int(amount), nil, "unprocessed", rates)
+		// |amount * fee_rate - fee| < 1 and |fee * tax_rate - tax| < 1, with exact integer arithmetic.
+		feeDiff := int64(invoice.Amount)*int64(invoice.FeeRate) - int64(invoice.Fee)*RateScale
+		taxDiff := int64(invoice.Fee)*int64(invoice.TaxRate) - int64(invoice.Tax)*RateScale
+		return invoice.Amount+invoice.Fee+invoice.Tax == invoice.Total &&
+			feeDiff > -RateScale && feeDiff < RateScale &&
+			taxDiff > -RateScale && taxDiff < RateScale
+	}
+	require.NoError(t, quick.Check(f, &quick.Config{MaxCount: 10000}))
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// TaxCategory is the consumption tax category of an invoice line.
+type TaxCategory string
+
+const (
+	StandardTax = TaxCategory("standard") // 標準税率
+	ReducedTax  = TaxCategory("reduced")  // 軽減税率, applied to food and newspapers
+)
+
+func (c TaxCategory) Valid() bool {
+	return c == StandardTax || c == ReducedTax
+}
+
+// ReducedTaxRate is the rate of ReducedTax, which isn't negotiated per contract unlike the standard rate.
+const ReducedTaxRate = Rate(800) // 8%
+
+// InvoiceLine is an item of an itemized invoice. The amounts are exclusive of consumption tax.
+type InvoiceLine struct {
+	Description string      `json:"description"`
+	Quantity    int         `json:"quantity"`
+	UnitPrice   int         `json:"unit_price"`
+	TaxCategory TaxCategory `json:"tax_category"`
+}
+
+var ErrInvalidLine = errors.New("invalid invoice line")
+
+// Amount is the amount of the line before tax.
+func (l *InvoiceLine) Amount() int {
+	return l.Quantity * l.UnitPrice
+}
+
+func (l *InvoiceLine) Validate() error {
+	if l.Description == "" {
+		return fmt.Errorf("%w: 'description' mustn't be empty", ErrInvalidLine)
+	}
+	if l.Quantity <= 0 {
+		return fmt.Errorf("%w: 'quantity' must be positive, but got %v", ErrInvalidLine, l.Quantity)
+	}
+	if l.UnitPrice <= 0 {
+		return fmt.Errorf("%w: 'unit_price' must be positive, but got %v", ErrInvalidLine, l.UnitPrice)
+	}
+	if !l.TaxCategory.Valid() {
+		return fmt.Errorf("%w: 'tax_category' must be one of [standard, reduced], but got %v", ErrInvalidLine, l.TaxCategory)
+	}
+	return nil
+}
+
+// TaxRateOf returns the tax rate applied to the lines of category.
+func (r Rates) TaxRateOf(category TaxCategory) Rate {
+	if category == ReducedTax {
+		return ReducedTaxRate
+	}
+	return r.TaxRate
+}
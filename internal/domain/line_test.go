@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvoiceLine_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    InvoiceLine
+		wantErr bool
+	}{
+		{name: "valid", line: InvoiceLine{Description: "お弁当", Quantity: 3, UnitPrice: 800, TaxCategory: ReducedTax}},
+		{name: "empty description", line: InvoiceLine{Quantity: 1, UnitPrice: 800, TaxCategory: ReducedTax}, wantErr: true},
+		{name: "zero quantity", line: InvoiceLine{Description: "お弁当", UnitPrice: 800, TaxCategory: ReducedTax}, wantErr: true},
+		{name: "zero unit price", line: InvoiceLine{Description: "お弁当", Quantity: 1, TaxCategory: ReducedTax}, wantErr: true},
+		{name: "negative unit price", line: InvoiceLine{Description: "値引き", Quantity: 1, UnitPrice: -500, TaxCategory: StandardTax}, wantErr: true},
+		{name: "unknown tax category", line: InvoiceLine{Description: "お弁当", Quantity: 1, UnitPrice: 800, TaxCategory: "exempt"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.line.Validate()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidLine), err)
+		})
+	}
+}
+
+func TestRates_TaxRateOf(t *testing.T) {
+	rates := Rates{FeeRate: 300, TaxRate: 1000, Rounding: Floor}
+	assert.Equal(t, Rate(1000), rates.TaxRateOf(StandardTax))
+	assert.Equal(t, ReducedTaxRate, rates.TaxRateOf(ReducedTax))
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// BusinessPartner is a payee (支払先) that a company pays vendor invoices to.
+type BusinessPartner struct {
+	PartnerID  string
+	CompanyID  string
+	Name       string
+	Phone      string
+	PostalCode string
+	Address    string
+	// Withholding tells whether income tax is withheld from the payments to the partner.
+	Withholding WithholdingCategory
+}
+
+var ErrInvalidPartner = errors.New("invalid business partner")
+
+func (p *BusinessPartner) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("%w: 'name' mustn't be empty", ErrInvalidPartner)
+	}
+	if p.PostalCode != "" && !postalCodePattern.MatchString(p.PostalCode) {
+		return fmt.Errorf("%w: 'postal_code' must be formatted as NNN-NNNN, but got %v", ErrInvalidPartner, p.PostalCode)
+	}
+	if p.Phone != "" && !phonePattern.MatchString(p.Phone) {
+		return fmt.Errorf("%w: 'phone' must consist of digits and hyphens, but got %v", ErrInvalidPartner, p.Phone)
+	}
+	if !p.Withholding.Valid() {
+		return fmt.Errorf("%w: 'withholding' must be one of [none, professional], but got %v", ErrInvalidPartner, p.Withholding)
+	}
+	return nil
+}
+
+type AccountType string
+
+const (
+	Ordinary = AccountType("ordinary") // 普通
+	Checking = AccountType("checking") // 当座
+	Savings  = AccountType("savings")  // 貯蓄
+)
+
+// BankAccount is a bank account of a business partner that transfers are made to.
+type BankAccount struct {
+	BankAccountID  string
+	PartnerID      string
+	CompanyID      string
+	BankName       string
+	BranchName     string
+	AccountType    AccountType
+	AccountNumber  string
+	HolderNameKana string
+}
+
+var ErrInvalidBankAccount = errors.New("invalid bank account")
+
+var (
+	accountNumberPattern = regexp.MustCompile(`^\d{7}$`)
+	// Zengin transfers accept full-width or half-width katakana, digits, latin capitals and a few symbols for holder names.
+	holderNameKanaPattern = regexp.MustCompile(`^[\p{Katakana}ー･ｰﾞﾟ0-9A-Z ()（）.\-　]+$`)
+)
+
+func (a *BankAccount) Validate() error {
+	if a.BankName == "" {
+		return fmt.Errorf("%w: 'bank_name' mustn't be empty", ErrInvalidBankAccount)
+	}
+	if a.BranchName == "" {
+		return fmt.Errorf("%w: 'branch_name' mustn't be empty", ErrInvalidBankAccount)
+	}
+	if a.AccountType != Ordinary && a.AccountType != Checking && a.AccountType != Savings {
+		return fmt.Errorf("%w: 'account_type' must be one of [ordinary, checking, savings], but got %v", ErrInvalidBankAccount, a.AccountType)
+	}
+	if !accountNumberPattern.MatchString(a.AccountNumber) {
+		return fmt.Errorf("%w: 'account_number' must be 7 digits, but got %v", ErrInvalidBankAccount, a.AccountNumber)
+	}
+	if !holderNameKanaPattern.MatchString(a.HolderNameKana) {
+		return fmt.Errorf("%w: 'holder_name_kana' must be written in katakana, but got %v", ErrInvalidBankAccount, a.HolderNameKana)
+	}
+	return nil
+}
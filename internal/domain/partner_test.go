@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusinessPartner_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		partner BusinessPartner
+		wantErr bool
+	}{
+		{name: "valid", partner: BusinessPartner{Name: "Vendor Inc.", PostalCode: "100-0001", Phone: "03-1234-5678", Withholding: NoWithholding}},
+		{name: "sole proprietor with withholding", partner: BusinessPartner{Name: "山田デザイン事務所", Withholding: ProfessionalWithholding}},
+		{name: "empty name", partner: BusinessPartner{Withholding: NoWithholding}, wantErr: true},
+		{name: "invalid postal code", partner: BusinessPartner{Name: "Vendor Inc.", PostalCode: "1", Withholding: NoWithholding}, wantErr: true},
+		{name: "invalid phone", partner: BusinessPartner{Name: "Vendor Inc.", Phone: "phone", Withholding: NoWithholding}, wantErr: true},
+		{name: "unknown withholding", partner: BusinessPartner{Name: "Vendor Inc.", Withholding: "salary"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.partner.Validate()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidPartner), err)
+		})
+	}
+}
+
+func TestBankAccount_Validate(t *testing.T) {
+	valid := BankAccount{BankName: "みずほ銀行", BranchName: "東京営業部", AccountType: Ordinary, AccountNumber: "1234567", HolderNameKana: "カ）サンプル"}
+	tests := []struct {
+		name    string
+		modify  func(*BankAccount)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*BankAccount) {}},
+		{name: "half-width kana holder", modify: func(a *BankAccount) { a.HolderNameKana = "ｶ)ｻﾝﾌﾟﾙ" }},
+		{name: "empty bank name", modify: func(a *BankAccount) { a.BankName = "" }, wantErr: true},
+		{name: "empty branch name", modify: func(a *BankAccount) { a.BranchName = "" }, wantErr: true},
+		{name: "unknown account type", modify: func(a *BankAccount) { a.AccountType = "UNKNOWN" }, wantErr: true},
+		{name: "short account number", modify: func(a *BankAccount) { a.AccountNumber = "123456" }, wantErr: true},
+		{name: "non-digit account number", modify: func(a *BankAccount) { a.AccountNumber = "123456a" }, wantErr: true},
+		{name: "kanji holder", modify: func(a *BankAccount) { a.HolderNameKana = "株式会社サンプル" }, wantErr: true},
+		{name: "empty holder", modify: func(a *BankAccount) { a.HolderNameKana = "" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			account := valid
+			tt.modify(&account)
+			err := account.Validate()
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidBankAccount), err)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// The qualified invoice system (適格請求書等保存方式) has required since October 2023 that invoices state the
+// registration number of the issuer and the consumption tax per tax rate, rounded once per rate on each invoice.
+
+var (
+	ErrInvalidRegistrationNumber = errors.New("invalid registration number")
+	ErrNonQualifiedInvoice       = errors.New("invoice doesn't satisfy the qualified invoice system")
+)
+
+var registrationNumberPattern = regexp.MustCompile(`^T\d{13}$`)
+
+// ValidateRegistrationNumber checks a registration number (登録番号), which is T followed by the 13 digit corporate
+// number or the number assigned to a sole proprietor. The first of the digits is the check digit of the other 12.
+func ValidateRegistrationNumber(s string) error {
+	if !registrationNumberPattern.MatchString(s) {
+		return fmt.Errorf("%w: must be T followed by 13 digits, but got %v", ErrInvalidRegistrationNumber, s)
+	}
+	digits := s[1:]
+	if int(digits[0]-'0') != checkDigit(digits[1:]) {
+		return fmt.Errorf("%w: check digit of %v doesn't match", ErrInvalidRegistrationNumber, s)
+	}
+	return nil
+}
+
+// checkDigit computes the check digit of the 12 digit base number of a corporate number, which weighs the digits
+// by 1 and 2 alternately from the lowest one.
+func checkDigit(base string) int {
+	sum := 0
+	for i := range len(base) {
+		weight := 1
+		if i%2 == 1 {
+			weight = 2
+		}
+		sum += int(base[len(base)-1-i]-'0') * weight
+	}
+	return 9 - sum%9
+}
+
+// TaxAmount is the consumption tax of the amounts taxed at Rate on an invoice.
+type TaxAmount struct {
+	Rate    Rate `json:"rate"`
+	Taxable int  `json:"taxable"`
+	Tax     int  `json:"tax"`
+}
+
+// TaxAmounts is the per-rate breakdown of the tax of an invoice in descending order of the rate.
+type TaxAmounts []TaxAmount
+
+// NewTaxAmounts sums taxable up per rate and rounds the tax once per rate.
+func NewTaxAmounts(taxable map[Rate]int, mode RoundingMode) TaxAmounts {
+	amounts := make(TaxAmounts, 0, len(taxable))
+	for rate, amount := range taxable {
+		amounts = append(amounts, TaxAmount{Rate: rate, Taxable: amount, Tax: rate.Apply(amount, mode)})
+	}
+	slices.SortFunc(amounts, func(a, b TaxAmount) int { return int(b.Rate - a.Rate) })
+	return amounts
+}
+
+// Total is the tax of the invoice.
+func (a TaxAmounts) Total() int {
+	total := 0
+	for _, amount := range a {
+		total += amount.Tax
+	}
+	return total
+}
+
+// Value stores the breakdown as a JSON array.
+func (a TaxAmounts) Value() (driver.Value, error) {
+	if a == nil {
+		a = TaxAmounts{}
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads JSON columns, which the mysql driver returns as []byte.
+func (a *TaxAmounts) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	}
+	return fmt.Errorf("can't scan %T as tax amounts", src)
+}
+
+// Qualify makes the invoice a qualified invoice (適格請求書) of the issuer with registrationNumber.
+// ErrNonQualifiedInvoice is returned when the invoice lacks what a qualified invoice must state.
+func (i *Invoice) Qualify(registrationNumber string) error {
+	if err := ValidateRegistrationNumber(registrationNumber); err != nil {
+		return fmt.Errorf("%w: %v", ErrNonQualifiedInvoice, err)
+	}
+	if len(i.TaxAmounts) == 0 {
+		return fmt.Errorf("%w: tax per tax rate is missing", ErrNonQualifiedInvoice)
+	}
+	for _, amount := range i.TaxAmounts {
+		// The tax may differ from the exact product by less than 1 yen whatever the rounding mode is.
+		if diff := int64(amount.Taxable)*int64(amount.Rate) - int64(amount.Tax)*RateScale; diff <= -RateScale || diff >= RateScale {
+			return fmt.Errorf("%w: tax at %v%% isn't rounded once from %v", ErrNonQualifiedInvoice, amount.Rate.Percent(), amount.Taxable)
+		}
+	}
+	if total := i.TaxAmounts.Total(); total != i.Tax {
+		return fmt.Errorf("%w: tax %v doesn't equal the sum of tax per tax rate %v", ErrNonQualifiedInvoice, i.Tax, total)
+	}
+	i.RegistrationNumber = registrationNumber
+	return nil
+}
@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRegistrationNumber(t *testing.T) {
+	tests := []struct {
+		name    string
+		number  string
+		wantErr bool
+	}{
+		// The corporate number of the National Tax Agency.
+		{name: "valid", number: "T7000012050002"},
+		{name: "valid with check digit 1", number: "T1180301018771"},
+		{name: "wrong check digit", number: "T8000012050002", wantErr: true},
+		{name: "without T", number: "7000012050002", wantErr: true},
+		{name: "12 digits", number: "T700001205000", wantErr: true},
+		{name: "full-width digits", number: "T７０００１２０５０００２", wantErr: true},
+		{name: "empty", number: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRegistrationNumber(tt.number)
+			assert.Equal(t, tt.wantErr, errors.Is(err, ErrInvalidRegistrationNumber), err)
+		})
+	}
+}
+
+func TestNewTaxAmounts(t *testing.T) {
+	got := NewTaxAmounts(map[Rate]int{MustParseRate("0.08"): 1999, MustParseRate("0.1"): 1999}, Floor)
+	assert.Equal(t, TaxAmounts{
+		{Rate: MustParseRate("0.1"), Taxable: 1999, Tax: 199},
+		{Rate: MustParseRate("0.08"), Taxable: 1999, Tax: 159},
+	}, got)
+	assert.Equal(t, 358, got.Total())
+}
+
+func TestTaxAmounts_Scan(t *testing.T) {
+	want := TaxAmounts{{Rate: MustParseRate("0.1"), Taxable: 400, Tax: 40}}
+	v, err := want.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `[{"rate":0.1,"taxable":400,"tax":40}]`, v)
+
+	var got TaxAmounts
+	require.NoError(t, got.Scan([]byte(`[{"rate": 0.1000, "taxable": 400, "tax": 40}]`)))
+	assert.Equal(t, want, got)
+	assert.Error(t, got.Scan(1))
+}
+
+func TestInvoice_Qualify(t *testing.T) {
+	date := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		invoice func() *Invoice
+		number  string
+		wantErr bool
+	}{
+		{name: "qualified", invoice: func() *Invoice { return NewInvoice(date, date, 10000, nil, "unprocessed", DefaultRates) }, number: "T7000012050002"},
+		{name: "invalid registration number", invoice: func() *Invoice { return NewInvoice(date, date, 10000, nil, "unprocessed", DefaultRates) }, number: "T1234567890123", wantErr: true},
+		{name: "without tax amounts", invoice: func() *Invoice {
+			invoice := NewInvoice(date, date, 10000, nil, "unprocessed", DefaultRates)
+			invoice.TaxAmounts = nil
+			return invoice
+		}, number: "T7000012050002", wantErr: true},
+		{name: "tax rounded per item", invoice: func() *Invoice {
+			invoice := NewInvoice(date, date, 10000, nil, "unprocessed", DefaultRates)
+			invoice.TaxAmounts[0].Tax = 39
+			return invoice
+		}, number: "T7000012050002", wantErr: true},
+		{name: "tax differs from the breakdown", invoice: func() *Invoice {
+			invoice := NewInvoice(date, date, 10000, nil, "unprocessed", DefaultRates)
+			invoice.Tax = 41
+			return invoice
+		}, number: "T7000012050002", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := tt.invoice()
+			err := invoice.Qualify(tt.number)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrNonQualifiedInvoice)
+				assert.Empty(t, invoice.RegistrationNumber)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.number, invoice.RegistrationNumber)
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// rateDigits is the number of fractional digits a Rate holds, which matches the DECIMAL(5, 4) columns.
+const rateDigits = 4
+
+// RateScale is the number of Rate units in 1 (=100%).
+const RateScale = 10000
+
+// Rate is a fixed-point decimal rate such as a fee or tax rate, in units of 1/RateScale.
+// It avoids the rounding errors of float arithmetic when applied to amounts.
+type Rate int64
+
+var ErrInvalidRate = errors.New("invalid rate")
+
+// ParseRate parses a decimal string such as "0.04" exactly. More than four fractional digits is an error.
+func ParseRate(s string) (Rate, error) {
+	neg := strings.HasPrefix(s, "-")
+	intPart, fracPart, _ := strings.Cut(strings.TrimPrefix(s, "-"), ".")
+	if intPart == "" || len(fracPart) > rateDigits {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	fracPart += strings.Repeat("0", rateDigits-len(fracPart))
+	i, err := strconv.ParseUint(intPart, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	f, err := strconv.ParseUint(fracPart, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidRate, s)
+	}
+	r := Rate(i*RateScale + f)
+	if neg {
+		r = -r
+	}
+	return r, nil
+}
+
+// MustParseRate is like ParseRate but panics on error. It is meant for constants.
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// String formats the rate as a decimal without trailing zeros, e.g. "0.04".
+func (r Rate) String() string {
+	sign := ""
+	if r < 0 {
+		sign = "-"
+		r = -r
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%0*d", rateDigits, r%RateScale), "0")
+	if frac == "" {
+		return fmt.Sprintf("%s%d", sign, r/RateScale)
+	}
+	return fmt.Sprintf("%s%d.%s", sign, r/RateScale, frac)
+}
+
+// Percent formats the rate in percent without trailing zeros, e.g. "10.21".
+func (r Rate) Percent() string {
+	return (r * 100).String()
+}
+
+// Apply multiplies amount by the rate and rounds the result to an integer with mode.
+func (r Rate) Apply(amount int, mode RoundingMode) int {
+	product := int64(amount) * int64(r)
+	q, rem := product/RateScale, product%RateScale
+	// Go truncates toward zero, so normalize to floor division first.
+	if rem < 0 {
+		q--
+		rem += RateScale
+	}
+	switch mode {
+	case Ceil:
+		if rem > 0 {
+			q++
+		}
+	case RoundHalfUp:
+		if rem*2 >= RateScale {
+			q++
+		}
+	}
+	return int(q)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(b []byte) error {
+	parsed, err := ParseRate(strings.Trim(string(b), `"`))
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+// Value stores the rate as an exact decimal string.
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+// Scan reads DECIMAL columns, which the mysql driver returns as []byte.
+func (r *Rate) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return r.Scan(string(v))
+	case string:
+		parsed, err := ParseRate(v)
+		if err != nil {
+			return err
+		}
+		*r = parsed
+	case float64:
+		*r = Rate(math.Round(v * RateScale))
+	case int64:
+		*r = Rate(v * RateScale)
+	default:
+		return fmt.Errorf("%w: can't scan %T", ErrInvalidRate, src)
+	}
+	return nil
+}
+
+// RoundingMode decides how fractional yen are rounded when a rate is applied.
+type RoundingMode string
+
+const (
+	Floor       = RoundingMode("floor")
+	RoundHalfUp = RoundingMode("half_up")
+	Ceil        = RoundingMode("ceil")
+)
+
+func (m RoundingMode) Valid() bool {
+	return m == Floor || m == RoundHalfUp || m == Ceil
+}
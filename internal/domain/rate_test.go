@@ -0,0 +1,143 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Rate
+		wantErr bool
+	}{
+		{in: "0.04", want: 400},
+		{in: "0.1", want: 1000},
+		{in: "0.10", want: 1000},
+		{in: "0.0825", want: 825},
+		{in: "1", want: RateScale},
+		{in: "-0.5", want: -5000},
+		{in: "0.12345", wantErr: true},
+		{in: ".5", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "0.-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRate(tt.in)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidRate))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRate_String(t *testing.T) {
+	assert.Equal(t, "0.04", Rate(400).String())
+	assert.Equal(t, "0.1", Rate(1000).String())
+	assert.Equal(t, "0.0825", Rate(825).String())
+	assert.Equal(t, "1", Rate(RateScale).String())
+	assert.Equal(t, "0", Rate(0).String())
+	assert.Equal(t, "-0.5", Rate(-5000).String())
+}
+
+func TestRate_Apply(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   Rate
+		amount int
+		mode   RoundingMode
+		want   int
+	}{
+		{name: "exact", rate: 400, amount: 10000, mode: Floor, want: 400},
+		{name: "floor", rate: 400, amount: 10001, mode: Floor, want: 400},
+		{name: "ceil", rate: 400, amount: 10001, mode: Ceil, want: 401},
+		{name: "half up below half", rate: 400, amount: 10012, mode: RoundHalfUp, want: 400},
+		{name: "half up at half", rate: 1000, amount: 5, mode: RoundHalfUp, want: 1},
+		{name: "floor at half", rate: 1000, amount: 5, mode: Floor, want: 0},
+		{name: "large amount float32 can't represent", rate: 400, amount: 123456789, mode: Floor, want: 4938271},
+		{name: "negative amount floors toward minus infinity", rate: 1000, amount: -5, mode: Floor, want: -1},
+		{name: "negative amount ceils toward zero", rate: 1000, amount: -5, mode: Ceil, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rate.Apply(tt.amount, tt.mode))
+		})
+	}
+}
+
+// TestRate_Apply_Property checks Apply against exact rational arithmetic for arbitrary inputs.
+func TestRate_Apply_Property(t *testing.T) {
+	f := func(amount int32, rate uint16) bool {
+		r := Rate(rate % (RateScale + 1))
+		exact := new(big.Rat).Mul(big.NewRat(int64(amount), 1), big.NewRat(int64(r), RateScale))
+		floor, ceil := r.Apply(int(amount), Floor), r.Apply(int(amount), Ceil)
+		half := r.Apply(int(amount), RoundHalfUp)
+
+		// floor <= exact <= ceil, and they differ by at most one yen.
+		if big.NewRat(int64(floor), 1).Cmp(exact) > 0 || big.NewRat(int64(ceil), 1).Cmp(exact) < 0 || ceil-floor > 1 {
+			return false
+		}
+		if exact.IsInt() && floor != ceil {
+			return false
+		}
+		// half up picks ceil iff the fraction is at least one half.
+		frac := new(big.Rat).Sub(exact, big.NewRat(int64(floor), 1))
+		if frac.Cmp(big.NewRat(1, 2)) >= 0 {
+			return half == ceil
+		}
+		return half == floor
+	}
+	require.NoError(t, quick.Check(f, &quick.Config{MaxCount: 10000}))
+}
+
+func TestRate_JSON(t *testing.T) {
+	b, err := json.Marshal(struct {
+		Rate Rate `json:"rate"`
+	}{Rate: 400})
+	require.NoError(t, err)
+	assert.Equal(t, `{"rate":0.04}`, string(b))
+
+	var got struct {
+		Rate Rate `json:"rate"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(`{"rate":0.1}`), &got))
+	assert.Equal(t, Rate(1000), got.Rate)
+}
+
+func TestRate_Scan(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     any
+		want    Rate
+		wantErr bool
+	}{
+		{name: "decimal bytes", src: []byte("0.0400"), want: 400},
+		{name: "string", src: "0.10", want: 1000},
+		{name: "float64", src: 0.04, want: 400},
+		{name: "int64", src: int64(1), want: RateScale},
+		{name: "unsupported", src: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got Rate
+			err := got.Scan(tt.src)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
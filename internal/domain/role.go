@@ -0,0 +1,52 @@
+package domain
+
+// Role is what a user may do within its company.
+type Role string
+
+const (
+	Viewer     = Role("viewer")     // 閲覧のみ
+	Accountant = Role("accountant") // 請求書・支払先の登録
+	Approver   = Role("approver")   // 請求書のステータス変更(支払承認)
+	Admin      = Role("admin")      // 全操作
+)
+
+// Permission is an operation guarded by the policy layer.
+type Permission string
+
+const (
+	InvoicesRead         = Permission("invoices:read")
+	InvoicesCreate       = Permission("invoices:create")
+	InvoicesChangeStatus = Permission("invoices:change_status")
+	CompaniesRead        = Permission("companies:read")
+	CompaniesManage      = Permission("companies:manage")
+	PartnersRead         = Permission("partners:read")
+	PartnersManage       = Permission("partners:manage")
+
+	// CompaniesCreate isn't granted to any role, as a user of a company mustn't create other tenants.
+	// Companies are created by the company create subcommand.
+	CompaniesCreate = Permission("companies:create")
+)
+
+// rolePermissions is the single source of truth of who may do what. Add a permission here rather than
+// checking roles in handlers.
+var rolePermissions = map[Role][]Permission{
+	Viewer:     {InvoicesRead, CompaniesRead, PartnersRead},
+	Accountant: {InvoicesRead, InvoicesCreate, CompaniesRead, PartnersRead, PartnersManage},
+	Approver:   {InvoicesRead, InvoicesChangeStatus, CompaniesRead, PartnersRead},
+	Admin:      {InvoicesRead, InvoicesCreate, InvoicesChangeStatus, CompaniesRead, CompaniesManage, PartnersRead, PartnersManage},
+}
+
+func (r Role) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether the role is granted permission. Unknown roles are granted nothing.
+func (r Role) Can(permission Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRole_Can(t *testing.T) {
+	tests := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{Viewer, InvoicesRead, true},
+		{Viewer, InvoicesCreate, false},
+		{Viewer, InvoicesChangeStatus, false},
+		{Viewer, CompaniesManage, false},
+		{Accountant, InvoicesCreate, true},
+		{Accountant, InvoicesChangeStatus, false},
+		{Accountant, PartnersManage, true},
+		{Approver, InvoicesChangeStatus, true},
+		{Approver, InvoicesCreate, false},
+		{Admin, CompaniesManage, true},
+		{Admin, CompaniesCreate, false},
+		{Admin, InvoicesChangeStatus, true},
+		{Role("owner"), InvoicesRead, false},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role)+"/"+string(tt.permission), func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.role.Can(tt.permission))
+		})
+	}
+}
+
+func TestRole_Valid(t *testing.T) {
+	for _, role := range []Role{Viewer, Accountant, Approver, Admin} {
+		assert.True(t, role.Valid(), role)
+	}
+	assert.False(t, Role("owner").Valid())
+	assert.False(t, Role("").Valid())
+}
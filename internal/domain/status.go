@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+type Status string
+
+const (
+	Unprocessed = Status("unprocessed")
+	Processing  = Status("processing")
+	Paid        = Status("paid")
+	Error       = Status("error")
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// transitions lists the statuses each status may move to.
+// Paid is terminal, and an errored invoice can only be retried by moving it back to processing.
+var transitions = map[Status][]Status{
+	Unprocessed: {Processing, Error},
+	Processing:  {Paid, Error},
+	Error:       {Processing},
+	Paid:        {},
+}
+
+func (s Status) Valid() bool {
+	_, ok := transitions[s]
+	return ok
+}
+
+// TransitionTo returns next if s may move to it, or an error wrapping ErrInvalidTransition otherwise.
+func (s Status) TransitionTo(next Status) (Status, error) {
+	for _, allowed := range transitions[s] {
+		if allowed == next {
+			return next, nil
+		}
+	}
+	return s, fmt.Errorf("%w: from %s to %s", ErrInvalidTransition, s, next)
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus_TransitionTo(t *testing.T) {
+	tests := []struct {
+		from    Status
+		to      Status
+		wantErr bool
+	}{
+		{from: Unprocessed, to: Processing},
+		{from: Unprocessed, to: Error},
+		{from: Unprocessed, to: Paid, wantErr: true},
+		{from: Unprocessed, to: Unprocessed, wantErr: true},
+		{from: Processing, to: Paid},
+		{from: Processing, to: Error},
+		{from: Processing, to: Unprocessed, wantErr: true},
+		{from: Error, to: Processing},
+		{from: Error, to: Paid, wantErr: true},
+		{from: Error, to: Unprocessed, wantErr: true},
+		{from: Paid, to: Processing, wantErr: true},
+		{from: Paid, to: Error, wantErr: true},
+		{from: Paid, to: Unprocessed, wantErr: true},
+		{from: Status("UNKNOWN"), to: Processing, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.from)+" to "+string(tt.to), func(t *testing.T) {
+			got, err := tt.from.TransitionTo(tt.to)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidTransition))
+				assert.Equal(t, tt.from, got)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.to, got)
+		})
+	}
+}
+
+func TestStatus_Valid(t *testing.T) {
+	for _, s := range []Status{Unprocessed, Processing, Paid, Error} {
+		assert.True(t, s.Valid(), s)
+	}
+	assert.False(t, Status("UNKNOWN").Valid())
+	assert.False(t, Status("").Valid())
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User is an account that signs in to operate the invoices of its company.
+type User struct {
+	UserID       string
+	CompanyID    string
+	Username     string
+	PasswordHash []byte
+	Role         Role
+}
+
+var ErrInvalidUser = errors.New("invalid user")
+
+// minPasswordLength is a lower bound for passwords. bcrypt itself rejects passwords longer than 72 bytes.
+const minPasswordLength = 8
+
+// NewUser hashes password with bcrypt so that the plain password is never stored.
+func NewUser(companyID, username, password string, role Role) (*User, error) {
+	if companyID == "" {
+		return nil, fmt.Errorf("%w: 'company_id' mustn't be empty", ErrInvalidUser)
+	}
+	if username == "" {
+		return nil, fmt.Errorf("%w: 'username' mustn't be empty", ErrInvalidUser)
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: 'role' must be one of [viewer, accountant, approver, admin], but got %v", ErrInvalidUser, role)
+	}
+	if len(password) < minPasswordLength {
+		return nil, fmt.Errorf("%w: 'password' must be at least %d characters", ErrInvalidUser, minPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUser, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &User{CompanyID: companyID, Username: username, PasswordHash: hash, Role: role}, nil
+}
+
+// Authenticate reports whether password matches the stored hash. The comparison takes constant time.
+func (u *User) Authenticate(password string) bool {
+	return bcrypt.CompareHashAndPassword(u.PasswordHash, []byte(password)) == nil
+}
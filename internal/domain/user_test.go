@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewUser(t *testing.T) {
+	tests := []struct {
+		name      string
+		companyID string
+		username  string
+		password  string
+		role      Role
+		wantErr   bool
+	}{
+		{name: "valid", companyID: "1", username: "alice", password: "correct horse", role: Viewer},
+		{name: "empty company", username: "alice", password: "correct horse", role: Viewer, wantErr: true},
+		{name: "empty username", companyID: "1", password: "correct horse", role: Viewer, wantErr: true},
+		{name: "unknown role", companyID: "1", username: "alice", password: "correct horse", role: "owner", wantErr: true},
+		{name: "short password", companyID: "1", username: "alice", password: "short", role: Viewer, wantErr: true},
+		{name: "password longer than bcrypt accepts", companyID: "1", username: "alice", password: strings.Repeat("a", 73), role: Viewer, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewUser(tt.companyID, tt.username, tt.password, tt.role)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, ErrInvalidUser), err)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotContains(t, string(got.PasswordHash), tt.password)
+			assert.True(t, got.Authenticate(tt.password))
+			assert.False(t, got.Authenticate("wrong password"))
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package domain
+
+// Income tax (源泉所得税) must be withheld from fees paid to sole proprietors for professional services such as
+// writing, design, consulting or lecturing (報酬・料金, Article 204 of the Income Tax Act). The rates include the
+// special income tax for reconstruction (復興特別所得税).
+
+// WithholdingCategory tells whether income tax is withheld from the payments to a business partner.
+type WithholdingCategory string
+
+const (
+	NoWithholding = WithholdingCategory("none")
+	// ProfessionalWithholding is for sole proprietors paid for professional services.
+	ProfessionalWithholding = WithholdingCategory("professional")
+)
+
+func (c WithholdingCategory) Valid() bool {
+	return c == NoWithholding || c == ProfessionalWithholding
+}
+
+const (
+	// withholdingThreshold is the payment up to which withholdingRate applies.
+	withholdingThreshold = 1000000
+	withholdingRate      = Rate(1021) // 10.21%
+	// withholdingExcessRate applies to the excess over withholdingThreshold.
+	withholdingExcessRate = Rate(2042) // 20.42%
+)
+
+// WithholdingTax computes the income tax withheld from a payment of amount with the two-tier formula:
+// 10.21% up to ¥1,000,000 and 20.42% of the excess, truncating fractions of a yen.
+func WithholdingTax(amount int) int {
+	if amount <= 0 {
+		return 0
+	}
+	if amount <= withholdingThreshold {
+		return withholdingRate.Apply(amount, Floor)
+	}
+	// 10.21% of the threshold is exactly ¥102,100, so truncating the excess part truncates the sum.
+	return withholdingRate.Apply(withholdingThreshold, Floor) + withholdingExcessRate.Apply(amount-withholdingThreshold, Floor)
+}
+
+// Withhold deducts the income tax withheld from the payment to a business partner of category from Total.
+// The tax is computed from Amount, which doesn't include the fee. The consumption tax of itemized invoices is left
+// out of it as well, which the law allows when the tax is stated separately.
+func (i *Invoice) Withhold(category WithholdingCategory) {
+	if category != ProfessionalWithholding {
+		return
+	}
+	i.WithholdingTax = WithholdingTax(i.Amount)
+	i.Total -= i.WithholdingTax
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithholdingTax(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount int
+		want   int
+	}{
+		{name: "zero", amount: 0, want: 0},
+		{name: "fraction is truncated", amount: 55555, want: 5672},
+		{name: "just below the threshold", amount: 999999, want: 102099},
+		{name: "at the threshold", amount: 1000000, want: 102100},
+		{name: "yen over the threshold", amount: 1000001, want: 102100},
+		{name: "over the threshold", amount: 1500000, want: 204200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, WithholdingTax(tt.amount))
+		})
+	}
+}
+
+func TestInvoice_Withhold(t *testing.T) {
+	date := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	t.Run("professional", func(t *testing.T) {
+		invoice := NewInvoice(date, date, 100000, nil, "unprocessed", DefaultRates)
+		invoice.Withhold(ProfessionalWithholding)
+		assert.Equal(t, 10210, invoice.WithholdingTax)
+		assert.Equal(t, 100000+4000+400-10210, invoice.Total)
+	})
+	t.Run("none", func(t *testing.T) {
+		invoice := NewInvoice(date, date, 100000, nil, "unprocessed", DefaultRates)
+		invoice.Withhold(NoWithholding)
+		assert.Zero(t, invoice.WithholdingTax)
+		assert.Equal(t, 104400, invoice.Total)
+	})
+}
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+// ExportColumns are the columns of exported CSV files in their default order.
+var ExportColumns = []string{"invoice_id", "company_id", "partner_id", "issue_date", "amount", "fee", "fee_rate", "tax", "tax_rate", "withholding_tax", "total", "due_date", "status"}
+
+var exportValues = map[string]func(*domain.Invoice) string{
+	"invoice_id":      func(i *domain.Invoice) string { return i.InvoiceID },
+	"company_id":      func(i *domain.Invoice) string { return i.CompanyID },
+	"partner_id":      func(i *domain.Invoice) string { return i.PartnerID },
+	"issue_date":      func(i *domain.Invoice) string { return i.IssueDate.Format(time.DateOnly) },
+	"amount":          func(i *domain.Invoice) string { return strconv.Itoa(i.Amount) },
+	"fee":             func(i *domain.Invoice) string { return strconv.Itoa(i.Fee) },
+	"fee_rate":        func(i *domain.Invoice) string { return i.FeeRate.String() },
+	"tax":             func(i *domain.Invoice) string { return strconv.Itoa(i.Tax) },
+	"tax_rate":        func(i *domain.Invoice) string { return i.TaxRate.String() },
+	"withholding_tax": func(i *domain.Invoice) string { return strconv.Itoa(i.WithholdingTax) },
+	"total":           func(i *domain.Invoice) string { return strconv.Itoa(i.Total) },
+	"due_date":        func(i *domain.Invoice) string { return i.DueDate.Format(time.DateOnly) },
+	"status":          func(i *domain.Invoice) string { return string(i.Status) },
+}
+
+// ParseExportColumns parses the comma separated columns to export. An empty string means ExportColumns.
+func ParseExportColumns(s string) ([]string, error) {
+	if s == "" {
+		return ExportColumns, nil
+	}
+	columns := strings.Split(s, ",")
+	for i, column := range columns {
+		column = strings.TrimSpace(column)
+		if _, ok := exportValues[column]; !ok {
+			return nil, fmt.Errorf("%w: 'columns' must be some of [%v], but got %v", ErrInvalidQuery, strings.Join(ExportColumns, ", "), column)
+		}
+		if slices.Contains(columns[:i], column) {
+			return nil, fmt.Errorf("%w: column %v is duplicated", ErrInvalidQuery, column)
+		}
+		columns[i] = column
+	}
+	return columns, nil
+}
+
+// InvoiceCSVWriter writes invoices as CSV rows of the columns. The header is written before the first row.
+type InvoiceCSVWriter struct {
+	w       io.Writer
+	csv     *csv.Writer
+	columns []string
+	bom     bool
+	started bool
+}
+
+// NewInvoiceCSVWriter returns a writer of the columns. bom prefixes the output with the UTF-8 BOM, without which
+// Excel reads UTF-8 files as Shift_JIS.
+func NewInvoiceCSVWriter(w io.Writer, columns []string, bom bool) *InvoiceCSVWriter {
+	return &InvoiceCSVWriter{w: w, csv: csv.NewWriter(w), columns: columns, bom: bom}
+}
+
+// Start writes the BOM and the header unless they have been written.
+func (w *InvoiceCSVWriter) Start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if w.bom {
+		if _, err := w.w.Write(utf8BOM); err != nil {
+			return err
+		}
+	}
+	return w.csv.Write(w.columns)
+}
+
+func (w *InvoiceCSVWriter) Write(invoice *domain.Invoice) error {
+	if err := w.Start(); err != nil {
+		return err
+	}
+	record := make([]string, 0, len(w.columns))
+	for _, column := range w.columns {
+		record = append(record, exportValues[column](invoice))
+	}
+	return w.csv.Write(record)
+}
+
+// Flush writes the buffered rows to the underlying writer.
+func (w *InvoiceCSVWriter) Flush() error {
+	w.csv.Flush()
+	return w.csv.Error()
+}
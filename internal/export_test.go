@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExportColumns(t *testing.T) {
+	got, err := ParseExportColumns("")
+	require.NoError(t, err)
+	assert.Equal(t, ExportColumns, got)
+
+	got, err = ParseExportColumns("invoice_id, total,due_date")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoice_id", "total", "due_date"}, got)
+
+	_, err = ParseExportColumns("invoice_id,password")
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+	_, err = ParseExportColumns("total,total")
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
+
+func TestInvoiceCSVWriter(t *testing.T) {
+	invoice := &domain.Invoice{
+		InvoiceID: "1",
+		CompanyID: "1",
+		PartnerID: "2",
+		IssueDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		Amount:    10000,
+		Fee:       400,
+		FeeRate:   domain.MustParseRate("0.04"),
+		Tax:       40,
+		TaxRate:   domain.MustParseRate("0.10"),
+		Total:     10440,
+		DueDate:   time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
+		Status:    domain.Unprocessed,
+	}
+	tests := []struct {
+		name     string
+		columns  []string
+		bom      bool
+		invoices []*domain.Invoice
+		want     string
+	}{
+		{
+			name:     "all columns",
+			columns:  ExportColumns,
+			invoices: []*domain.Invoice{invoice},
+			want:     "invoice_id,company_id,partner_id,issue_date,amount,fee,fee_rate,tax,tax_rate,withholding_tax,total,due_date,status\n1,1,2,2024-10-01,10000,400,0.04,40,0.1,0,10440,2024-10-31,unprocessed\n",
+		},
+		{
+			name:     "selected columns with bom",
+			columns:  []string{"due_date", "total"},
+			bom:      true,
+			invoices: []*domain.Invoice{invoice, invoice},
+			want:     "\ufeffdue_date,total\n2024-10-31,10440\n2024-10-31,10440\n",
+		},
+		{
+			name:    "header only",
+			columns: []string{"invoice_id"},
+			want:    "invoice_id\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			w := NewInvoiceCSVWriter(&b, tt.columns, tt.bom)
+			for _, invoice := range tt.invoices {
+				require.NoError(t, w.Write(invoice))
+			}
+			require.NoError(t, w.Start())
+			require.NoError(t, w.Flush())
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
@@ -1,112 +1,423 @@
 package internal
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Ryuheeeei/super-invoicer/internal/calendar"
 	"github.com/Ryuheeeei/super-invoicer/internal/domain"
 )
 
 type InvoiceResponse struct {
-	InvoiceID string    `json:"invoice_id"`
-	CompanyID string    `json:"company_id"`
-	IssueDate time.Time `json:"issue_date"`
-	Amount    int       `json:"amount"`
-	Fee       int       `json:"fee"`
-	FeeRate   float32   `json:"fee_rate"`
-	Tax       int       `json:"tax"`
-	TaxRate   float32   `json:"tax_rate"`
-	Total     int       `json:"total"`
-	DueDate   time.Time `json:"due_date"`
-	Status    string    `json:"status"`
+	InvoiceID string      `json:"invoice_id"`
+	CompanyID string      `json:"company_id"`
+	PartnerID string      `json:"partner_id"`
+	IssueDate time.Time   `json:"issue_date"`
+	Amount    int         `json:"amount"`
+	Fee       int         `json:"fee"`
+	FeeRate   domain.Rate `json:"fee_rate"`
+	Tax       int         `json:"tax"`
+	TaxRate   domain.Rate `json:"tax_rate"`
+	// WithholdingTax is already deducted from Total, which is the amount transferred to the partner.
+	WithholdingTax int       `json:"withholding_tax"`
+	Total          int       `json:"total"`
+	DueDate        time.Time `json:"due_date"`
+	Status         string    `json:"status"`
+	// TaxAmounts and RegistrationNumber are what the qualified invoice system requires.
+	TaxAmounts         domain.TaxAmounts `json:"tax_amounts"`
+	RegistrationNumber string            `json:"registration_number,omitempty"`
+	// Lines are left out for invoices of a single amount.
+	Lines []domain.InvoiceLine `json:"lines,omitempty"`
+}
+
+// NewInvoiceResponse is the representation of an invoice shared by the API and the CLI.
+func NewInvoiceResponse(invoice *domain.Invoice) InvoiceResponse {
+	taxAmounts := invoice.TaxAmounts
+	if taxAmounts == nil {
+		taxAmounts = domain.TaxAmounts{}
+	}
+	return InvoiceResponse{
+		InvoiceID:          invoice.InvoiceID,
+		CompanyID:          invoice.CompanyID,
+		PartnerID:          invoice.PartnerID,
+		IssueDate:          invoice.IssueDate,
+		Amount:             invoice.Amount,
+		Fee:                invoice.Fee,
+		FeeRate:            invoice.FeeRate,
+		Tax:                invoice.Tax,
+		TaxRate:            invoice.TaxRate,
+		WithholdingTax:     invoice.WithholdingTax,
+		Total:              invoice.Total,
+		DueDate:            invoice.DueDate,
+		Status:             string(invoice.Status),
+		TaxAmounts:         taxAmounts,
+		RegistrationNumber: invoice.RegistrationNumber,
+		Lines:              invoice.Lines,
+	}
 }
 
 type ListResponse struct {
-	Invoices []InvoiceResponse `json:"invoices"`
+	Invoices   []InvoiceResponse `json:"invoices"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func NewListResponse(page *InvoicePage) ListResponse {
+	resp := ListResponse{Invoices: make([]InvoiceResponse, 0, len(page.Invoices))}
+	for _, invoice := range page.Invoices {
+		resp.Invoices = append(resp.Invoices, NewInvoiceResponse(&invoice))
+	}
+	if page.NextCursor != nil {
+		resp.NextCursor = page.NextCursor.Encode()
+	}
+	return resp
 }
 
 type Finder interface {
-	Find(context.Context, string, time.Time) ([]domain.Invoice, error)
+	Find(context.Context, InvoiceQuery) (*InvoicePage, error)
 }
 
-type FinderFunc func(context.Context, string, time.Time) ([]domain.Invoice, error)
+type FinderFunc func(context.Context, InvoiceQuery) (*InvoicePage, error)
 
-func (f FinderFunc) Find(ctx context.Context, s string, date time.Time) ([]domain.Invoice, error) {
-	return f(ctx, s, date)
+func (f FinderFunc) Find(ctx context.Context, q InvoiceQuery) (*InvoicePage, error) {
+	return f(ctx, q)
 }
 
-func ListHandler(finder Finder, logger *slog.Logger) http.HandlerFunc {
+func ListHandler(finder Finder, clock Clock, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		companyID := r.URL.Query().Get("company_id")
-		if companyID == "" {
+		if !requireScope(w, r, domain.ScopeInvoicesRead, logger) {
+			return
+		}
+		q, ok := parseListQuery(w, r, clock, logger)
+		if !ok {
+			return
+		}
+		companyID := q.CompanyID
+		page, err := finder.Find(r.Context(), q)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Invoices of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to find invoices", "customer_id", companyID, "query", r.URL.RawQuery, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to find invoices"}`))
+			return
+		}
+
+		if err := json.NewEncoder(w).Encode(NewListResponse(page)); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to encode found invoices to json", "invoices", page.Invoices)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to encode found invoices"}`))
+			return
+		}
+	}
+}
+
+// parseListQuery parses the query parameters of GET /api/invoices as of today of clock. It writes 400 and reports false
+// when they are invalid.
+func parseListQuery(w http.ResponseWriter, r *http.Request, clock Clock, logger *slog.Logger) (InvoiceQuery, bool) {
+	companyID := companyIDOrDefault(r.Context(), r.URL.Query().Get("company_id"))
+	if companyID == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"'company_id' mustn't be empty"}`))
+		return InvoiceQuery{}, false
+	}
+	q, err := ParseInvoiceQuery(r.URL.Query(), companyID, Today(clock))
+	if errors.Is(err, errInvalidDueDate) {
+		logger.ErrorContext(r.Context(), "Failed to convert duedate parameter to date", "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"Can't convert duedate parameter to date"}`))
+		return InvoiceQuery{}, false
+	}
+	if err != nil {
+		writeValidationError(w, err)
+		return InvoiceQuery{}, false
+	}
+	return q, true
+}
+
+type Exporter interface {
+	Export(context.Context, InvoiceQuery, func(*domain.Invoice) error) error
+}
+
+type ExporterFunc func(context.Context, InvoiceQuery, func(*domain.Invoice) error) error
+
+func (f ExporterFunc) Export(ctx context.Context, q InvoiceQuery, fn func(*domain.Invoice) error) error {
+	return f(ctx, q, fn)
+}
+
+// wantsCSV reports whether the client asked for CSV with the format query parameter or the Accept header.
+// The format parameter takes precedence over the header.
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "csv"
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(accept, ";")
+		if strings.EqualFold(strings.TrimSpace(mediaType), "text/csv") {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportHandler streams the invoices of GET /api/invoices as CSV when the client asks for it with ?format=csv or
+// Accept: text/csv, and passes the other requests to fallback. It takes the parameters of ListHandler plus columns
+// and bom, but exports every matching invoice unless limit is given. The response can't be turned into an error once
+// the rows have started streaming, so the connection is aborted on errors in the middle of it.
+func ExportHandler(exporter Exporter, clock Clock, logger *slog.Logger, fallback http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "csv" && format != "json" {
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"'company_id' mustn't be empty"}`))
+			w.Write([]byte(fmt.Sprintf(`{"message":"'format' must be one of [json, csv], but got %v"}`, format)))
+			return
+		}
+		if !wantsCSV(r) {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		if !requireScope(w, r, domain.ScopeInvoicesRead, logger) {
 			return
 		}
-		dueDate, err := time.Parse(time.DateOnly, r.URL.Query().Get("due_date"))
+		q, ok := parseListQuery(w, r, clock, logger)
+		if !ok {
+			return
+		}
+		if !r.URL.Query().Has("limit") {
+			q.Limit = 0
+		}
+		columns, err := ParseExportColumns(r.URL.Query().Get("columns"))
 		if err != nil {
-			logger.ErrorContext(r.Context(), "Failed to convert duedate parameter to date", "err", err)
+			writeValidationError(w, err)
+			return
+		}
+		bom := false
+		if v := r.URL.Query().Get("bom"); v != "" {
+			bom, err = strconv.ParseBool(v)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(fmt.Sprintf(`{"message":"'bom' must be true or false, but got %v"}`, v)))
+				return
+			}
+		}
+
+		csvWriter := NewInvoiceCSVWriter(w, columns, bom)
+		start := func() error {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			w.Header().Set("Content-Disposition", `attachment; filename="invoices.csv"`)
+			return csvWriter.Start()
+		}
+		exported := 0
+		err = exporter.Export(r.Context(), q, func(invoice *domain.Invoice) error {
+			if exported == 0 {
+				if err := start(); err != nil {
+					return err
+				}
+			}
+			exported++
+			return csvWriter.Write(invoice)
+		})
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Invoices of another company were requested", "company_id", q.CompanyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if err != nil && exported == 0 {
+			logger.ErrorContext(r.Context(), "Failed to export invoices", "company_id", q.CompanyID, "query", r.URL.RawQuery, "err", err)
+			w.Header().Del("Content-Type")
+			w.Header().Del("Content-Disposition")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to export invoices"}`))
+			return
+		}
+		if err == nil && exported == 0 {
+			err = start()
+		}
+		if err == nil {
+			err = csvWriter.Flush()
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to export invoices in the middle", "company_id", q.CompanyID, "exported", exported, "err", err)
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+type IDFinder interface {
+	FindByID(context.Context, string, string) (*domain.Invoice, error)
+}
+
+type IDFinderFunc func(context.Context, string, string) (*domain.Invoice, error)
+
+func (f IDFinderFunc) FindByID(ctx context.Context, s1, s2 string) (*domain.Invoice, error) {
+	return f(ctx, s1, s2)
+}
+
+func GetHandler(finder IDFinder, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireScope(w, r, domain.ScopeInvoicesRead, logger) {
+			return
+		}
+		companyID := companyIDOrDefault(r.Context(), r.URL.Query().Get("company_id"))
+		if companyID == "" {
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Can't convert duedate parameter to date"}`))
+			w.Write([]byte(`{"message":"'company_id' mustn't be empty"}`))
+			return
+		}
+		invoiceID := r.PathValue("id")
+		invoice, err := finder.FindByID(r.Context(), companyID, invoiceID)
+		if errors.Is(err, ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Invoice not found"}`))
+			return
+		}
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Invoice of another company was requested", "company_id", companyID, "invoice_id", invoiceID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
 			return
 		}
-		invoices, err := finder.Find(r.Context(), companyID, dueDate)
 		if err != nil {
-			logger.ErrorContext(r.Context(), "Failed to find invoices", "customer_id", companyID, "due_date", dueDate, "err", err)
+			logger.ErrorContext(r.Context(), "Failed to find invoice", "company_id", companyID, "invoice_id", invoiceID, "err", err)
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{"message":"Failed to find invoices"}`))
+			w.Write([]byte(`{"message":"Failed to find invoice"}`))
+			return
+		}
+		if err := json.NewEncoder(w).Encode(NewInvoiceResponse(invoice)); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to encode found invoice to json", "invoice", invoice)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to encode found invoice"}`))
 			return
 		}
+	}
+}
 
-		resp := make([]InvoiceResponse, 0)
-		for _, invoice := range invoices {
-			resp = append(resp, InvoiceResponse{
-				InvoiceID: invoice.InvoiceID,
-				CompanyID: invoice.CompanyID,
-				IssueDate: invoice.IssueDate,
-				Amount:    invoice.Amount,
-				Fee:       invoice.Fee,
-				FeeRate:   invoice.FeeRate,
-				Tax:       invoice.Tax,
-				TaxRate:   invoice.TaxRate,
-				Total:     invoice.Total,
-				DueDate:   invoice.DueDate,
-				Status:    string(invoice.Status),
-			})
+type Documenter interface {
+	Document(context.Context, string, string) (*InvoiceDocument, error)
+}
+
+type DocumenterFunc func(context.Context, string, string) (*InvoiceDocument, error)
+
+func (f DocumenterFunc) Document(ctx context.Context, s1, s2 string) (*InvoiceDocument, error) {
+	return f(ctx, s1, s2)
+}
+
+// PDFHandler renders the invoice as an A4 PDF. The PDF is rendered in memory first so that a failure is still
+// reported as a JSON error.
+func PDFHandler(documenter Documenter, renderer *PDFRenderer, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireScope(w, r, domain.ScopeInvoicesRead, logger) {
+			return
 		}
-		if err := json.NewEncoder(w).Encode(ListResponse{Invoices: resp}); err != nil {
-			logger.ErrorContext(r.Context(), "Failed to encode found invoices to json", "invoices", invoices)
+		companyID := companyIDOrDefault(r.Context(), r.URL.Query().Get("company_id"))
+		if companyID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"'company_id' mustn't be empty"}`))
+			return
+		}
+		invoiceID := r.PathValue("id")
+		doc, err := documenter.Document(r.Context(), companyID, invoiceID)
+		if errors.Is(err, ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Invoice not found"}`))
+			return
+		}
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Invoice of another company was requested", "company_id", companyID, "invoice_id", invoiceID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to find invoice", "company_id", companyID, "invoice_id", invoiceID, "err", err)
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{"message":"Failed to encode found invoices"}`))
+			w.Write([]byte(`{"message":"Failed to find invoice"}`))
 			return
 		}
+		var buf bytes.Buffer
+		if err := renderer.Render(&buf, doc); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to render invoice as pdf", "company_id", companyID, "invoice_id", invoiceID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to render invoice"}`))
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="invoice-%s.pdf"`, invoiceID))
+		w.Write(buf.Bytes())
 	}
 }
 
+// InvoiceRequest is an invoice of either a single amount or lines, from which the amount is derived.
 type InvoiceRequest struct {
-	CompanyID string `json:"company_id"`
-	IssueDate string `json:"issue_date"`
-	Amount    int    `json:"amount"`
-	DueDate   string `json:"due_date"`
-	Status    string `json:"status"`
+	CompanyID string               `json:"company_id"`
+	PartnerID string               `json:"partner_id"`
+	IssueDate string               `json:"issue_date"`
+	Amount    int                  `json:"amount"`
+	Lines     []domain.InvoiceLine `json:"lines,omitempty"`
+	DueDate   string               `json:"due_date"`
+	Status    string               `json:"status"`
+}
+
+// Validate checks the request and parses its dates. Every entry point creating invoices validates with it,
+// and the messages of the errors are shown to the client as is.
+func (body *InvoiceRequest) Validate() (issueDate, dueDate time.Time, err error) {
+	if body.CompanyID == "" {
+		return time.Time{}, time.Time{}, errors.New("'company_id' mustn't be empty")
+	}
+	if body.PartnerID == "" {
+		return time.Time{}, time.Time{}, errors.New("'partner_id' mustn't be empty")
+	}
+	issueDate, err = time.ParseInLocation(time.DateOnly, body.IssueDate, time.UTC)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("Failed to decode issue_date as YYYY-MM-DD")
+	}
+	dueDate, err = calendar.ParseDueDate(body.DueDate, issueDate)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Failed to decode due_date as YYYY-MM-DD or one of [%v]", strings.Join(calendar.DueDateShorthands, ", "))
+	}
+	if !domain.Status(body.Status).Valid() {
+		return time.Time{}, time.Time{}, fmt.Errorf("'status' must be one of [unprocessed, processing, paid, error], but got %v", body.Status)
+	}
+	if len(body.Lines) > 0 && body.Amount != 0 {
+		return time.Time{}, time.Time{}, errors.New("'amount' is derived from 'lines' and mustn't be given with them")
+	}
+	for i := range body.Lines {
+		if err := body.Lines[i].Validate(); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("lines[%d]: %w", i, err)
+		}
+	}
+	return issueDate, dueDate, nil
 }
 
 type Registerer interface {
-	Register(context.Context, string, time.Time, int, time.Time, string) (*domain.Invoice, error)
+	Register(context.Context, string, string, time.Time, int, []domain.InvoiceLine, time.Time, string) (*domain.Invoice, error)
 }
 
-type RegistererFunc func(context.Context, string, time.Time, int, time.Time, string) (*domain.Invoice, error)
+type RegistererFunc func(context.Context, string, string, time.Time, int, []domain.InvoiceLine, time.Time, string) (*domain.Invoice, error)
 
-func (f RegistererFunc) Register(ctx context.Context, s1 string, t1 time.Time, i int, t2 time.Time, s2 string) (*domain.Invoice, error) {
-	return f(ctx, s1, t1, i, t2, s2)
+func (f RegistererFunc) Register(ctx context.Context, s1, s2 string, t1 time.Time, i int, lines []domain.InvoiceLine, t2 time.Time, s3 string) (*domain.Invoice, error) {
+	return f(ctx, s1, s2, t1, i, lines, t2, s3)
 }
 
 func CreateHandler(registerer Registerer, logger *slog.Logger) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireScope(w, r, domain.ScopeInvoicesWrite, logger) {
+			return
+		}
 		var body InvoiceRequest
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			logger.ErrorContext(r.Context(), "Failed to decode invoice request", "body", body, "err", err)
@@ -114,59 +425,499 @@ func CreateHandler(registerer Registerer, logger *slog.Logger) http.HandlerFunc
 			w.Write([]byte(`{"message":"Failed to decode invoice request"}`))
 			return
 		}
-		if body.CompanyID == "" {
-			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"'company_id' mustn't be empty"}`))
+		body.CompanyID = companyIDOrDefault(r.Context(), body.CompanyID)
+		issueDate, dueDate, err := body.Validate()
+		if err != nil {
+			writeValidationError(w, err)
+			return
+		}
+		invoice, err := registerer.Register(r.Context(), body.CompanyID, body.PartnerID, issueDate, body.Amount, body.Lines, dueDate, body.Status)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Invoice creation for another company was requested", "company_id", body.CompanyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrCompanyNotFound) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(fmt.Sprintf(`{"message":"Company %v doesn't exist"}`, body.CompanyID)))
+			return
+		}
+		if errors.Is(err, ErrPartnerNotFound) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(fmt.Sprintf(`{"message":"Business partner %v doesn't exist in company %v"}`, body.PartnerID, body.CompanyID)))
+			return
+		}
+		if errors.Is(err, domain.ErrNonQualifiedInvoice) {
+			msg, _ := json.Marshal(err.Error())
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(fmt.Sprintf(`{"message":%s}`, msg)))
 			return
 		}
-		issueDate, err := time.ParseInLocation(time.DateOnly, body.IssueDate, time.UTC)
 		if err != nil {
-			logger.ErrorContext(r.Context(), "Failed to decode issue_date as YYYY-MM-DD", "issue_date", body.IssueDate, "err", err)
+			logger.ErrorContext(r.Context(), "Failed to create invoice", "customer_id", body.CompanyID, "issue_date", body.IssueDate, "amount", body.Amount, "due_date", body.DueDate, "status", body.Status, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to create invoice"}`))
+			return
+		}
+		resp := NewInvoiceResponse(invoice)
+		resp.CompanyID = body.CompanyID
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to encode created invoice to json", "invoice", invoice)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to encode created invoice"}`))
+			return
+		}
+	}
+}
+
+type BatchRegisterer interface {
+	RegisterBatch(context.Context, []BatchItem, BatchMode) ([]BatchResult, error)
+}
+
+type BatchRegistererFunc func(context.Context, []BatchItem, BatchMode) ([]BatchResult, error)
+
+func (f BatchRegistererFunc) RegisterBatch(ctx context.Context, items []BatchItem, mode BatchMode) ([]BatchResult, error) {
+	return f(ctx, items, mode)
+}
+
+// BatchItemResponse is the result of the invoice at Index of the request. Status is one of created, failed and
+// skipped, which means the invoice was valid but not created because another one of an all_or_nothing batch failed.
+type BatchItemResponse struct {
+	Index   int              `json:"index"`
+	Status  string           `json:"status"`
+	Invoice *InvoiceResponse `json:"invoice,omitempty"`
+	Message string           `json:"message,omitempty"`
+}
+
+type BatchResponse struct {
+	Mode    BatchMode           `json:"mode"`
+	Created int                 `json:"created"`
+	Failed  int                 `json:"failed"`
+	Results []BatchItemResponse `json:"results"`
+}
+
+// batchErrorMessage returns the message CreateHandler responds with for err.
+func batchErrorMessage(err error, body *InvoiceRequest) string {
+	switch {
+	case errors.Is(err, ErrForbidden):
+		return "Forbidden"
+	case errors.Is(err, ErrCompanyNotFound):
+		return fmt.Sprintf("Company %v doesn't exist", body.CompanyID)
+	case errors.Is(err, ErrPartnerNotFound):
+		return fmt.Sprintf("Business partner %v doesn't exist in company %v", body.PartnerID, body.CompanyID)
+	}
+	return err.Error()
+}
+
+// BatchCreateHandler creates the array of invoices in the request body in a single transaction. The mode query
+// parameter is all_or_nothing (default) or best_effort. The response is 422 when an all_or_nothing batch failed.
+func BatchCreateHandler(registerer BatchRegisterer, maxItems int, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireScope(w, r, domain.ScopeInvoicesWrite, logger) {
+			return
+		}
+		mode := BatchMode(r.URL.Query().Get("mode"))
+		if mode == "" {
+			mode = AllOrNothing
+		}
+		if !mode.Valid() {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"message":"'mode' must be one of [all_or_nothing, best_effort], but got %v"}`, mode)))
+			return
+		}
+		var bodies []InvoiceRequest
+		if err := json.NewDecoder(r.Body).Decode(&bodies); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode invoice requests", "err", err)
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Failed to decode issue_date as YYYY-MM-DD"}`))
+			w.Write([]byte(`{"message":"Failed to decode invoice requests"}`))
 			return
 		}
-		dueDate, err := time.ParseInLocation(time.DateOnly, body.DueDate, time.UTC)
+		if len(bodies) == 0 || len(bodies) > maxItems {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"message":"The number of invoices must be between 1 and %d"}`, maxItems)))
+			return
+		}
+		items := make([]BatchItem, 0, len(bodies))
+		for i := range bodies {
+			bodies[i].CompanyID = companyIDOrDefault(r.Context(), bodies[i].CompanyID)
+			issueDate, dueDate, err := bodies[i].Validate()
+			items = append(items, BatchItem{
+				CompanyID: bodies[i].CompanyID,
+				PartnerID: bodies[i].PartnerID,
+				IssueDate: issueDate,
+				Amount:    bodies[i].Amount,
+				Lines:     bodies[i].Lines,
+				DueDate:   dueDate,
+				Status:    bodies[i].Status,
+				Err:       err,
+			})
+		}
+		results, err := registerer.RegisterBatch(r.Context(), items, mode)
 		if err != nil {
-			logger.ErrorContext(r.Context(), "Failed to decode due_date as YYYY-MM-DD", "due_date", body.DueDate, "err", err)
+			logger.ErrorContext(r.Context(), "Failed to create invoices", "count", len(items), "mode", mode, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to create invoices"}`))
+			return
+		}
+		response := BatchResponse{Mode: mode, Results: make([]BatchItemResponse, 0, len(results))}
+		for i, result := range results {
+			item := BatchItemResponse{Index: i}
+			switch {
+			case result.Invoice != nil:
+				invoice := NewInvoiceResponse(result.Invoice)
+				item.Status, item.Invoice = "created", &invoice
+				response.Created++
+			case result.Err != nil:
+				item.Status, item.Message = "failed", batchErrorMessage(result.Err, &bodies[i])
+				response.Failed++
+			default:
+				item.Status = "skipped"
+			}
+			response.Results = append(response.Results, item)
+		}
+		if mode == AllOrNothing && response.Failed > 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+		}
+		writeJSON(w, r, response, logger)
+	}
+}
+
+type StatusRequest struct {
+	Status string `json:"status"`
+}
+
+type StatusChanger interface {
+	ChangeStatus(context.Context, string, string, domain.Status) (*domain.Invoice, error)
+}
+
+type StatusChangerFunc func(context.Context, string, string, domain.Status) (*domain.Invoice, error)
+
+func (f StatusChangerFunc) ChangeStatus(ctx context.Context, s1, s2 string, status domain.Status) (*domain.Invoice, error) {
+	return f(ctx, s1, s2, status)
+}
+
+func StatusHandler(changer StatusChanger, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requireScope(w, r, domain.ScopeInvoicesApprove, logger) {
+			return
+		}
+		companyID := companyIDOrDefault(r.Context(), r.URL.Query().Get("company_id"))
+		if companyID == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"'company_id' mustn't be empty"}`))
+			return
+		}
+		var body StatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode status request", "body", body, "err", err)
 			w.WriteHeader(http.StatusBadRequest)
-			w.Write([]byte(`{"message":"Failed to decode due_date as YYYY-MM-DD"}`))
+			w.Write([]byte(`{"message":"Failed to decode status request"}`))
 			return
 		}
-		if body.Status != "unprocessed" && body.Status != "processing" && body.Status != "paid" && body.Status != "error" {
+		status := domain.Status(body.Status)
+		if !status.Valid() {
 			w.WriteHeader(http.StatusBadRequest)
 			w.Write([]byte(fmt.Sprintf(`{"message":"'status' must be one of [unprocessed, processing, paid, error], but got %v"}`, body.Status)))
 			return
 		}
-		invoice, err := registerer.Register(r.Context(), body.CompanyID, issueDate, body.Amount, dueDate, body.Status)
+		invoiceID := r.PathValue("id")
+		invoice, err := changer.ChangeStatus(r.Context(), companyID, invoiceID, status)
+		if errors.Is(err, ErrNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Invoice not found"}`))
+			return
+		}
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Status change of another company's invoice was requested", "company_id", companyID, "invoice_id", invoiceID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidTransition) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(fmt.Sprintf(`{"message":"Can't change status to %v"}`, status)))
+			return
+		}
+		if errors.Is(err, ErrConflict) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Invoice was modified concurrently"}`))
+			return
+		}
 		if err != nil {
-			logger.ErrorContext(r.Context(), "Failed to create invoice", "customer_id", body.CompanyID, "issue_date", body.IssueDate, "amount", body.Amount, "due_date", body.DueDate, "status", body.Status, "err", err)
+			logger.ErrorContext(r.Context(), "Failed to change invoice status", "company_id", companyID, "invoice_id", invoiceID, "status", status, "err", err)
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{"message":"Failed to create invoice"}`))
+			w.Write([]byte(`{"message":"Failed to change invoice status"}`))
 			return
 		}
-		if err := json.NewEncoder(w).Encode(InvoiceResponse{InvoiceID: invoice.InvoiceID, CompanyID: body.CompanyID, IssueDate: invoice.IssueDate, Amount: invoice.Amount, Fee: invoice.Fee, FeeRate: invoice.FeeRate, Tax: invoice.Tax, TaxRate: invoice.TaxRate, Total: invoice.Total, DueDate: invoice.DueDate, Status: string(invoice.Status)}); err != nil {
-			logger.ErrorContext(r.Context(), "Failed to encode created invoice to json", "invoice", invoice)
+		if err := json.NewEncoder(w).Encode(NewInvoiceResponse(invoice)); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to encode updated invoice to json", "invoice", invoice)
 			w.WriteHeader(http.StatusInternalServerError)
-			w.Write([]byte(`{"message":"Failed to encode created invoice"}`))
+			w.Write([]byte(`{"message":"Failed to encode updated invoice"}`))
 			return
 		}
 	}
 }
 
-func BasicAuthMiddleware(username, password string, next http.Handler) http.HandlerFunc {
+func writeJSON(w http.ResponseWriter, r *http.Request, v any, logger *slog.Logger) {
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.ErrorContext(r.Context(), "Failed to encode response to json", "response", v, "err", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(`{"message":"Failed to encode response"}`))
+	}
+}
+
+func writeValidationError(w http.ResponseWriter, err error) {
+	msg, _ := json.Marshal(err.Error())
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write([]byte(fmt.Sprintf(`{"message":%s}`, msg)))
+}
+
+type Authenticator interface {
+	Authenticate(context.Context, string, string) (*domain.User, error)
+}
+
+type AuthenticatorFunc func(context.Context, string, string) (*domain.User, error)
+
+func (f AuthenticatorFunc) Authenticate(ctx context.Context, username, password string) (*domain.User, error) {
+	return f(ctx, username, password)
+}
+
+// BasicAuthMiddleware authenticates the request against the users table and stores the user in the request context.
+func BasicAuthMiddleware(authenticator Authenticator, next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		user, pass, ok := r.BasicAuth()
+		username, password, ok := r.BasicAuth()
 		if !ok {
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte(`{"message":"Authorization Header doesn't exist"}`))
 			return
 		}
-		if user != username || pass != password {
+		user, err := authenticator.Authenticate(r.Context(), username, password)
+		if errors.Is(err, ErrUnauthorized) {
 			w.WriteHeader(http.StatusUnauthorized)
 			w.Write([]byte(`{"message":"Unauthorized"}`))
 			return
 		}
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to authenticate"}`))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	}
+}
+
+type KeyAuthenticator interface {
+	Authenticate(context.Context, string) (*domain.APIKey, error)
+}
+
+type KeyAuthenticatorFunc func(context.Context, string) (*domain.APIKey, error)
+
+func (f KeyAuthenticatorFunc) Authenticate(ctx context.Context, key string) (*domain.APIKey, error) {
+	return f(ctx, key)
+}
+
+// APIKeyMiddleware authenticates requests carrying an X-API-Key header and stores the key in the request context.
+// Requests without the header are passed to fallback, which usually authenticates users.
+func APIKeyMiddleware(authenticator KeyAuthenticator, logger *slog.Logger, next, fallback http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		plain := r.Header.Get("X-API-Key")
+		if plain == "" {
+			fallback.ServeHTTP(w, r)
+			return
+		}
+		key, err := authenticator.Authenticate(r.Context(), plain)
+		if errors.Is(err, ErrUnauthorized) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Unauthorized"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to authenticate api key", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to authenticate"}`))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithAPIKey(r.Context(), key)))
+	}
+}
+
+// requireScope writes 403 and reports false when the request was authenticated with an API key lacking scope.
+func requireScope(w http.ResponseWriter, r *http.Request, scope domain.Scope, logger *slog.Logger) bool {
+	if scopeAllowed(r.Context(), scope) {
+		return true
+	}
+	key, _ := APIKeyFromContext(r.Context())
+	logger.WarnContext(r.Context(), "API key lacks the required scope", "key_id", key.KeyID, "scope", scope)
+	w.WriteHeader(http.StatusForbidden)
+	w.Write([]byte(fmt.Sprintf(`{"message":"API key doesn't have scope %v"}`, scope)))
+	return false
+}
+
+type TokenVerifier interface {
+	Verify(context.Context, string) (*domain.User, error)
+}
+
+type TokenVerifierFunc func(context.Context, string) (*domain.User, error)
+
+func (f TokenVerifierFunc) Verify(ctx context.Context, token string) (*domain.User, error) {
+	return f(ctx, token)
+}
+
+// BearerAuthMiddleware authenticates the request with the bearer token and stores its subject in the request context.
+func BearerAuthMiddleware(verifier TokenVerifier, logger *slog.Logger, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Authorization Header doesn't exist"}`))
+			return
+		}
+		user, err := verifier.Verify(r.Context(), token)
+		if errors.Is(err, ErrUnauthorized) {
+			logger.WarnContext(r.Context(), "Invalid bearer token", "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Unauthorized"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to verify bearer token", "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to authenticate"}`))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	}
+}
+
+// PolicyMiddleware lets the request through only when the role of the authenticated user is granted permission.
+// API keys are restricted by their scopes instead, and requests are unrestricted when authentication is disabled.
+func PolicyMiddleware(permission domain.Permission, logger *slog.Logger, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := UserFromContext(r.Context())
+		if ok && !user.Role.Can(permission) {
+			logger.WarnContext(r.Context(), "Permission denied", "user_id", user.UserID, "company_id", user.CompanyID, "role", user.Role, "permission", permission, "method", r.Method, "path", r.URL.Path)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(fmt.Sprintf(`{"message":"Role %v doesn't have permission %v"}`, user.Role, permission)))
+			return
+		}
 		next.ServeHTTP(w, r)
 	}
 }
+
+type IdempotencyKeeper interface {
+	Begin(context.Context, string, string, []byte) (*IdempotencyRecord, error)
+	Complete(context.Context, *IdempotencyRecord, int, []byte) error
+	Release(context.Context, string, string) error
+}
+
+// maxIdempotencyKeyLength is the length of the idempotency_key column.
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyMiddleware makes the requests carrying an Idempotency-Key header safe to retry. The response to the
+// first request is stored, and the retries of the same request get it back with an Idempotent-Replayed header
+// instead of being processed again. Keys are scoped by the authenticated company, and reusing a key for a
+// different request is rejected with 422. Server errors aren't stored so that the request can be retried.
+func IdempotencyMiddleware(keeper IdempotencyKeeper, logger *slog.Logger, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("Idempotency-Key")
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf(`{"message":"Idempotency-Key must be at most %d bytes"}`, maxIdempotencyKeyLength)))
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to read request body"}`))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		companyID, _ := CompanyIDFromContext(r.Context())
+		record, err := keeper.Begin(r.Context(), companyID, key, requestFingerprint(r, body))
+		if errors.Is(err, ErrIdempotencyKeyReused) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"message":"Idempotency-Key was already used for a different request"}`))
+			return
+		}
+		if errors.Is(err, ErrIdempotencyKeyInProgress) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"A request with the same Idempotency-Key is in progress"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to claim idempotency key", "company_id", companyID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to claim idempotency key"}`))
+			return
+		}
+		if record.Completed {
+			w.Header().Set("Idempotent-Replayed", "true")
+			w.WriteHeader(record.ResponseCode)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, code: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		// The response has been sent, so the key is kept or released even if the client has gone.
+		ctx := context.WithoutCancel(r.Context())
+		if recorder.code >= http.StatusInternalServerError {
+			if err := keeper.Release(ctx, companyID, key); err != nil {
+				logger.ErrorContext(ctx, "Failed to release idempotency key", "company_id", companyID, "err", err)
+			}
+			return
+		}
+		err = keeper.Complete(ctx, record, recorder.code, recorder.body.Bytes())
+		if errors.Is(err, ErrIdempotencyKeyLost) {
+			logger.WarnContext(ctx, "Idempotency key was claimed again before the response was stored", "company_id", companyID, "err", err)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to store idempotent response", "company_id", companyID, "err", err)
+		}
+	}
+}
+
+// requestFingerprint identifies a request by its method, path, query and body. Insignificant whitespace in a JSON
+// body doesn't make it another request, but another query such as ?mode= does.
+func requestFingerprint(r *http.Request, body []byte) []byte {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, body); err != nil {
+		compact.Reset()
+		compact.Write(body)
+	}
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s?%s\n", r.Method, r.URL.Path, r.URL.RawQuery)
+	h.Write(compact.Bytes())
+	return h.Sum(nil)
+}
+
+// responseRecorder passes the response through while keeping a copy of it.
+type responseRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
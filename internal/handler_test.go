@@ -20,12 +20,13 @@ import (
 
 func TestListHandler(t *testing.T) {
 	tests := []struct {
-		name      string
-		query     string
-		invoices  []domain.Invoice
-		finderErr error
-		wantBody  string
-		wantCode  int
+		name       string
+		query      string
+		invoices   []domain.Invoice
+		nextCursor *Cursor
+		finderErr  error
+		wantBody   string
+		wantCode   int
 	}{
 		{
 			name:  "200 ok with invoices",
@@ -34,29 +35,31 @@ func TestListHandler(t *testing.T) {
 				{
 					InvoiceID: "1",
 					CompanyID: "1",
+					PartnerID: "1",
 					IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 					Amount:    10000,
 					Fee:       400,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       40,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 					Status:    domain.Processing,
 				},
 				{
 					InvoiceID: "2",
 					CompanyID: "1",
+					PartnerID: "1",
 					IssueDate: time.Date(1970, 1, 2, 9, 0, 0, 0, time.UTC),
 					Amount:    5000,
 					Fee:       200,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       20,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					DueDate:   time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
 					Status:    domain.Processing,
 				},
 			},
-			wantBody: `{"invoices":[{"invoice_id":"1","company_id":"1","issue_date":"1970-01-01T09:00:00Z","amount":10000,"fee":400,"fee_rate":0.04,"tax":40,"tax_rate":0.1,"total":0,"due_date":"2024-10-30T00:00:00Z","status":"processing"},{"invoice_id":"2","company_id":"1","issue_date":"1970-01-02T09:00:00Z","amount":5000,"fee":200,"fee_rate":0.04,"tax":20,"tax_rate":0.1,"total":0,"due_date":"2024-12-01T00:00:00Z","status":"processing"}]}` + "\n",
+			wantBody: `{"invoices":[{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"1970-01-01T09:00:00Z","amount":10000,"fee":400,"fee_rate":0.04,"tax":40,"tax_rate":0.1,"withholding_tax":0,"total":0,"due_date":"2024-10-30T00:00:00Z","status":"processing","tax_amounts":[]},{"invoice_id":"2","company_id":"1","partner_id":"1","issue_date":"1970-01-02T09:00:00Z","amount":5000,"fee":200,"fee_rate":0.04,"tax":20,"tax_rate":0.1,"withholding_tax":0,"total":0,"due_date":"2024-12-01T00:00:00Z","status":"processing","tax_amounts":[]}]}` + "\n",
 			wantCode: http.StatusOK,
 		},
 		{
@@ -66,6 +69,26 @@ func TestListHandler(t *testing.T) {
 			wantBody: `{"invoices":[]}` + "\n",
 			wantCode: http.StatusOK,
 		},
+		{
+			name:       "200 ok with next_cursor",
+			query:      "?company_id=1&status=paid&sort=-total&limit=1",
+			invoices:   []domain.Invoice{},
+			nextCursor: &Cursor{Sort: "-total", Value: "10440", InvoiceID: 1},
+			wantBody:   `{"invoices":[],"next_cursor":"eyJzIjoiLXRvdGFsIiwidiI6IjEwNDQwIiwiaWQiOjF9"}` + "\n",
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:     "400 bad request with invalid filter",
+			query:    "?company_id=1&status=UNKNOWN",
+			wantBody: `{"message":"invalid query: 'status' must be one of [unprocessed, processing, paid, error], but got UNKNOWN"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request with cursor of other filters",
+			query:    "?company_id=1&status=paid&sort=-total&limit=1&cursor=" + (&Cursor{Sort: "-total", Value: "10440", InvoiceID: 1, Filter: "OTHER"}).Encode(),
+			wantBody: `{"message":"invalid query: cursor was issued for other filters"}`,
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name:     "400 bad request without company_id",
 			query:    "?company_id=&due_date=1970-01-01",
@@ -90,12 +113,128 @@ func TestListHandler(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			finder := FinderFunc(func(context.Context, string, time.Time) ([]domain.Invoice, error) {
-				return tt.invoices, tt.finderErr
+			finder := FinderFunc(func(_ context.Context, q InvoiceQuery) (*InvoicePage, error) {
+				assert.Equal(t, "1", q.CompanyID)
+				return &InvoicePage{Invoices: tt.invoices, NextCursor: tt.nextCursor}, tt.finderErr
 			})
 			w := httptest.NewRecorder()
 			r := httptest.NewRequest(http.MethodGet, "http://localhost"+tt.query, nil)
-			f := ListHandler(finder, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			f := ListHandler(finder, testClock, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			f(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestListHandler_Today(t *testing.T) {
+	jst, err := time.LoadLocation(DefaultTimezone)
+	require.NoError(t, err)
+	tests := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "morning in JST when it's still the previous day in UTC",
+			now:  time.Date(2024, 10, 1, 8, 0, 0, 0, jst),
+			want: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "night in JST",
+			now:  time.Date(2024, 10, 1, 23, 0, 0, 0, jst),
+			want: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got InvoiceQuery
+			finder := FinderFunc(func(_ context.Context, q InvoiceQuery) (*InvoicePage, error) {
+				got = q
+				return &InvoicePage{Invoices: []domain.Invoice{}}, nil
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost?company_id=1&due_date=2024-10-31", nil)
+			ListHandler(finder, fixedClock(tt.now), slog.New(slog.NewTextHandler(io.Discard, nil)))(w, r)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, tt.want, got.DueDateFrom)
+			assert.Equal(t, time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC), got.DueDateTo)
+		})
+	}
+}
+
+func TestGetHandler(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		invoice   *domain.Invoice
+		finderErr error
+		wantBody  string
+		wantCode  int
+	}{
+		{
+			name:  "200 ok with invoice",
+			query: "?company_id=1",
+			invoice: &domain.Invoice{
+				InvoiceID: "1",
+				CompanyID: "1",
+				PartnerID: "1",
+				IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
+				Amount:    10000,
+				Fee:       400,
+				FeeRate:   domain.MustParseRate("0.04"),
+				Tax:       40,
+				TaxRate:   domain.MustParseRate("0.10"),
+				Total:     10440,
+				DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
+				Status:    domain.Processing,
+			},
+			wantBody: `{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"1970-01-01T09:00:00Z","amount":10000,"fee":400,"fee_rate":0.04,"tax":40,"tax_rate":0.1,"withholding_tax":0,"total":10440,"due_date":"2024-10-30T00:00:00Z","status":"processing","tax_amounts":[]}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "400 bad request without company_id",
+			query:    "?company_id=",
+			wantBody: `{"message":"'company_id' mustn't be empty"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:      "403 forbidden when invoice belongs to another company",
+			query:     "?company_id=1",
+			finderErr: ErrForbidden,
+			wantBody:  `{"message":"Forbidden"}`,
+			wantCode:  http.StatusForbidden,
+		},
+		{
+			name:      "404 not found when invoice doesn't exist",
+			query:     "?company_id=1",
+			finderErr: ErrNotFound,
+			wantBody:  `{"message":"Invoice not found"}`,
+			wantCode:  http.StatusNotFound,
+		},
+		{
+			name:      "500 internal server error when finder fails",
+			query:     "?company_id=1",
+			finderErr: errors.New("this is test"),
+			wantBody:  `{"message":"Failed to find invoice"}`,
+			wantCode:  http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			finder := IDFinderFunc(func(_ context.Context, companyID, invoiceID string) (*domain.Invoice, error) {
+				assert.Equal(t, "1", invoiceID)
+				return tt.invoice, tt.finderErr
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/api/invoices/1"+tt.query, nil)
+			r.SetPathValue("id", "1")
+			f := GetHandler(finder, slog.New(slog.NewTextHandler(os.Stderr, nil)))
 			f(w, r)
 
 			assert.Equal(t, tt.wantCode, w.Code)
@@ -107,6 +246,49 @@ func TestListHandler(t *testing.T) {
 	}
 }
 
+func TestPDFHandler(t *testing.T) {
+	renderer, err := NewPDFRenderer(DefaultPDFTemplate())
+	require.NoError(t, err)
+	golden, err := os.ReadFile("testdata/invoice.pdf")
+	require.NoError(t, err)
+	tests := []struct {
+		name        string
+		query       string
+		documentErr error
+		wantBody    string
+		wantCode    int
+	}{
+		{name: "200 ok with pdf", query: "?company_id=1", wantBody: string(golden), wantCode: http.StatusOK},
+		{name: "400 bad request without company_id", query: "?company_id=", wantBody: `{"message":"'company_id' mustn't be empty"}`, wantCode: http.StatusBadRequest},
+		{name: "403 forbidden when invoice belongs to another company", query: "?company_id=1", documentErr: ErrForbidden, wantBody: `{"message":"Forbidden"}`, wantCode: http.StatusForbidden},
+		{name: "404 not found when invoice doesn't exist", query: "?company_id=1", documentErr: ErrNotFound, wantBody: `{"message":"Invoice not found"}`, wantCode: http.StatusNotFound},
+		{name: "500 internal server error when documenter fails", query: "?company_id=1", documentErr: errors.New("this is test"), wantBody: `{"message":"Failed to find invoice"}`, wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			documenter := DocumenterFunc(func(_ context.Context, companyID, invoiceID string) (*InvoiceDocument, error) {
+				assert.Equal(t, "1", invoiceID)
+				if tt.documentErr != nil {
+					return nil, tt.documentErr
+				}
+				return testInvoiceDocument(), nil
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodGet, "http://localhost/api/invoices/1/pdf"+tt.query, nil)
+			r.SetPathValue("id", "1")
+			f := PDFHandler(documenter, renderer, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			f(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			if tt.wantCode == http.StatusOK {
+				assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+				assert.Equal(t, `inline; filename="invoice-1.pdf"`, w.Header().Get("Content-Disposition"))
+			}
+		})
+	}
+}
+
 func TestCreateHandler(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -118,21 +300,55 @@ func TestCreateHandler(t *testing.T) {
 	}{
 		{
 			name: "200 ok with created invoice",
-			body: `{"company_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			body: `{"company_id":"1","partner_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
 			invoice: &domain.Invoice{
 				InvoiceID: "1",
+				PartnerID: "1",
 				IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 				Amount:    10000,
 				Fee:       400,
-				FeeRate:   0.04,
+				FeeRate:   domain.MustParseRate("0.04"),
 				Tax:       40,
-				TaxRate:   0.10,
+				TaxRate:   domain.MustParseRate("0.10"),
 				DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 				Status:    domain.Processing,
 			},
-			wantBody: `{"invoice_id":"1","company_id":"1","issue_date":"1970-01-01T09:00:00Z","amount":10000,"fee":400,"fee_rate":0.04,"tax":40,"tax_rate":0.1,"total":0,"due_date":"2024-10-30T00:00:00Z","status":"processing"}` + "\n",
+			wantBody: `{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"1970-01-01T09:00:00Z","amount":10000,"fee":400,"fee_rate":0.04,"tax":40,"tax_rate":0.1,"withholding_tax":0,"total":0,"due_date":"2024-10-30T00:00:00Z","status":"processing","tax_amounts":[]}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name: "200 ok with created invoice of lines",
+			body: `{"company_id":"1","partner_id":"1","lines":[{"description":"お弁当","quantity":2,"unit_price":1000,"tax_category":"reduced"}],"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			invoice: &domain.Invoice{
+				InvoiceID:  "1",
+				PartnerID:  "1",
+				IssueDate:  time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC),
+				Amount:     2000,
+				Fee:        80,
+				FeeRate:    domain.MustParseRate("0.04"),
+				Tax:        168,
+				TaxRate:    domain.MustParseRate("0.10"),
+				Total:      2248,
+				DueDate:    time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
+				Status:     domain.Processing,
+				TaxAmounts: domain.TaxAmounts{{Rate: domain.MustParseRate("0.10"), Taxable: 80, Tax: 8}, {Rate: domain.ReducedTaxRate, Taxable: 2000, Tax: 160}},
+				Lines:      []domain.InvoiceLine{{Description: "お弁当", Quantity: 2, UnitPrice: 1000, TaxCategory: domain.ReducedTax}},
+			},
+			wantBody: `{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"1970-01-01T00:00:00Z","amount":2000,"fee":80,"fee_rate":0.04,"tax":168,"tax_rate":0.1,"withholding_tax":0,"total":2248,"due_date":"2024-10-30T00:00:00Z","status":"processing","tax_amounts":[{"rate":0.1,"taxable":80,"tax":8},{"rate":0.08,"taxable":2000,"tax":160}],"lines":[{"description":"お弁当","quantity":2,"unit_price":1000,"tax_category":"reduced"}]}` + "\n",
 			wantCode: http.StatusOK,
 		},
+		{
+			name:     "400 bad request with both amount and lines",
+			body:     `{"company_id":"1","partner_id":"1","amount":10000,"lines":[{"description":"お弁当","quantity":2,"unit_price":1000,"tax_category":"reduced"}],"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			wantBody: `{"message":"'amount' is derived from 'lines' and mustn't be given with them"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request with invalid line",
+			body:     `{"company_id":"1","partner_id":"1","lines":[{"description":"お弁当","quantity":0,"unit_price":1000,"tax_category":"reduced"}],"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			wantBody: `{"message":"lines[0]: invalid invoice line: 'quantity' must be positive, but got 0"}`,
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name:     "400 bad request when failed request body decode",
 			body:     `INVALID`,
@@ -145,27 +361,54 @@ func TestCreateHandler(t *testing.T) {
 			wantBody: `{"message":"'company_id' mustn't be empty"}`,
 			wantCode: http.StatusBadRequest,
 		},
+		{
+			name:     "400 bad request with empty partner_id",
+			body:     `{"company_id":"1"}`,
+			wantBody: `{"message":"'partner_id' mustn't be empty"}`,
+			wantCode: http.StatusBadRequest,
+		},
 		{
 			name:     "400 bad request with invalid issue_date",
-			body:     `{"company_id":"1","issue_date":"INVALID"}`,
+			body:     `{"company_id":"1","partner_id":"1","issue_date":"INVALID"}`,
 			wantBody: `{"message":"Failed to decode issue_date as YYYY-MM-DD"}`,
 			wantCode: http.StatusBadRequest,
 		},
 		{
 			name:     "400 bad request with invalid due_date",
-			body:     `{"company_id":"1","issue_date":"1970-01-01","due_date":"INVALID"}`,
-			wantBody: `{"message":"Failed to decode due_date as YYYY-MM-DD"}`,
+			body:     `{"company_id":"1","partner_id":"1","issue_date":"1970-01-01","due_date":"INVALID"}`,
+			wantBody: `{"message":"Failed to decode due_date as YYYY-MM-DD or one of [end_of_month, 当月末, end_of_next_month, 翌月末, end_of_month_after_next, 翌々月末]"}`,
 			wantCode: http.StatusBadRequest,
 		},
 		{
 			name:     "400 bad request with invalid status",
-			body:     `{"company_id":"1","issue_date":"1970-01-01","due_date":"1971-01-01","status":"UNKNOWN"}`,
+			body:     `{"company_id":"1","partner_id":"1","issue_date":"1970-01-01","due_date":"1971-01-01","status":"UNKNOWN"}`,
 			wantBody: fmt.Sprintf(`{"message":"'status' must be one of [unprocessed, processing, paid, error], but got %v"}`, "UNKNOWN"),
 			wantCode: http.StatusBadRequest,
 		},
+		{
+			name:          "422 unprocessable entity with unknown company",
+			body:          `{"company_id":"999","partner_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			registererErr: ErrCompanyNotFound,
+			wantBody:      `{"message":"Company 999 doesn't exist"}`,
+			wantCode:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:          "422 unprocessable entity with partner of another company",
+			body:          `{"company_id":"1","partner_id":"2","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			registererErr: ErrPartnerNotFound,
+			wantBody:      `{"message":"Business partner 2 doesn't exist in company 1"}`,
+			wantCode:      http.StatusUnprocessableEntity,
+		},
+		{
+			name:          "422 unprocessable entity with non-qualified invoice",
+			body:          `{"company_id":"1","partner_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			registererErr: fmt.Errorf("%w: tax per tax rate is missing", domain.ErrNonQualifiedInvoice),
+			wantBody:      `{"message":"invoice doesn't satisfy the qualified invoice system: tax per tax rate is missing"}`,
+			wantCode:      http.StatusUnprocessableEntity,
+		},
 		{
 			name:          "500 internal server error when registerer fails",
-			body:          `{"company_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
+			body:          `{"company_id":"1","partner_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`,
 			registererErr: errors.New("this is test"),
 			wantBody:      `{"message":"Failed to create invoice"}`,
 			wantCode:      http.StatusInternalServerError,
@@ -173,7 +416,7 @@ func TestCreateHandler(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			registerer := RegistererFunc(func(context.Context, string, time.Time, int, time.Time, string) (*domain.Invoice, error) {
+			registerer := RegistererFunc(func(context.Context, string, string, time.Time, int, []domain.InvoiceLine, time.Time, string) (*domain.Invoice, error) {
 				return tt.invoice, tt.registererErr
 			})
 			w := httptest.NewRecorder()
@@ -190,9 +433,216 @@ func TestCreateHandler(t *testing.T) {
 	}
 }
 
+func TestStatusHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		body       string
+		invoice    *domain.Invoice
+		changerErr error
+		wantBody   string
+		wantCode   int
+	}{
+		{
+			name:     "200 ok with updated invoice",
+			query:    "?company_id=1",
+			body:     `{"status":"paid"}`,
+			invoice:  &domain.Invoice{InvoiceID: "1", CompanyID: "1", PartnerID: "1", Status: domain.Paid},
+			wantBody: `{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"0001-01-01T00:00:00Z","amount":0,"fee":0,"fee_rate":0,"tax":0,"tax_rate":0,"withholding_tax":0,"total":0,"due_date":"0001-01-01T00:00:00Z","status":"paid","tax_amounts":[]}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "400 bad request without company_id",
+			body:     `{"status":"paid"}`,
+			wantBody: `{"message":"'company_id' mustn't be empty"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request when failed request body decode",
+			query:    "?company_id=1",
+			body:     `INVALID`,
+			wantBody: `{"message":"Failed to decode status request"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request with invalid status",
+			query:    "?company_id=1",
+			body:     `{"status":"UNKNOWN"}`,
+			wantBody: `{"message":"'status' must be one of [unprocessed, processing, paid, error], but got UNKNOWN"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:       "403 forbidden when invoice belongs to another company",
+			query:      "?company_id=1",
+			body:       `{"status":"paid"}`,
+			changerErr: ErrForbidden,
+			wantBody:   `{"message":"Forbidden"}`,
+			wantCode:   http.StatusForbidden,
+		},
+		{
+			name:       "404 not found when invoice doesn't exist",
+			query:      "?company_id=1",
+			body:       `{"status":"paid"}`,
+			changerErr: ErrNotFound,
+			wantBody:   `{"message":"Invoice not found"}`,
+			wantCode:   http.StatusNotFound,
+		},
+		{
+			name:       "409 conflict with illegal transition",
+			query:      "?company_id=1",
+			body:       `{"status":"unprocessed"}`,
+			changerErr: fmt.Errorf("%w: from paid to unprocessed", domain.ErrInvalidTransition),
+			wantBody:   `{"message":"Can't change status to unprocessed"}`,
+			wantCode:   http.StatusConflict,
+		},
+		{
+			name:       "409 conflict when invoice changed concurrently",
+			query:      "?company_id=1",
+			body:       `{"status":"paid"}`,
+			changerErr: ErrConflict,
+			wantBody:   `{"message":"Invoice was modified concurrently"}`,
+			wantCode:   http.StatusConflict,
+		},
+		{
+			name:       "500 internal server error when changer fails",
+			query:      "?company_id=1",
+			body:       `{"status":"paid"}`,
+			changerErr: errors.New("this is test"),
+			wantBody:   `{"message":"Failed to change invoice status"}`,
+			wantCode:   http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changer := StatusChangerFunc(func(context.Context, string, string, domain.Status) (*domain.Invoice, error) {
+				return tt.invoice, tt.changerErr
+			})
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPatch, "http://localhost/api/invoices/1/status"+tt.query, strings.NewReader(tt.body))
+			r.SetPathValue("id", "1")
+			f := StatusHandler(changer, slog.New(slog.NewTextHandler(os.Stderr, nil)))
+			f(w, r)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	invoiceOfCompany2 := &Row{InvoiceID: "2", CompanyID: "2", PartnerID: "2", Status: "unprocessed"}
+	findService := &FindService{
+		Selector: SelectorFunc(func(_ context.Context, q InvoiceQuery) (*Rows, error) {
+			assert.Equal(t, "1", q.CompanyID)
+			return &Rows{}, nil
+		}),
+		IDSelector: IDSelectorFunc(func(context.Context, string) (*Row, error) {
+			return invoiceOfCompany2, nil
+		}),
+	}
+	registerService := &RegisterService{
+		Inserter: InserterFunc(func(context.Context, string, *domain.Invoice) (*Row, error) {
+			require.FailNow(t, "invoice of another company must not be inserted")
+			return nil, nil
+		}),
+		CompanySelector: CompanySelectorFunc(func(context.Context, string) (*CompanyRow, error) {
+			require.FailNow(t, "company of another tenant must not be looked up")
+			return nil, nil
+		}),
+	}
+	statusService := &StatusService{
+		IDSelector: findService.IDSelector,
+		StatusUpdater: StatusUpdaterFunc(func(context.Context, string, domain.Status, domain.Status) (bool, error) {
+			require.FailNow(t, "invoice of another company must not be updated")
+			return false, nil
+		}),
+	}
+	tests := []struct {
+		name     string
+		handler  http.HandlerFunc
+		req      *http.Request
+		id       string
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "list invoices of the authenticated company by default",
+			handler:  ListHandler(findService, testClock, logger),
+			req:      httptest.NewRequest(http.MethodGet, "/api/invoices?due_date=1970-01-01", nil),
+			wantBody: `{"invoices":[]}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "403 forbidden listing invoices of another company",
+			handler:  ListHandler(findService, testClock, logger),
+			req:      httptest.NewRequest(http.MethodGet, "/api/invoices?company_id=2&due_date=1970-01-01", nil),
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "403 forbidden getting an invoice of another company by its own company_id",
+			handler:  GetHandler(findService, logger),
+			req:      httptest.NewRequest(http.MethodGet, "/api/invoices/2?company_id=2", nil),
+			id:       "2",
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "403 forbidden getting an invoice of another company without company_id",
+			handler:  GetHandler(findService, logger),
+			req:      httptest.NewRequest(http.MethodGet, "/api/invoices/2", nil),
+			id:       "2",
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "403 forbidden creating an invoice for another company",
+			handler:  CreateHandler(registerService, logger),
+			req:      httptest.NewRequest(http.MethodPost, "/api/invoices", strings.NewReader(`{"company_id":"2","partner_id":"2","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`)),
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+		{
+			name:     "403 forbidden changing status of an invoice of another company",
+			handler:  StatusHandler(statusService, logger),
+			req:      httptest.NewRequest(http.MethodPatch, "/api/invoices/2/status?company_id=2", strings.NewReader(`{"status":"processing"}`)),
+			id:       "2",
+			wantBody: `{"message":"Forbidden"}`,
+			wantCode: http.StatusForbidden,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req.WithContext(WithUser(tt.req.Context(), &domain.User{UserID: "1", CompanyID: "1", Username: "foo"}))
+			req.SetPathValue("id", tt.id)
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
 func TestBasicAuthMiddleWware(t *testing.T) {
 	username := "USERNAME"
 	password := "PASSWORD"
+	authenticator := AuthenticatorFunc(func(_ context.Context, u, p string) (*domain.User, error) {
+		if u == "BROKEN" {
+			return nil, errors.New("this is test")
+		}
+		if u != username || p != password {
+			return nil, ErrUnauthorized
+		}
+		return &domain.User{UserID: "1", CompanyID: "2", Username: u}, nil
+	})
 	tests := []struct {
 		name     string
 		req      *http.Request
@@ -204,6 +654,12 @@ func TestBasicAuthMiddleWware(t *testing.T) {
 			name: "next handler with valid credentials",
 			req:  newRequestWithBasicAuth(username, password),
 			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				user, ok := UserFromContext(r.Context())
+				require.True(t, ok)
+				assert.Equal(t, "1", user.UserID)
+				companyID, ok := CompanyIDFromContext(r.Context())
+				require.True(t, ok)
+				assert.Equal(t, "2", companyID)
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte("Next handler called"))
 			}),
@@ -228,17 +684,327 @@ func TestBasicAuthMiddleWware(t *testing.T) {
 			wantCode: http.StatusUnauthorized,
 			wantBody: `{"message":"Unauthorized"}`,
 		},
+		{
+			name: "500 internal server error when authenticator fails",
+			req:  newRequestWithBasicAuth("BROKEN", password),
+			handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.FailNow(t, "next handler should not be called")
+			}),
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"message":"Failed to authenticate"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			BasicAuthMiddleware(authenticator, tt.handler).ServeHTTP(w, tt.req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestBearerAuthMiddleware(t *testing.T) {
+	verifier := TokenVerifierFunc(func(_ context.Context, token string) (*domain.User, error) {
+		switch token {
+		case "VALID":
+			return &domain.User{UserID: "foo", CompanyID: "2", Username: "foo"}, nil
+		case "BROKEN":
+			return nil, errors.New("this is test")
+		}
+		return nil, fmt.Errorf("%w: this is test", ErrUnauthorized)
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		companyID, ok := CompanyIDFromContext(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, "2", companyID)
+		w.Write([]byte("Next handler called"))
+	})
+	tests := []struct {
+		name          string
+		authorization string
+		wantCode      int
+		wantBody      string
+	}{
+		{
+			name:          "next handler with valid token",
+			authorization: "Bearer VALID",
+			wantCode:      http.StatusOK,
+			wantBody:      "Next handler called",
+		},
+		{
+			name:     "401 unauthorized without token",
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"message":"Authorization Header doesn't exist"}`,
+		},
+		{
+			name:          "401 unauthorized with basic credentials",
+			authorization: "Basic Zm9vOnBhc3N3b3Jk",
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"Authorization Header doesn't exist"}`,
+		},
+		{
+			name:          "401 unauthorized with invalid token",
+			authorization: "Bearer INVALID",
+			wantCode:      http.StatusUnauthorized,
+			wantBody:      `{"message":"Unauthorized"}`,
+		},
+		{
+			name:          "500 internal server error when verifier fails",
+			authorization: "Bearer BROKEN",
+			wantCode:      http.StatusInternalServerError,
+			wantBody:      `{"message":"Failed to authenticate"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			w := httptest.NewRecorder()
+			BearerAuthMiddleware(verifier, slog.New(slog.NewTextHandler(os.Stderr, nil)), next).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	authenticator := KeyAuthenticatorFunc(func(_ context.Context, plain string) (*domain.APIKey, error) {
+		switch plain {
+		case "VALID":
+			return &domain.APIKey{KeyID: "1", CompanyID: "2", Scopes: []domain.Scope{domain.ScopeInvoicesRead}}, nil
+		case "BROKEN":
+			return nil, errors.New("this is test")
+		}
+		return nil, ErrUnauthorized
+	})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		companyID, ok := CompanyIDFromContext(r.Context())
+		require.True(t, ok)
+		assert.Equal(t, "2", companyID)
+		w.Write([]byte("Next handler called"))
+	})
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Fallback handler called"))
+	})
+	tests := []struct {
+		name     string
+		apiKey   string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "next handler with valid key",
+			apiKey:   "VALID",
+			wantCode: http.StatusOK,
+			wantBody: "Next handler called",
+		},
+		{
+			name:     "fallback handler without key",
+			wantCode: http.StatusOK,
+			wantBody: "Fallback handler called",
+		},
+		{
+			name:     "401 unauthorized with invalid key",
+			apiKey:   "INVALID",
+			wantCode: http.StatusUnauthorized,
+			wantBody: `{"message":"Unauthorized"}`,
+		},
+		{
+			name:     "500 internal server error when authenticator fails",
+			apiKey:   "BROKEN",
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"message":"Failed to authenticate"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://localhost", nil)
+			if tt.apiKey != "" {
+				req.Header.Set("X-API-Key", tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			APIKeyMiddleware(authenticator, slog.New(slog.NewTextHandler(os.Stderr, nil)), next, fallback).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestAPIKeyScopes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	finder := FinderFunc(func(context.Context, InvoiceQuery) (*InvoicePage, error) {
+		return &InvoicePage{Invoices: []domain.Invoice{}}, nil
+	})
+	registerer := RegistererFunc(func(_ context.Context, companyID, partnerID string, issueDate time.Time, amount int, _ []domain.InvoiceLine, dueDate time.Time, status string) (*domain.Invoice, error) {
+		return &domain.Invoice{InvoiceID: "1", CompanyID: companyID, PartnerID: partnerID, IssueDate: issueDate, Amount: amount, DueDate: dueDate, Status: domain.Status(status)}, nil
+	})
+	changer := StatusChangerFunc(func(_ context.Context, companyID, invoiceID string, status domain.Status) (*domain.Invoice, error) {
+		return &domain.Invoice{InvoiceID: invoiceID, CompanyID: companyID, Status: status}, nil
+	})
+	createBody := `{"company_id":"1","partner_id":"1","amount":10000,"issue_date":"1970-01-01","due_date":"2024-10-30","status":"processing"}`
+	tests := []struct {
+		name     string
+		scopes   []domain.Scope
+		handler  http.HandlerFunc
+		req      *http.Request
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "list with invoices:read",
+			scopes:   []domain.Scope{domain.ScopeInvoicesRead},
+			handler:  ListHandler(finder, testClock, logger),
+			req:      httptest.NewRequest(http.MethodGet, "/api/invoices?due_date=1970-01-01", nil),
+			wantCode: http.StatusOK,
+			wantBody: `{"invoices":[]}` + "\n",
+		},
+		{
+			name:     "403 forbidden listing without invoices:read",
+			scopes:   []domain.Scope{domain.ScopeInvoicesWrite},
+			handler:  ListHandler(finder, testClock, logger),
+			req:      httptest.NewRequest(http.MethodGet, "/api/invoices?due_date=1970-01-01", nil),
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"API key doesn't have scope invoices:read"}`,
+		},
+		{
+			name:     "create with invoices:write",
+			scopes:   []domain.Scope{domain.ScopeInvoicesWrite},
+			handler:  CreateHandler(registerer, logger),
+			req:      httptest.NewRequest(http.MethodPost, "/api/invoices", strings.NewReader(createBody)),
+			wantCode: http.StatusOK,
+			wantBody: `{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"1970-01-01T00:00:00Z","amount":10000,"fee":0,"fee_rate":0,"tax":0,"tax_rate":0,"withholding_tax":0,"total":0,"due_date":"2024-10-30T00:00:00Z","status":"processing","tax_amounts":[]}` + "\n",
+		},
+		{
+			name:     "403 forbidden creating without invoices:write",
+			scopes:   []domain.Scope{domain.ScopeInvoicesRead},
+			handler:  CreateHandler(registerer, logger),
+			req:      httptest.NewRequest(http.MethodPost, "/api/invoices", strings.NewReader(createBody)),
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"API key doesn't have scope invoices:write"}`,
+		},
+		{
+			name:     "change status with invoices:approve",
+			scopes:   []domain.Scope{domain.ScopeInvoicesApprove},
+			handler:  StatusHandler(changer, logger),
+			req:      httptest.NewRequest(http.MethodPatch, "/api/invoices/1/status", strings.NewReader(`{"status":"paid"}`)),
+			wantCode: http.StatusOK,
+			wantBody: `{"invoice_id":"","company_id":"1","partner_id":"","issue_date":"0001-01-01T00:00:00Z","amount":0,"fee":0,"fee_rate":0,"tax":0,"tax_rate":0,"withholding_tax":0,"total":0,"due_date":"0001-01-01T00:00:00Z","status":"paid","tax_amounts":[]}` + "\n",
+		},
+		{
+			name:     "403 forbidden changing status with invoices:write only",
+			scopes:   []domain.Scope{domain.ScopeInvoicesRead, domain.ScopeInvoicesWrite},
+			handler:  StatusHandler(changer, logger),
+			req:      httptest.NewRequest(http.MethodPatch, "/api/invoices/1/status", strings.NewReader(`{"status":"paid"}`)),
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"API key doesn't have scope invoices:approve"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req.WithContext(WithAPIKey(tt.req.Context(), &domain.APIKey{KeyID: "1", CompanyID: "1", Scopes: tt.scopes}))
+			w := httptest.NewRecorder()
+			tt.handler.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+
+			b, err := io.ReadAll(w.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantBody, string(b))
+		})
+	}
+}
+
+func TestPolicyMiddleware(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Next handler called"))
+	})
+	tests := []struct {
+		name       string
+		ctx        context.Context
+		permission domain.Permission
+		wantCode   int
+		wantBody   string
+		wantLog    string
+	}{
+		{
+			name:       "next handler when the role is granted the permission",
+			ctx:        WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1", Role: domain.Accountant}),
+			permission: domain.InvoicesCreate,
+			wantCode:   http.StatusOK,
+			wantBody:   "Next handler called",
+		},
+		{
+			name:       "403 forbidden when the role isn't granted the permission",
+			ctx:        WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1", Role: domain.Viewer}),
+			permission: domain.InvoicesCreate,
+			wantCode:   http.StatusForbidden,
+			wantBody:   `{"message":"Role viewer doesn't have permission invoices:create"}`,
+			wantLog:    "permission=invoices:create",
+		},
+		{
+			name:       "403 forbidden for users without a role",
+			ctx:        WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1"}),
+			permission: domain.InvoicesRead,
+			wantCode:   http.StatusForbidden,
+			wantBody:   `{"message":"Role  doesn't have permission invoices:read"}`,
+			wantLog:    "permission=invoices:read",
+		},
+		{
+			name:       "403 forbidden for admins creating a company",
+			ctx:        WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1", Role: domain.Admin}),
+			permission: domain.CompaniesCreate,
+			wantCode:   http.StatusForbidden,
+			wantBody:   `{"message":"Role admin doesn't have permission companies:create"}`,
+			wantLog:    "permission=companies:create",
+		},
+		{
+			name:       "next handler for API keys",
+			ctx:        WithAPIKey(context.Background(), &domain.APIKey{KeyID: "1", CompanyID: "1"}),
+			permission: domain.InvoicesCreate,
+			wantCode:   http.StatusOK,
+			wantBody:   "Next handler called",
+		},
+		{
+			name:       "next handler when authentication is disabled",
+			ctx:        context.Background(),
+			permission: domain.CompaniesManage,
+			wantCode:   http.StatusOK,
+			wantBody:   "Next handler called",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			var logs strings.Builder
+			logger := slog.New(slog.NewTextHandler(&logs, nil))
+			req := httptest.NewRequest(http.MethodPost, "/api/invoices", nil).WithContext(tt.ctx)
 			w := httptest.NewRecorder()
-			BasicAuthMiddleware(username, password, tt.handler).ServeHTTP(w, tt.req)
+			PolicyMiddleware(tt.permission, logger, next).ServeHTTP(w, req)
 
 			assert.Equal(t, tt.wantCode, w.Code)
 
 			b, err := io.ReadAll(w.Body)
 			require.NoError(t, err)
 			assert.Equal(t, tt.wantBody, string(b))
+			if tt.wantLog != "" {
+				assert.Contains(t, logs.String(), tt.wantLog)
+			} else {
+				assert.Empty(t, logs.String())
+			}
 		})
 	}
 }
@@ -248,3 +1014,308 @@ func newRequestWithBasicAuth(username, password string) *http.Request {
 	r.SetBasicAuth(username, password)
 	return r
 }
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		b, _ := io.ReadAll(r.Body)
+		if string(b) == `{"fail":true}` {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed"}`))
+			return
+		}
+		w.Write([]byte(fmt.Sprintf(`{"call":%d}`, calls)))
+	})
+	repository := &fakeIdempotencyRepository{}
+	handler := IdempotencyMiddleware(&IdempotencyService{Repository: repository, TTL: time.Hour}, slog.Default(), next)
+	ctx := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1"})
+	serve := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/invoices", strings.NewReader(body)).WithContext(ctx)
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("KEY", `{"amount": 1000}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"call":1}`, w.Body.String())
+
+	w = serve("KEY", `{"amount":1000}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"call":1}`, w.Body.String(), "the retry should get the original response")
+	assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+
+	w = serve("KEY", `{"amount":2000}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, `{"message":"Idempotency-Key was already used for a different request"}`, w.Body.String())
+
+	w = serve("", `{"amount":1000}`)
+	assert.Equal(t, `{"call":2}`, w.Body.String(), "requests without the header should always be processed")
+
+	w = serve("FAIL", `{"fail":true}`)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.NotContains(t, repository.records, "1/FAIL", "server errors should release the key")
+
+	repository.records["1/BUSY"] = &IdempotencyRecord{CompanyID: "1", Key: "BUSY", Fingerprint: requestFingerprint(httptest.NewRequest(http.MethodPost, "/api/invoices", nil), []byte("{}")), ExpiresAt: time.Now().Add(time.Minute)}
+	w = serve("BUSY", `{}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, `{"message":"A request with the same Idempotency-Key is in progress"}`, w.Body.String())
+
+	w = serve(strings.Repeat("k", 256), `{}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, 3, calls)
+}
+
+func TestIdempotencyMiddleware_Query(t *testing.T) {
+	calls := 0
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte(fmt.Sprintf(`{"call":%d}`, calls)))
+	})
+	handler := IdempotencyMiddleware(&IdempotencyService{Repository: &fakeIdempotencyRepository{}, TTL: time.Hour}, slog.Default(), next)
+	ctx := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1"})
+	serve := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/invoices:batch"+query, strings.NewReader(`{"invoices":[]}`)).WithContext(ctx)
+		req.Header.Set("Idempotency-Key", "KEY")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("?mode=all_or_nothing")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `{"call":1}`, w.Body.String())
+
+	w = serve("?mode=all_or_nothing")
+	assert.Equal(t, `{"call":1}`, w.Body.String(), "the retry should get the original response")
+
+	w = serve("?mode=best_effort")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, `{"message":"Idempotency-Key was already used for a different request"}`, w.Body.String())
+	assert.Equal(t, 1, calls)
+}
+
+func TestBatchCreateHandler(t *testing.T) {
+	invoice := &domain.Invoice{
+		InvoiceID: "1",
+		CompanyID: "1",
+		PartnerID: "1",
+		IssueDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+		Amount:    10000,
+		Fee:       400,
+		FeeRate:   domain.MustParseRate("0.04"),
+		Tax:       40,
+		TaxRate:   domain.MustParseRate("0.10"),
+		Total:     10440,
+		DueDate:   time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
+		Status:    domain.Unprocessed,
+	}
+	const created = `{"index":0,"status":"created","invoice":{"invoice_id":"1","company_id":"1","partner_id":"1","issue_date":"2024-10-01T00:00:00Z","amount":10000,"fee":400,"fee_rate":0.04,"tax":40,"tax_rate":0.1,"withholding_tax":0,"total":10440,"due_date":"2024-10-31T00:00:00Z","status":"unprocessed","tax_amounts":[]}}`
+	const body = `[{"company_id":"1","partner_id":"1","amount":10000,"issue_date":"2024-10-01","due_date":"2024-10-31","status":"unprocessed"},{"company_id":"1","partner_id":"9","amount":10000,"issue_date":"2024-10-01","due_date":"2024-10-31","status":"unprocessed"},{"company_id":"1","amount":10000,"issue_date":"2024-10-01","due_date":"2024-10-31","status":"unprocessed"}]`
+	tests := []struct {
+		name     string
+		query    string
+		body     string
+		results  []BatchResult
+		err      error
+		wantMode BatchMode
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "200 ok with partially created invoices in best effort mode",
+			query:    "?mode=best_effort",
+			body:     body,
+			results:  []BatchResult{{Invoice: invoice}, {Err: ErrPartnerNotFound}, {Err: errors.New("'partner_id' mustn't be empty")}},
+			wantMode: BestEffort,
+			wantBody: `{"mode":"best_effort","created":1,"failed":2,"results":[` + created + `,{"index":1,"status":"failed","message":"Business partner 9 doesn't exist in company 1"},{"index":2,"status":"failed","message":"'partner_id' mustn't be empty"}]}` + "\n",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "422 unprocessable entity when an invoice of all or nothing batch failed",
+			body:     body,
+			results:  []BatchResult{{}, {Err: ErrPartnerNotFound}, {Err: errors.New("'partner_id' mustn't be empty")}},
+			wantMode: AllOrNothing,
+			wantBody: `{"mode":"all_or_nothing","created":0,"failed":2,"results":[{"index":0,"status":"skipped"},{"index":1,"status":"failed","message":"Business partner 9 doesn't exist in company 1"},{"index":2,"status":"failed","message":"'partner_id' mustn't be empty"}]}` + "\n",
+			wantCode: http.StatusUnprocessableEntity,
+		},
+		{
+			name:     "400 bad request with unknown mode",
+			query:    "?mode=some",
+			body:     body,
+			wantBody: `{"message":"'mode' must be one of [all_or_nothing, best_effort], but got some"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request when body isn't an array",
+			body:     `{"company_id":"1"}`,
+			wantBody: `{"message":"Failed to decode invoice requests"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request with too many invoices",
+			body:     "[" + strings.Repeat(`{},`, 3) + "{}]",
+			wantBody: `{"message":"The number of invoices must be between 1 and 3"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "400 bad request with no invoices",
+			body:     "[]",
+			wantBody: `{"message":"The number of invoices must be between 1 and 3"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "500 internal server error when registerer failed",
+			body:     body,
+			err:      errors.New("this is test"),
+			wantMode: AllOrNothing,
+			wantBody: `{"message":"Failed to create invoices"}`,
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registerer := BatchRegistererFunc(func(_ context.Context, items []BatchItem, mode BatchMode) ([]BatchResult, error) {
+				assert.Equal(t, tt.wantMode, mode)
+				assert.Len(t, items, 3)
+				assert.EqualError(t, items[2].Err, "'partner_id' mustn't be empty")
+				return tt.results, tt.err
+			})
+			req := httptest.NewRequest(http.MethodPost, "/api/invoices:batch"+tt.query, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			BatchCreateHandler(registerer, 3, slog.New(slog.NewTextHandler(io.Discard, nil))).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+		})
+	}
+}
+
+func TestExportHandler(t *testing.T) {
+	invoices := []domain.Invoice{
+		{InvoiceID: "1", CompanyID: "1", PartnerID: "1", IssueDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), Amount: 10000, Total: 10440, DueDate: time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC), Status: domain.Unprocessed},
+		{InvoiceID: "2", CompanyID: "1", PartnerID: "2", IssueDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), Amount: 5000, Total: 5220, DueDate: time.Date(2024, 11, 30, 0, 0, 0, 0, time.UTC), Status: domain.Paid},
+	}
+	fallback := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Fallback handler called"))
+	})
+	tests := []struct {
+		name      string
+		query     string
+		accept    string
+		err       error
+		wantLimit int
+		wantCode  int
+		wantBody  string
+		wantType  string
+	}{
+		{
+			name:     "csv requested with the format parameter",
+			query:    "company_id=1&format=csv&columns=invoice_id,total,status",
+			wantCode: http.StatusOK,
+			wantBody: "invoice_id,total,status\n1,10440,unprocessed\n2,5220,paid\n",
+			wantType: "text/csv; charset=utf-8",
+		},
+		{
+			name:      "csv requested with the accept header with bom and limit",
+			query:     "company_id=1&bom=true&columns=invoice_id&limit=2",
+			accept:    "text/csv;q=0.9, application/json;q=0.5",
+			wantLimit: 2,
+			wantCode:  http.StatusOK,
+			wantBody:  "\ufeffinvoice_id\n1\n2\n",
+			wantType:  "text/csv; charset=utf-8",
+		},
+		{
+			name:     "json requested",
+			query:    "company_id=1&format=json",
+			accept:   "text/csv",
+			wantCode: http.StatusOK,
+			wantBody: "Fallback handler called",
+		},
+		{
+			name:     "no format requested",
+			query:    "company_id=1",
+			wantCode: http.StatusOK,
+			wantBody: "Fallback handler called",
+		},
+		{
+			name:     "400 bad request with unknown format",
+			query:    "company_id=1&format=xlsx",
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"message":"'format' must be one of [json, csv], but got xlsx"}`,
+		},
+		{
+			name:     "400 bad request with unknown column",
+			query:    "company_id=1&format=csv&columns=secret",
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"message":"invalid query: 'columns' must be some of [invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status], but got secret"}`,
+		},
+		{
+			name:     "400 bad request with invalid filter",
+			query:    "company_id=1&format=csv&status=unknown",
+			wantCode: http.StatusBadRequest,
+			wantBody: `{"message":"invalid query: 'status' must be one of [unprocessed, processing, paid, error], but got unknown"}`,
+		},
+		{
+			name:     "403 forbidden",
+			query:    "company_id=1&format=csv",
+			err:      ErrForbidden,
+			wantCode: http.StatusForbidden,
+			wantBody: `{"message":"Forbidden"}`,
+		},
+		{
+			name:     "500 internal server error before streaming",
+			query:    "company_id=1&format=csv",
+			err:      errors.New("this is test"),
+			wantCode: http.StatusInternalServerError,
+			wantBody: `{"message":"Failed to export invoices"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := ExporterFunc(func(_ context.Context, q InvoiceQuery, f func(*domain.Invoice) error) error {
+				assert.Equal(t, tt.wantLimit, q.Limit)
+				if tt.err != nil {
+					return tt.err
+				}
+				for _, invoice := range invoices {
+					if err := f(&invoice); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			req := httptest.NewRequest(http.MethodGet, "/api/invoices?"+tt.query, nil)
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+			w := httptest.NewRecorder()
+			ExportHandler(exporter, testClock, slog.New(slog.NewTextHandler(io.Discard, nil)), fallback).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			if tt.wantType != "" {
+				assert.Equal(t, tt.wantType, w.Header().Get("Content-Type"))
+			} else {
+				assert.NotContains(t, w.Header().Get("Content-Type"), "text/csv")
+			}
+		})
+	}
+
+	t.Run("connection is aborted on errors while streaming", func(t *testing.T) {
+		exporter := ExporterFunc(func(_ context.Context, _ InvoiceQuery, f func(*domain.Invoice) error) error {
+			if err := f(&invoices[0]); err != nil {
+				return err
+			}
+			return errors.New("this is test")
+		})
+		req := httptest.NewRequest(http.MethodGet, "/api/invoices?company_id=1&format=csv", nil)
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			ExportHandler(exporter, testClock, slog.New(slog.NewTextHandler(io.Discard, nil)), fallback).ServeHTTP(httptest.NewRecorder(), req)
+		})
+	})
+}
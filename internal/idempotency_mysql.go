@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+var _ IdempotencyRepository = (*MySQL)(nil)
+
+// ClaimIdempotencyKey inserts the claim unless the key is held by another request, in which case the record holding
+// it is returned. The primary key serializes concurrent claims: a claim waits until the transaction inserting the
+// same key commits and then reads its record. An expired record is replaced by the claim.
+func (s *MySQL) ClaimIdempotencyKey(ctx context.Context, claim *IdempotencyRecord, now time.Time) (*IdempotencyRecord, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE company_id = ? AND idempotency_key = ? AND expires_at <= ?;", claim.CompanyID, claim.Key, now.UTC().Format(time.DateTime)); err != nil {
+		return nil, err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO idempotency_keys (company_id, idempotency_key, fingerprint, status, expires_at) VALUES (?, ?, ?, 'processing', ?);",
+		claim.CompanyID, claim.Key, claim.Fingerprint, claim.ExpiresAt.UTC().Format(time.DateTime))
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return s.selectIdempotencyKey(ctx, tx, claim.CompanyID, claim.Key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, tx.Commit()
+}
+
+func (s *MySQL) selectIdempotencyKey(ctx context.Context, tx *sql.Tx, companyID, key string) (*IdempotencyRecord, error) {
+	var (
+		record       = IdempotencyRecord{CompanyID: companyID, Key: key}
+		status       string
+		responseCode sql.NullInt64
+		expiresAt    string
+	)
+	err := tx.QueryRowContext(ctx, "SELECT fingerprint, status, response_code, response_body, expires_at FROM idempotency_keys WHERE company_id = ? AND idempotency_key = ?;", companyID, key).
+		Scan(&record.Fingerprint, &status, &responseCode, &record.ResponseBody, &expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	record.Completed = status == "completed"
+	record.ResponseCode = int(responseCode.Int64)
+	record.ExpiresAt, err = time.ParseInLocation(time.DateTime, expiresAt, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CompleteIdempotencyKey stores the response in the record of the claim. The claim is matched by its fingerprint
+// and expiry, so that a request whose lease ran out doesn't overwrite the key claimed again by another request.
+func (s *MySQL) CompleteIdempotencyKey(ctx context.Context, claim, record *IdempotencyRecord) error {
+	result, err := s.DB.ExecContext(ctx, "UPDATE idempotency_keys SET status = 'completed', response_code = ?, response_body = ?, expires_at = ? WHERE company_id = ? AND idempotency_key = ? AND fingerprint = ? AND status = 'processing' AND expires_at = ?;",
+		record.ResponseCode, record.ResponseBody, record.ExpiresAt.UTC().Format(time.DateTime), claim.CompanyID, claim.Key, claim.Fingerprint, claim.ExpiresAt.UTC().Format(time.DateTime))
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrIdempotencyKeyLost
+	}
+	return nil
+}
+
+func (s *MySQL) DeleteIdempotencyKey(ctx context.Context, companyID, key string) error {
+	_, err := s.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE company_id = ? AND idempotency_key = ?;", companyID, key)
+	return err
+}
+
+func (s *MySQL) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= ?;", now.UTC().Format(time.DateTime))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQL_ClaimIdempotencyKey(t *testing.T) {
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	claim := &IdempotencyRecord{CompanyID: "1", Key: "KEY", Fingerprint: []byte("FINGERPRINT"), ExpiresAt: now.Add(time.Minute)}
+	tests := []struct {
+		name   string
+		expect func(sqlmock.Sqlmock)
+		want   *IdempotencyRecord
+	}{
+		{
+			name: "claimed",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys (company_id, idempotency_key, fingerprint, status, expires_at) VALUES (?, ?, ?, 'processing', ?);")).
+					WithArgs("1", "KEY", []byte("FINGERPRINT"), "2024-10-01 09:01:00").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name: "held by a completed request",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).WillReturnError(&mysql.MySQLError{Number: errDupEntry})
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT fingerprint, status, response_code, response_body, expires_at FROM idempotency_keys WHERE company_id = ? AND idempotency_key = ?;")).
+					WithArgs("1", "KEY").
+					WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status", "response_code", "response_body", "expires_at"}).AddRow([]byte("FINGERPRINT"), "completed", 200, []byte("{}"), "2024-10-02 09:00:00"))
+				mock.ExpectRollback()
+			},
+			want: &IdempotencyRecord{CompanyID: "1", Key: "KEY", Fingerprint: []byte("FINGERPRINT"), Completed: true, ResponseCode: 200, ResponseBody: []byte("{}"), ExpiresAt: time.Date(2024, 10, 2, 9, 0, 0, 0, time.UTC)},
+		},
+		{
+			name: "held by a request in progress",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO idempotency_keys")).WillReturnError(&mysql.MySQLError{Number: errDupEntry})
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT fingerprint, status, response_code, response_body, expires_at FROM idempotency_keys")).
+					WillReturnRows(sqlmock.NewRows([]string{"fingerprint", "status", "response_code", "response_body", "expires_at"}).AddRow([]byte("FINGERPRINT"), "processing", nil, nil, "2024-10-01 09:01:00"))
+				mock.ExpectRollback()
+			},
+			want: &IdempotencyRecord{CompanyID: "1", Key: "KEY", Fingerprint: []byte("FINGERPRINT"), ExpiresAt: time.Date(2024, 10, 1, 9, 1, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE company_id = ? AND idempotency_key = ? AND expires_at <= ?;")).
+				WithArgs("1", "KEY", "2024-10-01 09:00:00").WillReturnResult(sqlmock.NewResult(0, 0))
+			tt.expect(mock)
+
+			s := &MySQL{DB: db}
+			got, err := s.ClaimIdempotencyKey(context.Background(), claim, now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMySQL_CompleteIdempotencyKey(t *testing.T) {
+	claim := &IdempotencyRecord{CompanyID: "1", Key: "KEY", Fingerprint: []byte("FINGERPRINT"), ExpiresAt: time.Date(2024, 10, 1, 9, 1, 0, 0, time.UTC)}
+	record := &IdempotencyRecord{CompanyID: "1", Key: "KEY", Fingerprint: []byte("FINGERPRINT"), Completed: true, ResponseCode: 200, ResponseBody: []byte("{}"), ExpiresAt: time.Date(2024, 10, 2, 9, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name    string
+		updated int64
+		wantErr error
+	}{
+		{name: "claimed", updated: 1},
+		{name: "claimed again by another request", updated: 0, wantErr: ErrIdempotencyKeyLost},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE idempotency_keys SET status = 'completed', response_code = ?, response_body = ?, expires_at = ? WHERE company_id = ? AND idempotency_key = ? AND fingerprint = ? AND status = 'processing' AND expires_at = ?;")).
+				WithArgs(200, []byte("{}"), "2024-10-02 09:00:00", "1", "KEY", []byte("FINGERPRINT"), "2024-10-01 09:01:00").WillReturnResult(sqlmock.NewResult(0, tt.updated))
+
+			s := &MySQL{DB: db}
+			err = s.CompleteIdempotencyKey(context.Background(), claim, record)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMySQL_DeleteExpiredIdempotencyKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE expires_at <= ?;")).WithArgs("2024-10-01 09:00:00").WillReturnResult(sqlmock.NewResult(0, 3))
+
+	s := &MySQL{DB: db}
+	n, err := s.DeleteExpiredIdempotencyKeys(context.Background(), time.Date(2024, 10, 1, 18, 0, 0, 0, time.FixedZone("JST", 9*60*60)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was used for another request")
+	ErrIdempotencyKeyInProgress = errors.New("request with the idempotency key is in progress")
+	ErrIdempotencyKeyLost       = errors.New("idempotency key is no longer claimed by the request")
+)
+
+// idempotencyLease is the default Lease of IdempotencyService.
+const idempotencyLease = time.Minute
+
+// IdempotencyRecord is a request made with an Idempotency-Key header. The response is stored once the request
+// completes, and replayed to the retries of the same request until ExpiresAt.
+type IdempotencyRecord struct {
+	CompanyID    string
+	Key          string
+	Fingerprint  []byte
+	Completed    bool
+	ResponseCode int
+	ResponseBody []byte
+	ExpiresAt    time.Time
+}
+
+type IdempotencyRepository interface {
+	ClaimIdempotencyKey(context.Context, *IdempotencyRecord, time.Time) (*IdempotencyRecord, error)
+	CompleteIdempotencyKey(context.Context, *IdempotencyRecord, *IdempotencyRecord) error
+	DeleteIdempotencyKey(context.Context, string, string) error
+	DeleteExpiredIdempotencyKeys(context.Context, time.Time) (int64, error)
+}
+
+type IdempotencyService struct {
+	Repository IdempotencyRepository
+	// TTL is how long the response of a completed request is replayed.
+	TTL time.Duration
+	// Lease is how long a key stays claimed by a request being processed. A key left claimed by a crashed server
+	// can be claimed again after it, so it should be longer than the slowest request. Zero means a minute.
+	Lease time.Duration
+	// Clock tells when the keys expire. Nil means the system clock.
+	Clock Clock
+}
+
+// Begin claims the key of the company for the request identified by fingerprint. It returns the claim to pass to
+// Complete when the request should be processed, or the completed record to replay. ErrIdempotencyKeyReused is
+// returned when the key was used for a different request, and ErrIdempotencyKeyInProgress while another request
+// holds it.
+func (s *IdempotencyService) Begin(ctx context.Context, companyID, key string, fingerprint []byte) (*IdempotencyRecord, error) {
+	now := currentTime(s.Clock)
+	lease := s.Lease
+	if lease <= 0 {
+		lease = idempotencyLease
+	}
+	claim := &IdempotencyRecord{CompanyID: companyID, Key: key, Fingerprint: fingerprint, ExpiresAt: now.Add(lease)}
+	existing, err := s.Repository.ClaimIdempotencyKey(ctx, claim, now)
+	if err != nil {
+		return nil, fmt.Errorf("claim error: %w", err)
+	}
+	if existing == nil {
+		return claim, nil
+	}
+	if !bytes.Equal(existing.Fingerprint, fingerprint) {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+	return existing, nil
+}
+
+// Complete stores the response of the request holding the claim returned by Begin. ErrIdempotencyKeyLost is
+// returned when the lease ran out and the key was claimed again by another request or released, in which case
+// nothing is stored.
+func (s *IdempotencyService) Complete(ctx context.Context, claim *IdempotencyRecord, code int, body []byte) error {
+	record := &IdempotencyRecord{CompanyID: claim.CompanyID, Key: claim.Key, Fingerprint: claim.Fingerprint, Completed: true, ResponseCode: code, ResponseBody: body, ExpiresAt: currentTime(s.Clock).Add(s.TTL)}
+	if err := s.Repository.CompleteIdempotencyKey(ctx, claim, record); err != nil {
+		return fmt.Errorf("complete error: %w", err)
+	}
+	return nil
+}
+
+// Release gives up the key claimed by Begin so that the request can be retried, e.g. after a server error.
+func (s *IdempotencyService) Release(ctx context.Context, companyID, key string) error {
+	if err := s.Repository.DeleteIdempotencyKey(ctx, companyID, key); err != nil {
+		return fmt.Errorf("release error: %w", err)
+	}
+	return nil
+}
+
+// Purge deletes the expired keys and returns how many were deleted.
+func (s *IdempotencyService) Purge(ctx context.Context) (int64, error) {
+	n, err := s.Repository.DeleteExpiredIdempotencyKeys(ctx, currentTime(s.Clock))
+	if err != nil {
+		return 0, fmt.Errorf("purge error: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIdempotencyRepository keeps the records in memory like the idempotency_keys table.
+type fakeIdempotencyRepository struct {
+	records map[string]*IdempotencyRecord
+	err     error
+}
+
+func (r *fakeIdempotencyRepository) ClaimIdempotencyKey(_ context.Context, claim *IdempotencyRecord, now time.Time) (*IdempotencyRecord, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.records == nil {
+		r.records = make(map[string]*IdempotencyRecord)
+	}
+	id := claim.CompanyID + "/" + claim.Key
+	if existing, ok := r.records[id]; ok && existing.ExpiresAt.After(now) {
+		return existing, nil
+	}
+	r.records[id] = claim
+	return nil, nil
+}
+
+func (r *fakeIdempotencyRepository) CompleteIdempotencyKey(_ context.Context, claim, record *IdempotencyRecord) error {
+	if r.err != nil {
+		return r.err
+	}
+	existing, ok := r.records[claim.CompanyID+"/"+claim.Key]
+	if !ok || existing.Completed || !bytes.Equal(existing.Fingerprint, claim.Fingerprint) || !existing.ExpiresAt.Equal(claim.ExpiresAt) {
+		return ErrIdempotencyKeyLost
+	}
+	existing.Completed, claim.ResponseCode, claim.ResponseBody, claim.ExpiresAt = true, record.ResponseCode, record.ResponseBody, record.ExpiresAt
+	return nil
+}
+
+func (r *fakeIdempotencyRepository) DeleteIdempotencyKey(_ context.Context, companyID, key string) error {
+	delete(r.records, companyID+"/"+key)
+	return r.err
+}
+
+func (r *fakeIdempotencyRepository) DeleteExpiredIdempotencyKeys(_ context.Context, now time.Time) (int64, error) {
+	var n int64
+	for id, record := range r.records {
+		if !record.ExpiresAt.After(now) {
+			delete(r.records, id)
+			n++
+		}
+	}
+	return n, r.err
+}
+
+func TestIdempotencyService(t *testing.T) {
+	ctx := context.Background()
+	repository := &fakeIdempotencyRepository{}
+	s := &IdempotencyService{Repository: repository, TTL: time.Hour}
+
+	claim, err := s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.False(t, claim.Completed, "the first request should be processed")
+	assert.WithinDuration(t, time.Now().Add(idempotencyLease), repository.records["1/KEY"].ExpiresAt, time.Minute)
+
+	_, err = s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress)
+
+	require.NoError(t, s.Complete(ctx, claim, 200, []byte(`{"invoice_id":"1"}`)))
+	record, err := s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.Equal(t, 200, record.ResponseCode)
+	assert.Equal(t, []byte(`{"invoice_id":"1"}`), record.ResponseBody)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), record.ExpiresAt, time.Minute)
+
+	_, err = s.Begin(ctx, "1", "KEY", []byte("ANOTHER"))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+
+	record, err = s.Begin(ctx, "2", "KEY", []byte("ANOTHER"))
+	require.NoError(t, err)
+	assert.False(t, record.Completed, "keys should be scoped by the company")
+	require.NoError(t, s.Release(ctx, "2", "KEY"))
+	assert.NotContains(t, repository.records, "2/KEY")
+
+	repository.records["1/KEY"].ExpiresAt = time.Now().Add(-time.Second)
+	n, err := s.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	s = &IdempotencyService{Repository: &fakeIdempotencyRepository{err: errors.New("ERROR")}}
+	_, err = s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	assert.EqualError(t, err, "claim error: ERROR")
+}
+
+func TestIdempotencyService_Clock(t *testing.T) {
+	ctx := context.Background()
+	repository := &fakeIdempotencyRepository{}
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	s := &IdempotencyService{Repository: repository, TTL: time.Hour, Clock: ClockFunc(func() time.Time { return now })}
+
+	record, err := s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.False(t, record.Completed)
+	assert.Equal(t, now.Add(idempotencyLease), repository.records["1/KEY"].ExpiresAt)
+
+	require.NoError(t, s.Complete(ctx, record, 200, []byte(`{"invoice_id":"1"}`)))
+	assert.Equal(t, now.Add(time.Hour), repository.records["1/KEY"].ExpiresAt)
+
+	record, err = s.Begin(ctx, "2", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.False(t, record.Completed)
+	now = now.Add(idempotencyLease)
+	record, err = s.Begin(ctx, "2", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.False(t, record.Completed, "the key left claimed should be claimed again after the lease")
+
+	now = now.Add(time.Hour)
+	n, err := s.Purge(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+}
+
+func TestIdempotencyService_Lease(t *testing.T) {
+	ctx := context.Background()
+	repository := &fakeIdempotencyRepository{}
+	now := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	s := &IdempotencyService{Repository: repository, TTL: time.Hour, Lease: 5 * time.Minute, Clock: ClockFunc(func() time.Time { return now })}
+
+	slow, err := s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(5*time.Minute), slow.ExpiresAt)
+
+	now = now.Add(time.Minute)
+	_, err = s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyInProgress, "the key should stay claimed for the configured lease")
+
+	now = now.Add(5 * time.Minute)
+	retry, err := s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.False(t, retry.Completed, "the key should be claimed again after the lease")
+
+	err = s.Complete(ctx, slow, 200, []byte(`{"invoice_id":"1"}`))
+	assert.ErrorIs(t, err, ErrIdempotencyKeyLost, "the request whose lease ran out shouldn't store its response")
+	assert.False(t, repository.records["1/KEY"].Completed)
+
+	require.NoError(t, s.Complete(ctx, retry, 200, []byte(`{"invoice_id":"2"}`)))
+	record, err := s.Begin(ctx, "1", "KEY", []byte("FINGERPRINT"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte(`{"invoice_id":"2"}`), record.ResponseBody)
+
+	require.NoError(t, s.Release(ctx, "1", "KEY"))
+	assert.ErrorIs(t, s.Complete(ctx, retry, 200, nil), ErrIdempotencyKeyLost)
+}
@@ -0,0 +1,211 @@
+package internal
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"golang.org/x/text/encoding/japanese"
+)
+
+var (
+	ErrInvalidImport = errors.New("invalid import file")
+	errInvalidAmount = errors.New("Failed to decode amount as integer")
+)
+
+// ImportFields are the fields of InvoiceRequest the columns of an imported CSV are mapped to.
+var ImportFields = []string{"company_id", "partner_id", "issue_date", "amount", "due_date", "status"}
+
+// requiredImportFields are the fields without a default.
+var requiredImportFields = []string{"partner_id", "issue_date", "amount", "due_date"}
+
+// utf8BOM is written at the head of UTF-8 files by Excel.
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// ImportOptions tells how to read an imported CSV.
+type ImportOptions struct {
+	// Mapping maps the headers of the file to ImportFields. Headers not in it are mapped to the field of the same name,
+	// and the other columns are ignored.
+	Mapping map[string]string
+	// Encoding is utf-8, shift_jis or auto, which reads files that aren't valid UTF-8 as Shift_JIS.
+	Encoding string
+	// CompanyID is the company of the rows without company_id.
+	CompanyID string
+}
+
+// ImportRow is a row of an imported CSV. Err is set when the row can't be read as an InvoiceRequest.
+type ImportRow struct {
+	Line    int
+	Request InvoiceRequest
+	Err     error
+}
+
+// decodeCSV returns the content of the file in UTF-8.
+func decodeCSV(b []byte, encoding string) ([]byte, error) {
+	switch strings.ToLower(encoding) {
+	case "", "auto":
+		if utf8.Valid(b) {
+			return bytes.TrimPrefix(b, utf8BOM), nil
+		}
+		return japanese.ShiftJIS.NewDecoder().Bytes(b)
+	case "utf-8", "utf8":
+		if !utf8.Valid(b) {
+			return nil, fmt.Errorf("%w: file isn't encoded in UTF-8", ErrInvalidImport)
+		}
+		return bytes.TrimPrefix(b, utf8BOM), nil
+	case "shift_jis", "sjis":
+		b, err := japanese.ShiftJIS.NewDecoder().Bytes(b)
+		if err != nil {
+			return nil, fmt.Errorf("%w: file isn't encoded in Shift_JIS: %v", ErrInvalidImport, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: encoding must be one of [auto, utf-8, shift_jis], but got %v", ErrInvalidImport, encoding)
+}
+
+// ReadInvoiceCSV reads the invoices of a CSV whose first line is the header. ErrInvalidImport is returned when
+// the file as a whole can't be read, e.g. a required column is missing, while the errors of each row are
+// reported in the rows.
+func ReadInvoiceCSV(r io.Reader, opts ImportOptions) ([]ImportRow, error) {
+	for header, field := range opts.Mapping {
+		if !slices.Contains(ImportFields, field) {
+			return nil, fmt.Errorf("%w: header %v is mapped to unknown field %v", ErrInvalidImport, header, field)
+		}
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	b, err = decodeCSV(b, opts.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(bytes.NewReader(b))
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	columns := make(map[string]int)
+	for i, h := range header {
+		h = strings.TrimSpace(h)
+		field, ok := opts.Mapping[h]
+		if !ok {
+			field = h
+		}
+		if !slices.Contains(ImportFields, field) {
+			continue
+		}
+		if _, ok := columns[field]; ok {
+			return nil, fmt.Errorf("%w: more than one column is mapped to %v", ErrInvalidImport, field)
+		}
+		columns[field] = i
+	}
+	for _, field := range requiredImportFields {
+		if _, ok := columns[field]; !ok {
+			return nil, fmt.Errorf("%w: column of %v doesn't exist", ErrInvalidImport, field)
+		}
+	}
+
+	rows := make([]ImportRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(field string) string {
+			i, ok := columns[field]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		row := ImportRow{Line: line, Request: InvoiceRequest{
+			CompanyID: value("company_id"),
+			PartnerID: value("partner_id"),
+			IssueDate: value("issue_date"),
+			DueDate:   value("due_date"),
+			Status:    value("status"),
+		}}
+		if row.Request.CompanyID == "" {
+			row.Request.CompanyID = opts.CompanyID
+		}
+		if row.Request.Status == "" {
+			row.Request.Status = string(domain.Unprocessed)
+		}
+		row.Request.Amount, err = strconv.Atoi(value("amount"))
+		if err != nil {
+			row.Err = errInvalidAmount
+		}
+		rows = append(rows, row)
+	}
+}
+
+// ImportResult is the outcome of an imported row. Status is created, failed, skipped when another row of
+// an all_or_nothing import failed, or valid in a dry run.
+type ImportResult struct {
+	Line    int              `json:"line"`
+	Status  string           `json:"status"`
+	Invoice *InvoiceResponse `json:"invoice,omitempty"`
+	Message string           `json:"message,omitempty"`
+}
+
+// Import validates the rows with the rules of POST /api/invoices and creates the invoices of them in a single
+// transaction. Nothing is created when dryRun is true.
+func (s *RegisterService) Import(ctx context.Context, rows []ImportRow, mode BatchMode, dryRun bool) ([]ImportResult, error) {
+	items := make([]BatchItem, 0, len(rows))
+	for _, row := range rows {
+		item := BatchItem{CompanyID: row.Request.CompanyID, PartnerID: row.Request.PartnerID, Amount: row.Request.Amount, Status: row.Request.Status, Err: row.Err}
+		if item.Err == nil {
+			item.IssueDate, item.DueDate, item.Err = row.Request.Validate()
+		}
+		items = append(items, item)
+	}
+	var (
+		results []BatchResult
+		err     error
+	)
+	if dryRun {
+		results, err = s.ValidateBatch(ctx, items)
+	} else {
+		results, err = s.RegisterBatch(ctx, items, mode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	imported := make([]ImportResult, 0, len(results))
+	for i, result := range results {
+		r := ImportResult{Line: rows[i].Line}
+		switch {
+		case result.Invoice != nil:
+			invoice := NewInvoiceResponse(result.Invoice)
+			r.Status, r.Invoice = "created", &invoice
+		case result.Err != nil:
+			r.Status, r.Message = "failed", batchErrorMessage(result.Err, &rows[i].Request)
+		case dryRun:
+			r.Status = "valid"
+		default:
+			r.Status = "skipped"
+		}
+		imported = append(imported, r)
+	}
+	return imported, nil
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestReadInvoiceCSV(t *testing.T) {
+	shiftJIS, err := japanese.ShiftJIS.NewEncoder().String("取引先,発行日,金額,支払期日,備考\n1,2024-10-01,10000,2024-10-31,十月分\n")
+	require.NoError(t, err)
+	mapping := map[string]string{"取引先": "partner_id", "発行日": "issue_date", "金額": "amount", "支払期日": "due_date"}
+	tests := []struct {
+		name    string
+		content string
+		opts    ImportOptions
+		want    []ImportRow
+		wantErr bool
+	}{
+		{
+			name:    "utf-8 with bom",
+			content: "\ufeffcompany_id,partner_id,issue_date,amount,due_date,status\n2,1,2024-10-01,10000,2024-10-31,paid\n,3,2024-10-02,500,2024-11-30,\n\n",
+			opts:    ImportOptions{CompanyID: "1"},
+			want: []ImportRow{
+				{Line: 2, Request: InvoiceRequest{CompanyID: "2", PartnerID: "1", IssueDate: "2024-10-01", Amount: 10000, DueDate: "2024-10-31", Status: "paid"}},
+				{Line: 3, Request: InvoiceRequest{CompanyID: "1", PartnerID: "3", IssueDate: "2024-10-02", Amount: 500, DueDate: "2024-11-30", Status: "unprocessed"}},
+			},
+		},
+		{
+			name:    "shift_jis detected with mapped headers",
+			content: shiftJIS,
+			opts:    ImportOptions{CompanyID: "1", Mapping: mapping},
+			want: []ImportRow{
+				{Line: 2, Request: InvoiceRequest{CompanyID: "1", PartnerID: "1", IssueDate: "2024-10-01", Amount: 10000, DueDate: "2024-10-31", Status: "unprocessed"}},
+			},
+		},
+		{
+			name:    "shift_jis specified",
+			content: shiftJIS,
+			opts:    ImportOptions{CompanyID: "1", Mapping: mapping, Encoding: "shift_jis"},
+			want: []ImportRow{
+				{Line: 2, Request: InvoiceRequest{CompanyID: "1", PartnerID: "1", IssueDate: "2024-10-01", Amount: 10000, DueDate: "2024-10-31", Status: "unprocessed"}},
+			},
+		},
+		{
+			name:    "invalid amount is reported in the row",
+			content: "partner_id,issue_date,amount,due_date\n1,2024-10-01,\"10,000\",2024-10-31\n",
+			opts:    ImportOptions{CompanyID: "1"},
+			want: []ImportRow{
+				{Line: 2, Request: InvoiceRequest{CompanyID: "1", PartnerID: "1", IssueDate: "2024-10-01", DueDate: "2024-10-31", Status: "unprocessed"}, Err: errInvalidAmount},
+			},
+		},
+		{name: "shift_jis read as utf-8", content: shiftJIS, opts: ImportOptions{Mapping: mapping, Encoding: "utf-8"}, wantErr: true},
+		{name: "unknown encoding", content: "partner_id\n", opts: ImportOptions{Encoding: "euc-jp"}, wantErr: true},
+		{name: "required column missing", content: "partner_id,issue_date,due_date\n1,2024-10-01,2024-10-31\n", wantErr: true},
+		{name: "mapped to unknown field", content: "partner_id\n", opts: ImportOptions{Mapping: map[string]string{"金額": "total"}}, wantErr: true},
+		{name: "columns mapped to the same field", content: "partner_id,取引先,issue_date,amount,due_date\n", opts: ImportOptions{Mapping: map[string]string{"取引先": "partner_id"}}, wantErr: true},
+		{name: "empty file", content: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadInvoiceCSV(strings.NewReader(tt.content), tt.opts)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidImport)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegisterService_Import(t *testing.T) {
+	rows := []ImportRow{
+		{Line: 2, Request: InvoiceRequest{CompanyID: "1", PartnerID: "1", IssueDate: "2024-10-01", Amount: 10000, DueDate: "2024-10-31", Status: "unprocessed"}},
+		{Line: 3, Request: InvoiceRequest{CompanyID: "1", PartnerID: "1", IssueDate: "2024/10/01", Amount: 10000, DueDate: "2024-10-31", Status: "unprocessed"}},
+		{Line: 4, Request: InvoiceRequest{CompanyID: "9", PartnerID: "1", IssueDate: "2024-10-01", Amount: 10000, DueDate: "2024-10-31", Status: "unprocessed"}},
+		{Line: 5, Err: errInvalidAmount},
+	}
+	companies := CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+		if companyID == "9" {
+			return nil, nil
+		}
+		return &CompanyRow{CompanyID: companyID}, nil
+	})
+	inserted := 0
+	s := &RegisterService{
+		BatchInserter: BatchInserterFunc(func(_ context.Context, rows []Row, _ BatchMode) ([]Row, []error, error) {
+			inserted += len(rows)
+			rows[0].InvoiceID = "10"
+			return rows, nil, nil
+		}),
+		RateSelector:    RateSelectorFunc(func(context.Context, string, time.Time) (*RateRow, error) { return nil, nil }),
+		CompanySelector: companies,
+		PartnerSelector: partnerOfCompany1,
+	}
+	failures := []ImportResult{
+		{Line: 3, Status: "failed", Message: "Failed to decode issue_date as YYYY-MM-DD"},
+		{Line: 4, Status: "failed", Message: "Company 9 doesn't exist"},
+		{Line: 5, Status: "failed", Message: "Failed to decode amount as integer"},
+	}
+
+	got, err := s.Import(context.Background(), rows, AllOrNothing, true)
+	require.NoError(t, err)
+	assert.Equal(t, append([]ImportResult{{Line: 2, Status: "valid"}}, failures...), got)
+	assert.Zero(t, inserted, "dry run mustn't create invoices")
+
+	got, err = s.Import(context.Background(), rows, AllOrNothing, false)
+	require.NoError(t, err)
+	assert.Equal(t, append([]ImportResult{{Line: 2, Status: "skipped"}}, failures...), got)
+	assert.Zero(t, inserted)
+
+	got, err = s.Import(context.Background(), rows, BestEffort, false)
+	require.NoError(t, err)
+	assert.Equal(t, "created", got[0].Status)
+	assert.Equal(t, "10", got[0].Invoice.InvoiceID)
+	assert.Equal(t, failures, got[1:])
+	assert.Equal(t, 1, inserted)
+}
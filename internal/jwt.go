@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidJWKS = errors.New("invalid jwks")
+
+// Claims are the JWT claims issued by the identity provider. company_id carries the tenant of the subject,
+// and role its role in the company, which defaults to viewer.
+type Claims struct {
+	CompanyID string `json:"company_id"`
+	Role      string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// JWTVerifier validates HS256 and RS256 bearer tokens.
+type JWTVerifier struct {
+	keys     map[string]any
+	issuer   string
+	audience string
+	// Clock tells the time exp, nbf and iat are checked against. Nil means the system clock.
+	Clock Clock
+}
+
+// NewSecretVerifier returns a JWTVerifier accepting HS256 tokens signed with the shared secret.
+func NewSecretVerifier(secret []byte, issuer, audience string) (*JWTVerifier, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("jwt secret mustn't be empty")
+	}
+	return newJWTVerifier(map[string]any{"": secret}, issuer, audience)
+}
+
+// NewJWKSVerifier returns a JWTVerifier accepting tokens signed with the keys of the JSON Web Key Set.
+// RSA keys verify RS256 tokens and oct keys verify HS256 tokens.
+func NewJWKSVerifier(jwks []byte, issuer, audience string) (*JWTVerifier, error) {
+	keys, err := parseJWKS(jwks)
+	if err != nil {
+		return nil, err
+	}
+	return newJWTVerifier(keys, issuer, audience)
+}
+
+func newJWTVerifier(keys map[string]any, issuer, audience string) (*JWTVerifier, error) {
+	if issuer == "" || audience == "" {
+		return nil, errors.New("jwt issuer and audience mustn't be empty")
+	}
+	return &JWTVerifier{keys: keys, issuer: issuer, audience: audience}, nil
+}
+
+// Verify returns the user the token was issued for, or ErrUnauthorized when the signature, exp, nbf, iat, iss or aud
+// is invalid, the token has no company_id claim or the role claim is unknown.
+func (v *JWTVerifier) Verify(_ context.Context, token string) (*domain.User, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, v.key,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithTimeFunc(func() time.Time { return currentTime(v.Clock) }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnauthorized, err)
+	}
+	if claims.CompanyID == "" {
+		return nil, fmt.Errorf("%w: company_id claim is missing", ErrUnauthorized)
+	}
+	role := domain.Viewer
+	if claims.Role != "" {
+		role = domain.Role(claims.Role)
+	}
+	if !role.Valid() {
+		return nil, fmt.Errorf("%w: unknown role %v", ErrUnauthorized, claims.Role)
+	}
+	return &domain.User{UserID: claims.Subject, CompanyID: claims.CompanyID, Username: claims.Subject, Role: role}, nil
+}
+
+// key looks the verification key up by the kid header. A token without kid is accepted only when there's a single key,
+// and a key without kid, like a shared secret, verifies tokens whatever kid they carry.
+// The signing method checks the key type, so an RSA key never verifies an HS256 token and vice versa.
+func (v *JWTVerifier) key(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, nil
+		}
+	}
+	if key, ok := v.keys[""]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+func parseJWKS(data []byte) (map[string]any, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidJWKS, err)
+	}
+	keys := make(map[string]any, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.key()
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %w", ErrInvalidJWKS, k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no signing keys", ErrInvalidJWKS)
+	}
+	return keys, nil
+}
+
+func (k *jwk) key() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 {
+			return nil, errors.New("malformed RSA key")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return nil, err
+		}
+		if len(secret) == 0 {
+			return nil, errors.New("empty oct key")
+		}
+		return secret, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testAudience = "super-invoicer"
+)
+
+func validClaims() Claims {
+	now := time.Now()
+	return Claims{
+		CompanyID: "1",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   "foo",
+			Issuer:    testIssuer,
+			Audience:  jwt.ClaimStrings{testAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+			NotBefore: jwt.NewNumericDate(now.Add(-time.Minute)),
+		},
+	}
+}
+
+func mintToken(t *testing.T, method jwt.SigningMethod, kid string, key any, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	require.NoError(t, err)
+	return s
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	secret := []byte("shared-secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	jwks := fmt.Sprintf(`{"keys":[{"kty":"RSA","kid":"rsa-1","use":"sig","alg":"RS256","n":%q,"e":%q},{"kty":"oct","kid":"hmac-1","k":%q}]}`,
+		base64.RawURLEncoding.EncodeToString(rsaKey.N.Bytes()),
+		base64.RawURLEncoding.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes()),
+		base64.RawURLEncoding.EncodeToString(secret))
+
+	secretVerifier, err := NewSecretVerifier(secret, testIssuer, testAudience)
+	require.NoError(t, err)
+	jwksVerifier, err := NewJWKSVerifier([]byte(jwks), testIssuer, testAudience)
+	require.NoError(t, err)
+
+	with := func(modify func(*Claims)) Claims {
+		c := validClaims()
+		modify(&c)
+		return c
+	}
+	tests := []struct {
+		name     string
+		verifier *JWTVerifier
+		token    string
+		want     *domain.User
+	}{
+		{
+			name:     "HS256 with shared secret",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, validClaims()),
+			want:     &domain.User{UserID: "foo", CompanyID: "1", Username: "foo", Role: domain.Viewer},
+		},
+		{
+			name:     "role claim",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) { c.Role = "approver" })),
+			want:     &domain.User{UserID: "foo", CompanyID: "1", Username: "foo", Role: domain.Approver},
+		},
+		{
+			name:     "unknown role claim",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) { c.Role = "owner" })),
+		},
+		{
+			name:     "RS256 with JWKS",
+			verifier: jwksVerifier,
+			token:    mintToken(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims()),
+			want:     &domain.User{UserID: "foo", CompanyID: "1", Username: "foo", Role: domain.Viewer},
+		},
+		{
+			name:     "HS256 with JWKS",
+			verifier: jwksVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "hmac-1", secret, validClaims()),
+			want:     &domain.User{UserID: "foo", CompanyID: "1", Username: "foo", Role: domain.Viewer},
+		},
+		{
+			name:     "signed with another key",
+			verifier: jwksVerifier,
+			token:    mintToken(t, jwt.SigningMethodRS256, "rsa-1", otherKey, validClaims()),
+		},
+		{
+			name:     "unknown kid",
+			verifier: jwksVerifier,
+			token:    mintToken(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, validClaims()),
+		},
+		{
+			name:     "HS256 signed with the RSA public key",
+			verifier: jwksVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "rsa-1", rsaKey.N.Bytes(), validClaims()),
+		},
+		{
+			name:     "none algorithm",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, validClaims()),
+		},
+		{
+			name:     "expired",
+			verifier: secretVerifier,
+			token: mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) {
+				c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+			})),
+		},
+		{
+			name:     "without exp",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) { c.ExpiresAt = nil })),
+		},
+		{
+			name:     "not valid yet",
+			verifier: secretVerifier,
+			token: mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) {
+				c.NotBefore = jwt.NewNumericDate(time.Now().Add(time.Hour))
+			})),
+		},
+		{
+			name:     "wrong issuer",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) { c.Issuer = "https://evil.example.com" })),
+		},
+		{
+			name:     "wrong audience",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) { c.Audience = jwt.ClaimStrings{"another-app"} })),
+		},
+		{
+			name:     "without company_id",
+			verifier: secretVerifier,
+			token:    mintToken(t, jwt.SigningMethodHS256, "", secret, with(func(c *Claims) { c.CompanyID = "" })),
+		},
+		{
+			name:     "malformed",
+			verifier: secretVerifier,
+			token:    "INVALID",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.verifier.Verify(context.Background(), tt.token)
+			if tt.want == nil {
+				assert.ErrorIs(t, err, ErrUnauthorized)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestJWTVerifier_Clock(t *testing.T) {
+	secret := []byte("shared-secret")
+	verifier, err := NewSecretVerifier(secret, testIssuer, testAudience)
+	require.NoError(t, err)
+	issuedAt := time.Date(2024, 10, 1, 9, 0, 0, 0, time.UTC)
+	claims := validClaims()
+	claims.IssuedAt = jwt.NewNumericDate(issuedAt)
+	claims.NotBefore = nil
+	claims.ExpiresAt = jwt.NewNumericDate(issuedAt.Add(time.Hour))
+	token := mintToken(t, jwt.SigningMethodHS256, "", secret, claims)
+	claims.IssuedAt = nil
+	claims.NotBefore = jwt.NewNumericDate(issuedAt)
+	notBeforeToken := mintToken(t, jwt.SigningMethodHS256, "", secret, claims)
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		wantErr bool
+	}{
+		{name: "valid at the time of the clock", token: token, now: issuedAt.Add(30 * time.Minute)},
+		{name: "expired", token: token, now: issuedAt.Add(time.Hour + time.Second), wantErr: true},
+		{name: "issued in the future", token: token, now: issuedAt.Add(-time.Second), wantErr: true},
+		{name: "not valid yet", token: notBeforeToken, now: issuedAt.Add(-time.Second), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier.Clock = fixedClock(tt.now)
+			_, err := verifier.Verify(context.Background(), tt.token)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrUnauthorized)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestNewJWKSVerifier(t *testing.T) {
+	tests := []struct {
+		name string
+		jwks string
+	}{
+		{name: "not json", jwks: "INVALID"},
+		{name: "no keys", jwks: `{"keys":[]}`},
+		{name: "encryption keys only", jwks: `{"keys":[{"kty":"oct","use":"enc","k":"c2VjcmV0"}]}`},
+		{name: "unsupported key type", jwks: `{"keys":[{"kty":"EC","kid":"ec-1"}]}`},
+		{name: "malformed RSA key", jwks: `{"keys":[{"kty":"RSA","kid":"rsa-1","n":"","e":"AQAB"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewJWKSVerifier([]byte(tt.jwks), testIssuer, testAudience)
+			assert.ErrorIs(t, err, ErrInvalidJWKS)
+		})
+	}
+
+	_, err := NewJWKSVerifier([]byte(`{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`), "", testAudience)
+	assert.Error(t, err)
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+// insertLines inserts the lines of the rows, which have their InvoiceID, with multi-row inserts in tx.
+// Lines are numbered from 1 in the order of the invoice.
+func insertLines(ctx context.Context, tx *sql.Tx, rows []Row) error {
+	placeholders := make([]string, 0, batchInsertRows)
+	args := make([]any, 0, batchInsertRows*6)
+	flush := func() error {
+		if len(placeholders) == 0 {
+			return nil
+		}
+		_, err := tx.ExecContext(ctx, "INSERT INTO invoice_lines (invoice_id, line_no, description, quantity, unit_price, tax_category) VALUES "+strings.Join(placeholders, ", ")+";", args...)
+		placeholders, args = placeholders[:0], args[:0]
+		return err
+	}
+	for _, row := range rows {
+		for i, line := range row.Lines {
+			placeholders = append(placeholders, "(?, ?, ?, ?, ?, ?)")
+			args = append(args, row.InvoiceID, i+1, line.Description, line.Quantity, line.UnitPrice, line.TaxCategory)
+			if len(placeholders) == batchInsertRows {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return flush()
+}
+
+// selectLines returns the lines of the invoices by their IDs. Invoices without lines aren't in the result.
+func (s *MySQL) selectLines(ctx context.Context, invoiceIDs []string) (map[string][]domain.InvoiceLine, error) {
+	lines := make(map[string][]domain.InvoiceLine)
+	if len(invoiceIDs) == 0 {
+		return lines, nil
+	}
+	args := make([]any, 0, len(invoiceIDs))
+	for _, id := range invoiceIDs {
+		args = append(args, id)
+	}
+	rows, err := s.DB.QueryContext(ctx, "SELECT invoice_id, description, quantity, unit_price, tax_category FROM invoice_lines WHERE invoice_id IN (?"+strings.Repeat(", ?", len(invoiceIDs)-1)+") ORDER BY invoice_id, line_no;", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var invoiceID string
+		var line domain.InvoiceLine
+		if err := rows.Scan(&invoiceID, &line.Description, &line.Quantity, &line.UnitPrice, &line.TaxCategory); err != nil {
+			return nil, err
+		}
+		lines[invoiceID] = append(lines[invoiceID], line)
+	}
+	return lines, rows.Err()
+}
@@ -0,0 +1,292 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	//go:embed migrations/*.sql
+	migrationFiles embed.FS
+	// seedSQL is the sample data for development. It inserts rows with fixed IDs and ignores existing ones,
+	// so seeding twice doesn't duplicate them.
+	//
+	//go:embed seed/seed.sql
+	seedSQL string
+)
+
+var (
+	ErrInvalidMigration = errors.New("invalid migration")
+	ErrMigrationLocked  = errors.New("another migration is running")
+	ErrPendingMigration = errors.New("schema isn't up to date")
+)
+
+// migrationLock is the name of the advisory lock serializing the instances migrating the same database.
+const migrationLock = "super_invoicer.schema_migrations"
+
+// Migration is a numbered schema change. A migration named 0001_create_invoices is read from
+// 0001_create_invoices.up.sql and 0001_create_invoices.down.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tells whether a migration has been applied. AppliedAt is nil when it is pending.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// LoadMigrations reads the migrations in the root of fsys in the order of their versions.
+// Every version must have both of the up and down files.
+func LoadMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("%w: unexpected file %v", ErrInvalidMigration, entry.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		b, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != m[2] {
+			return nil, fmt.Errorf("%w: version %d is used by both %v and %v", ErrInvalidMigration, version, migration.Name, m[2])
+		}
+		if m[3] == "up" {
+			migration.Up = string(b)
+		} else {
+			migration.Down = string(b)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: %04d_%v must have both of up and down", ErrInvalidMigration, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// EmbeddedMigrations returns the schema migrations shipped with the binary. They're the only definition of
+// the schema: change it by adding a migration, not by SQL run elsewhere such as an init script of the container.
+func EmbeddedMigrations() ([]Migration, error) {
+	sub, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return LoadMigrations(sub)
+}
+
+// splitStatements splits a migration into the statements executed one by one, as the driver doesn't run
+// multiple statements at once. A statement ends with a semicolon at the end of a line.
+func splitStatements(script string) []string {
+	var statements []string
+	var b strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		b.WriteString(line)
+		b.WriteString("\n")
+		if strings.HasSuffix(strings.TrimSpace(line), ";") {
+			statements = append(statements, strings.TrimSpace(b.String()))
+			b.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(b.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
+
+// Migrator applies its migrations to the database and records the applied versions in schema_migrations.
+// Up, Down and Seed hold an advisory lock while they run, so concurrent instances wait for each other instead
+// of applying the same migration twice. MySQL commits DDL implicitly, so a migration failing halfway isn't
+// rolled back and has to be fixed by hand.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations []Migration
+	// LockTimeout is how long to wait for the lock held by another instance.
+	LockTimeout time.Duration
+	// Clock tells when the migrations are applied. Nil means the system clock.
+	Clock Clock
+}
+
+// session is a *sql.Conn or a *sql.DB. The advisory lock belongs to a connection, so everything done under
+// the lock is run on the connection holding it.
+type session interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}
+
+// withLock runs f on a connection holding the advisory lock.
+func (m *Migrator) withLock(ctx context.Context, f func(*sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migration error: %w", err)
+	}
+	defer conn.Close()
+	// GET_LOCK returns 1 when the lock is acquired, 0 on timeout and NULL on errors.
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?);", migrationLock, int(m.LockTimeout.Seconds())).Scan(&acquired); err != nil {
+		return fmt.Errorf("migration error: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("%w: couldn't acquire the lock in %v", ErrMigrationLocked, m.LockTimeout)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "DO RELEASE_LOCK(?);", migrationLock)
+	return f(conn)
+}
+
+func createTable(ctx context.Context, s session) error {
+	_, err := s.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL);")
+	return err
+}
+
+func applied(ctx context.Context, s session) (map[int]time.Time, error) {
+	rows, err := s.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations ORDER BY version;")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version], err = time.ParseInLocation(time.DateTime, appliedAt, time.UTC)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) status(ctx context.Context, s session) ([]MigrationStatus, error) {
+	if err := createTable(ctx, s); err != nil {
+		return nil, fmt.Errorf("migration error: %w", err)
+	}
+	applied, err := applied(ctx, s)
+	if err != nil {
+		return nil, fmt.Errorf("migration error: %w", err)
+	}
+	statuses := make([]MigrationStatus, 0, len(m.Migrations))
+	for _, migration := range m.Migrations {
+		status := MigrationStatus{Migration: migration}
+		if appliedAt, ok := applied[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Status doesn't take the lock, so it may show a migration being applied as pending.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	return m.status(ctx, m.DB)
+}
+
+func run(ctx context.Context, s session, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := s.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Up applies the pending migrations in order and returns them.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.AppliedAt != nil {
+				continue
+			}
+			if err := run(ctx, conn, status.Up); err != nil {
+				return fmt.Errorf("migration %04d_%v error: %w", status.Version, status.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);", status.Version, status.Name, currentTime(m.Clock).UTC().Format(time.DateTime)); err != nil {
+				return fmt.Errorf("migration %04d_%v error: %w", status.Version, status.Name, err)
+			}
+			done = append(done, status.Migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down reverts the last steps applied migrations in reverse order and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	done := make([]Migration, 0)
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+			status := statuses[i]
+			if status.AppliedAt == nil {
+				continue
+			}
+			if err := run(ctx, conn, status.Down); err != nil {
+				return fmt.Errorf("migration %04d_%v error: %w", status.Version, status.Name, err)
+			}
+			if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?;", status.Version); err != nil {
+				return fmt.Errorf("migration %04d_%v error: %w", status.Version, status.Name, err)
+			}
+			done = append(done, status.Migration)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Seed inserts the sample data. ErrPendingMigration is returned unless every migration has been applied,
+// as the sample data is written for the latest schema.
+func (m *Migrator) Seed(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		statuses, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			if status.AppliedAt == nil {
+				return fmt.Errorf("%w: %04d_%v is pending", ErrPendingMigration, status.Version, status.Name)
+			}
+		}
+		if err := run(ctx, conn, seedSQL); err != nil {
+			return fmt.Errorf("seed error: %w", err)
+		}
+		return nil
+	})
+}
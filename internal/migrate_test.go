@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	migrations, err := EmbeddedMigrations()
+	require.NoError(t, err)
+	for i, migration := range migrations {
+		assert.Equal(t, i+1, migration.Version, "versions must be sequential")
+	}
+}
+
+func TestEmbeddedMigrations_OnlySchema(t *testing.T) {
+	// data/init.sql was the init script of the MySQL container that defined the schema before the migrations.
+	b, err := os.ReadFile(filepath.Join("..", "data", "init.sql"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return
+	}
+	require.NoError(t, err)
+	assert.NotRegexp(t, `(?i)\b(CREATE|ALTER|DROP)\s+TABLE\b`, string(b), "data/init.sql must not define the schema defined by internal/migrations")
+}
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		fsys    fstest.MapFS
+		want    []Migration
+		wantErr bool
+	}{
+		{
+			name: "ordered by version",
+			fsys: fstest.MapFS{
+				"0002_add_b.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+				"0002_add_b.down.sql": {Data: []byte("DROP TABLE b;")},
+				"0001_add_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+				"0001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+				"0010_add_c.up.sql":   {Data: []byte("CREATE TABLE c (id INT);")},
+				"0010_add_c.down.sql": {Data: []byte("DROP TABLE c;")},
+			},
+			want: []Migration{
+				{Version: 1, Name: "add_a", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+				{Version: 2, Name: "add_b", Up: "CREATE TABLE b (id INT);", Down: "DROP TABLE b;"},
+				{Version: 10, Name: "add_c", Up: "CREATE TABLE c (id INT);", Down: "DROP TABLE c;"},
+			},
+		},
+		{
+			name:    "without down",
+			fsys:    fstest.MapFS{"0001_add_a.up.sql": {Data: []byte("CREATE TABLE a (id INT);")}},
+			wantErr: true,
+		},
+		{
+			name: "duplicated version",
+			fsys: fstest.MapFS{
+				"0001_add_a.up.sql":   {Data: []byte("CREATE TABLE a (id INT);")},
+				"0001_add_a.down.sql": {Data: []byte("DROP TABLE a;")},
+				"0001_add_b.up.sql":   {Data: []byte("CREATE TABLE b (id INT);")},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unexpected file",
+			fsys:    fstest.MapFS{"README.md": {Data: []byte("")}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMigrations(tt.fsys)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidMigration)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- comment
+CREATE TABLE a (
+  id INT, -- trailing; comment
+  name VARCHAR(8) DEFAULT ";"
+);
+
+DROP TABLE b;
+`
+	assert.Equal(t, []string{
+		"-- comment\nCREATE TABLE a (\n  id INT, -- trailing; comment\n  name VARCHAR(8) DEFAULT \";\"\n);",
+		"DROP TABLE b;",
+	}, splitStatements(script))
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "add_a", Up: "CREATE TABLE a (id INT);", Down: "DROP TABLE a;"},
+	{Version: 2, Name: "add_b", Up: "CREATE TABLE b (id INT);\nCREATE INDEX b_id ON b (id);", Down: "DROP TABLE b;"},
+}
+
+func expectStatus(mock sqlmock.Sqlmock, applied ...int) {
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE IF NOT EXISTS schema_migrations (version INT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME NOT NULL);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"version", "applied_at"})
+	for _, version := range applied {
+		rows.AddRow(version, "2024-10-01 00:00:00")
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version, applied_at FROM schema_migrations ORDER BY version;")).WillReturnRows(rows)
+}
+
+func expectLock(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?);")).WithArgs("super_invoicer.schema_migrations", 10).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(1))
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("DO RELEASE_LOCK(?);")).WithArgs("super_invoicer.schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expectStatus(mock, 1)
+
+	m := &Migrator{DB: db, Migrations: testMigrations}
+	got, err := m.Status(context.Background())
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "2024-10-01 00:00:00", got[0].AppliedAt.Format("2006-01-02 15:04:05"))
+	assert.Nil(t, got[1].AppliedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Up(t *testing.T) {
+	tests := []struct {
+		name     string
+		applied  []int
+		expect   func(sqlmock.Sqlmock)
+		wantDone []int
+		wantErr  bool
+	}{
+		{
+			name:    "applies pending migrations",
+			applied: []int{1},
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX b_id ON b (id);")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);")).WithArgs(2, "add_b", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+			},
+			wantDone: []int{2},
+		},
+		{
+			name:     "up to date",
+			applied:  []int{1, 2},
+			expect:   func(sqlmock.Sqlmock) {},
+			wantDone: []int{},
+		},
+		{
+			name: "stops at the failing migration",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE a (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);")).WithArgs(1, "add_a", sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT);")).WillReturnError(errors.New("this is test"))
+			},
+			wantDone: []int{1},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+			expectLock(mock)
+			expectStatus(mock, tt.applied...)
+			tt.expect(mock)
+			expectUnlock(mock)
+
+			m := &Migrator{DB: db, Migrations: testMigrations, LockTimeout: 10 * time.Second}
+			done, err := m.Up(context.Background())
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			versions := make([]int, 0)
+			for _, migration := range done {
+				versions = append(versions, migration.Version)
+			}
+			assert.Equal(t, tt.wantDone, versions)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMigrator_Up_Clock(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expectLock(mock)
+	expectStatus(mock, 1)
+	mock.ExpectExec(regexp.QuoteMeta("CREATE TABLE b (id INT);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("CREATE INDEX b_id ON b (id);")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?);")).WithArgs(2, "add_b", "2024-10-01 09:00:00").WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlock(mock)
+
+	m := &Migrator{DB: db, Migrations: testMigrations, LockTimeout: 10 * time.Second, Clock: testClock}
+	_, err = m.Up(context.Background())
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	expectLock(mock)
+	expectStatus(mock, 1, 2)
+	mock.ExpectExec(regexp.QuoteMeta("DROP TABLE b;")).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version = ?;")).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUnlock(mock)
+
+	m := &Migrator{DB: db, Migrations: testMigrations, LockTimeout: 10 * time.Second}
+	done, err := m.Down(context.Background(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{testMigrations[1]}, done)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Locked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT GET_LOCK(?, ?);")).WithArgs("super_invoicer.schema_migrations", 10).WillReturnRows(sqlmock.NewRows([]string{"acquired"}).AddRow(0))
+
+	m := &Migrator{DB: db, Migrations: testMigrations, LockTimeout: 10 * time.Second}
+	_, err = m.Up(context.Background())
+	assert.ErrorIs(t, err, ErrMigrationLocked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Seed(t *testing.T) {
+	t.Run("seeds the latest schema", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		expectLock(mock)
+		expectStatus(mock, 1, 2)
+		for range splitStatements(seedSQL) {
+			mock.ExpectExec("INSERT IGNORE INTO").WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		expectUnlock(mock)
+
+		m := &Migrator{DB: db, Migrations: testMigrations, LockTimeout: 10 * time.Second}
+		assert.NoError(t, m.Seed(context.Background()))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("pending migration", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		require.NoError(t, err)
+		defer db.Close()
+		expectLock(mock)
+		expectStatus(mock, 1)
+		expectUnlock(mock)
+
+		m := &Migrator{DB: db, Migrations: testMigrations, LockTimeout: 10 * time.Second}
+		assert.ErrorIs(t, m.Seed(context.Background()), ErrPendingMigration)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,4 @@
+DROP TABLE invoice;
+DROP TABLE partner_bank_accounts;
+DROP TABLE business_partners;
+DROP TABLE companies;
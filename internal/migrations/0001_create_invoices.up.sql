@@ -0,0 +1,54 @@
+-- Customer companies (取引先) that invoices are issued for.
+CREATE TABLE companies (
+  company_id     INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  name           VARCHAR(255) NOT NULL,
+  representative VARCHAR(255) NOT NULL DEFAULT "",
+  phone          VARCHAR(20) NOT NULL DEFAULT "",
+  postal_code    VARCHAR(8) NOT NULL DEFAULT "",
+  address        VARCHAR(255) NOT NULL DEFAULT ""
+);
+
+-- Business partners (支払先) each company pays vendor invoices to, and their bank accounts.
+CREATE TABLE business_partners (
+  partner_id  INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  company_id  INT NOT NULL,
+  name        VARCHAR(255) NOT NULL,
+  phone       VARCHAR(20) NOT NULL DEFAULT "",
+  postal_code VARCHAR(8) NOT NULL DEFAULT "",
+  address     VARCHAR(255) NOT NULL DEFAULT "",
+  CONSTRAINT `business_partners_company_fk` FOREIGN KEY (`company_id`) REFERENCES `companies` (`company_id`) ON DELETE CASCADE
+);
+
+CREATE TABLE partner_bank_accounts (
+  bank_account_id  INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  partner_id       INT NOT NULL,
+  company_id       INT NOT NULL,
+  bank_name        VARCHAR(255) NOT NULL,
+  branch_name      VARCHAR(255) NOT NULL,
+  account_type     ENUM("ordinary", "checking", "savings") NOT NULL,
+  account_number   CHAR(7) NOT NULL,
+  holder_name_kana VARCHAR(255) NOT NULL,
+  CONSTRAINT `partner_bank_accounts_partner_fk` FOREIGN KEY (`partner_id`) REFERENCES `business_partners` (`partner_id`) ON DELETE CASCADE,
+  CONSTRAINT `partner_bank_accounts_company_fk` FOREIGN KEY (`company_id`) REFERENCES `companies` (`company_id`) ON DELETE CASCADE
+);
+
+CREATE TABLE invoice (
+  invoice_id    INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  company_id    INT NOT NULL,
+  partner_id    INT NOT NULL,
+  issue_date    DATE NOT NULL,
+  amount        INT NOT NULL,
+  fee           INT NOT NULL,
+  fee_rate      DECIMAL(5, 4) NOT NULL,
+  tax           INT NOT NULL,
+  tax_rate      DECIMAL(5, 4) NOT NULL,
+  total         INT NOT NULL,
+  due_date      DATE NOT NULL,
+  status        ENUM("unprocessed", "processing", "paid", "error") NOT NULL,
+  CONSTRAINT `total_check` CHECK ((`amount` + `fee` + `tax` = `total`)),
+  -- fee and tax are rounded to yen with the company's rounding mode, so they may differ from the exact product by less than 1.
+  CONSTRAINT `fee_check` CHECK ((ABS(`amount` * `fee_rate` - `fee`) < 1)),
+  CONSTRAINT `tax_check` CHECK ((ABS(`fee` * `tax_rate` - `tax`) < 1)),
+  CONSTRAINT `invoice_company_fk` FOREIGN KEY (`company_id`) REFERENCES `companies` (`company_id`),
+  CONSTRAINT `invoice_partner_fk` FOREIGN KEY (`partner_id`) REFERENCES `business_partners` (`partner_id`)
+);
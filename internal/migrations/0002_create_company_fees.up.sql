@@ -0,0 +1,14 @@
+-- Negotiated rates per company. The contract effective on an invoice's issue_date is applied,
+-- and tax_rate falls back to the default rate when NULL. Invoices keep their own copy of the rates.
+CREATE TABLE company_fees (
+  company_fee_id INT NOT NULL PRIMARY KEY AUTO_INCREMENT,
+  company_id     INT NOT NULL,
+  fee_rate       DECIMAL(5, 4) NOT NULL,
+  tax_rate       DECIMAL(5, 4),
+  rounding_mode  ENUM("floor", "half_up", "ceil") NOT NULL DEFAULT "floor",
+  valid_from     DATE NOT NULL,
+  valid_to       DATE,
+  UNIQUE KEY `company_valid_from` (`company_id`, `valid_from`),
+  CONSTRAINT `valid_range_check` CHECK ((`valid_to` IS NULL OR `valid_from` <= `valid_to`)),
+  CONSTRAINT `company_fees_company_fk` FOREIGN KEY (`company_id`) REFERENCES `companies` (`company_id`) ON DELETE CASCADE
+);
@@ -0,0 +1,8 @@
+CREATE TABLE users (
+    user_id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL,
+    username VARCHAR(255) NOT NULL UNIQUE,
+    password_hash VARBINARY(60) NOT NULL,
+    role ENUM("viewer", "accountant", "approver", "admin") NOT NULL DEFAULT "viewer",
+    CONSTRAINT users_company_fk FOREIGN KEY (company_id) REFERENCES companies(company_id) ON DELETE CASCADE
+);
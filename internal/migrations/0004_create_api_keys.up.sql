@@ -0,0 +1,12 @@
+CREATE TABLE api_keys (
+    key_id INT AUTO_INCREMENT PRIMARY KEY,
+    company_id INT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash BINARY(32) NOT NULL UNIQUE,
+    scopes SET("invoices:read", "invoices:write") NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME NULL,
+    created_at DATETIME NOT NULL,
+    CONSTRAINT api_keys_company_fk FOREIGN KEY (company_id) REFERENCES companies(company_id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_keys (
+    company_id VARCHAR(64) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+    fingerprint BINARY(32) NOT NULL,
+    status ENUM("processing", "completed") NOT NULL,
+    response_code INT NULL,
+    response_body MEDIUMBLOB NULL,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (company_id, idempotency_key),
+    INDEX idempotency_keys_expires_at (expires_at)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE invoice DROP COLUMN tax_amounts, DROP COLUMN registration_number;
+ALTER TABLE companies DROP COLUMN qualified_issuer, DROP COLUMN registration_number;
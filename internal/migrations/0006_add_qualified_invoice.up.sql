@@ -0,0 +1,11 @@
+-- Registration numbers of the qualified invoice system (適格請求書等保存方式).
+ALTER TABLE companies
+  ADD COLUMN registration_number CHAR(14) NOT NULL DEFAULT "",
+  ADD COLUMN qualified_issuer    BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- tax_amounts is the JSON array of the tax per tax rate. The existing invoices have a single rate.
+ALTER TABLE invoice
+  ADD COLUMN registration_number CHAR(14) NOT NULL DEFAULT "",
+  ADD COLUMN tax_amounts         JSON NULL;
+UPDATE invoice SET tax_amounts = JSON_ARRAY(JSON_OBJECT("rate", tax_rate, "taxable", fee, "tax", tax));
+ALTER TABLE invoice MODIFY tax_amounts JSON NOT NULL;
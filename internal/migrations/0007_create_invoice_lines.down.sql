@@ -0,0 +1,12 @@
+-- Dropping invoice_lines would lose the items of the itemized invoices, and their tax including the tax of
+-- the lines can't satisfy tax_check. Fail before changing anything until they are deleted.
+DROP TEMPORARY TABLE IF EXISTS rollback_guard;
+CREATE TEMPORARY TABLE rollback_guard (
+  count INT NOT NULL,
+  CONSTRAINT `itemized_invoices_must_be_deleted_first` CHECK ((`count` = 0))
+);
+INSERT INTO rollback_guard SELECT (SELECT COUNT(*) FROM invoice_lines) + (SELECT COUNT(*) FROM invoice WHERE ABS(fee * tax_rate - tax) >= 1);
+DROP TEMPORARY TABLE rollback_guard;
+
+DROP TABLE invoice_lines;
+ALTER TABLE invoice ADD CONSTRAINT `tax_check` CHECK ((ABS(`fee` * `tax_rate` - `tax`) < 1));
@@ -0,0 +1,16 @@
+-- Items of itemized invoices. Invoices created with a single amount have no lines.
+CREATE TABLE invoice_lines (
+  invoice_id   INT NOT NULL,
+  line_no      INT NOT NULL,
+  description  VARCHAR(255) NOT NULL,
+  quantity     INT NOT NULL,
+  unit_price   INT NOT NULL,
+  tax_category ENUM("standard", "reduced") NOT NULL,
+  PRIMARY KEY (invoice_id, line_no),
+  CONSTRAINT `quantity_check` CHECK ((`quantity` > 0)),
+  CONSTRAINT `invoice_lines_invoice_fk` FOREIGN KEY (`invoice_id`) REFERENCES `invoice` (`invoice_id`) ON DELETE CASCADE
+);
+
+-- The tax of an itemized invoice includes the tax of its lines besides the tax of the fee, so it can't be checked
+-- against the fee. It equals the sum of tax_amounts instead.
+ALTER TABLE invoice DROP CHECK tax_check;
@@ -0,0 +1,14 @@
+-- The total of an invoice with withholding can't satisfy the total_check without it, and MySQL doesn't roll back
+-- DDL. Fail before changing anything until those invoices are deleted.
+DROP TEMPORARY TABLE IF EXISTS rollback_guard;
+CREATE TEMPORARY TABLE rollback_guard (
+  count INT NOT NULL,
+  CONSTRAINT `withheld_invoices_must_be_deleted_first` CHECK ((`count` = 0))
+);
+INSERT INTO rollback_guard SELECT COUNT(*) FROM invoice WHERE withholding_tax <> 0;
+DROP TEMPORARY TABLE rollback_guard;
+
+ALTER TABLE invoice DROP CHECK total_check;
+ALTER TABLE invoice ADD CONSTRAINT `total_check` CHECK ((`amount` + `fee` + `tax` = `total`));
+ALTER TABLE invoice DROP COLUMN withholding_tax;
+ALTER TABLE business_partners DROP COLUMN withholding;
@@ -0,0 +1,7 @@
+-- Income tax withheld from the payments to sole proprietors (源泉徴収).
+ALTER TABLE business_partners ADD COLUMN withholding ENUM("none", "professional") NOT NULL DEFAULT "none";
+
+-- The withheld tax is paid to the tax office instead of the partner, so it's deducted from the total.
+ALTER TABLE invoice ADD COLUMN withholding_tax INT NOT NULL DEFAULT 0;
+ALTER TABLE invoice DROP CHECK total_check;
+ALTER TABLE invoice ADD CONSTRAINT `total_check` CHECK ((`amount` + `fee` + `tax` - `withholding_tax` = `total`));
@@ -0,0 +1,2 @@
+-- How the due dates falling on bank holidays are moved to business days: next (翌営業日) or previous (前営業日).
+ALTER TABLE companies ADD COLUMN due_date_policy ENUM("next", "previous") NOT NULL DEFAULT "next";
@@ -0,0 +1,3 @@
+-- Drop invoices:approve, the third member of the set, from the keys before the set loses it.
+UPDATE api_keys SET scopes = scopes & ~4;
+ALTER TABLE api_keys MODIFY COLUMN scopes SET("invoices:read", "invoices:write") NOT NULL;
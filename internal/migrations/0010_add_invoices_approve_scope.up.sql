@@ -0,0 +1,2 @@
+-- invoices:approve changes the status of invoices, which invoices:write doesn't allow anymore.
+ALTER TABLE api_keys MODIFY COLUMN scopes SET("invoices:read", "invoices:write", "invoices:approve") NOT NULL;
@@ -0,0 +1,14 @@
+-- The registration numbers of the partners have nowhere to go on the companies, as they belong to other
+-- businesses. Fail before changing anything until they are cleared.
+DROP TEMPORARY TABLE IF EXISTS rollback_guard;
+CREATE TEMPORARY TABLE rollback_guard (
+  count INT NOT NULL,
+  CONSTRAINT `partner_registration_numbers_must_be_cleared_first` CHECK ((`count` = 0))
+);
+INSERT INTO rollback_guard SELECT COUNT(*) FROM business_partners WHERE registration_number <> "";
+DROP TEMPORARY TABLE rollback_guard;
+
+ALTER TABLE companies
+  ADD COLUMN registration_number CHAR(14) NOT NULL DEFAULT "",
+  ADD COLUMN qualified_issuer    BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE business_partners DROP COLUMN qualified_issuer, DROP COLUMN registration_number;
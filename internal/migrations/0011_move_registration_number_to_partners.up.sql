@@ -0,0 +1,6 @@
+-- Invoices are issued by the business partners, so the registration numbers of the qualified invoice system
+-- belong to them rather than to the companies paying the invoices.
+ALTER TABLE business_partners
+  ADD COLUMN registration_number CHAR(14) NOT NULL DEFAULT "",
+  ADD COLUMN qualified_issuer    BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE companies DROP COLUMN qualified_issuer, DROP COLUMN registration_number;
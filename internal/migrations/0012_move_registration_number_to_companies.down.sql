@@ -0,0 +1,13 @@
+-- Fail before changing anything while the companies have registration numbers, which would be lost.
+DROP TEMPORARY TABLE IF EXISTS rollback_guard;
+CREATE TEMPORARY TABLE rollback_guard (
+  count INT NOT NULL,
+  CONSTRAINT `company_registration_numbers_must_be_cleared_first` CHECK ((`count` = 0))
+);
+INSERT INTO rollback_guard SELECT COUNT(*) FROM companies WHERE registration_number <> "";
+DROP TEMPORARY TABLE rollback_guard;
+
+ALTER TABLE business_partners
+  ADD COLUMN registration_number CHAR(14) NOT NULL DEFAULT "",
+  ADD COLUMN qualified_issuer    BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE companies DROP COLUMN qualified_issuer, DROP COLUMN registration_number;
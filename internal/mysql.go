@@ -3,7 +3,10 @@ package internal
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Ryuheeeei/super-invoicer/internal/domain"
@@ -14,51 +17,210 @@ type MySQL struct {
 	DB *sql.DB
 }
 
-var _ Selector = (*MySQL)(nil)
+var (
+	_ Selector      = (*MySQL)(nil)
+	_ EachSelector  = (*MySQL)(nil)
+	_ IDSelector    = (*MySQL)(nil)
+	_ Inserter      = (*MySQL)(nil)
+	_ StatusUpdater = (*MySQL)(nil)
+	_ RateSelector  = (*MySQL)(nil)
+)
 
 type Rows struct {
 	Rows []Row
 }
 
 type Row struct {
-	InvoiceID string
-	CompanyID string
-	IssueDate time.Time
-	Amount    int
-	Fee       int
-	FeeRate   float32
-	Tax       int
-	TaxRate   float32
-	Total     int
-	DueDate   time.Time
-	Status    string
+	InvoiceID          string
+	CompanyID          string
+	PartnerID          string
+	IssueDate          time.Time
+	Amount             int
+	Fee                int
+	FeeRate            domain.Rate
+	Tax                int
+	TaxRate            domain.Rate
+	WithholdingTax     int
+	Total              int
+	DueDate            time.Time
+	Status             string
+	TaxAmounts         domain.TaxAmounts
+	RegistrationNumber string
+	Lines              []domain.InvoiceLine
+}
+
+// RateRow is the contracted rates of a company. TaxRate is nil when the contract doesn't override the default tax rate.
+type RateRow struct {
+	FeeRate  domain.Rate
+	TaxRate  *domain.Rate
+	Rounding domain.RoundingMode
+}
+
+func (r *Row) invoice() *domain.Invoice {
+	return &domain.Invoice{
+		InvoiceID:          r.InvoiceID,
+		CompanyID:          r.CompanyID,
+		PartnerID:          r.PartnerID,
+		IssueDate:          r.IssueDate,
+		Amount:             r.Amount,
+		Fee:                r.Fee,
+		FeeRate:            r.FeeRate,
+		Tax:                r.Tax,
+		TaxRate:            r.TaxRate,
+		WithholdingTax:     r.WithholdingTax,
+		Total:              r.Total,
+		DueDate:            r.DueDate,
+		Status:             domain.Status(r.Status),
+		TaxAmounts:         r.TaxAmounts,
+		RegistrationNumber: r.RegistrationNumber,
+		Lines:              r.Lines,
+	}
+}
+
+const invoiceColumns = "invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number"
+
+// selectQuery builds the SELECT statement of q. Only whitelisted columns are interpolated; values are always bound.
+func selectQuery(q InvoiceQuery) (string, []any) {
+	var b strings.Builder
+	b.WriteString("SELECT " + invoiceColumns + " FROM invoice WHERE company_id = ?")
+	args := []any{q.CompanyID}
+	if len(q.Statuses) > 0 {
+		b.WriteString(" AND status IN (?" + strings.Repeat(", ?", len(q.Statuses)-1) + ")")
+		for _, status := range q.Statuses {
+			args = append(args, string(status))
+		}
+	}
+	if q.PartnerID != "" {
+		b.WriteString(" AND partner_id = ?")
+		args = append(args, q.PartnerID)
+	}
+	dates := []struct {
+		cond  string
+		value time.Time
+	}{
+		{" AND issue_date >= ?", q.IssueDateFrom},
+		{" AND issue_date <= ?", q.IssueDateTo},
+		{" AND due_date >= ?", q.DueDateFrom},
+		{" AND due_date <= ?", q.DueDateTo},
+	}
+	for _, d := range dates {
+		if !d.value.IsZero() {
+			b.WriteString(d.cond)
+			args = append(args, d.value.Format(time.DateOnly))
+		}
+	}
+	amounts := []struct {
+		cond  string
+		value *int
+	}{
+		{" AND amount >= ?", q.AmountMin},
+		{" AND amount <= ?", q.AmountMax},
+		{" AND total >= ?", q.TotalMin},
+		{" AND total <= ?", q.TotalMax},
+	}
+	for _, a := range amounts {
+		if a.value != nil {
+			b.WriteString(a.cond)
+			args = append(args, *a.value)
+		}
+	}
+	column := sortFields[q.Sort.Field]
+	op, order := ">", "ASC"
+	if q.Sort.Desc {
+		op, order = "<", "DESC"
+	}
+	if q.After != nil {
+		b.WriteString(fmt.Sprintf(" AND (%[1]s %[2]s ? OR (%[1]s = ? AND invoice_id %[2]s ?))", column, op))
+		args = append(args, q.After.Value, q.After.Value, q.After.InvoiceID)
+	}
+	b.WriteString(fmt.Sprintf(" ORDER BY %[1]s %[2]s, invoice_id %[2]s", column, order))
+	if q.Limit > 0 {
+		b.WriteString(" LIMIT ?")
+		args = append(args, q.Limit)
+	}
+	b.WriteString(";")
+	return b.String(), args
 }
 
-func (s *MySQL) Select(ctx context.Context, companyID string, dueDate time.Time) (*Rows, error) {
-	var results []Row
-	rows, err := s.DB.QueryContext(ctx, "SELECT invoice_id, company_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status FROM invoice WHERE company_id = ? AND due_date BETWEEN ? AND ? AND status != 'paid';", companyID, time.Now().Format(time.DateOnly), dueDate.Format(time.DateOnly))
+// Select returns at most q.Limit invoices matching q in its sort order with their lines.
+func (s *MySQL) Select(ctx context.Context, q InvoiceQuery) (*Rows, error) {
+	results := make([]Row, 0)
+	invoiceIDs := make([]string, 0)
+	err := s.SelectEach(ctx, q, func(row *Row) error {
+		results = append(results, *row)
+		invoiceIDs = append(invoiceIDs, row.InvoiceID)
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
+	lines, err := s.selectLines(ctx, invoiceIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Lines = lines[results[i].InvoiceID]
+	}
+	return &Rows{Rows: results}, nil
+}
+
+// SelectEach calls f with the invoices matching q in its sort order as they are read from the database, so that
+// exporting many invoices doesn't hold them in memory. Every invoice is read when q.Limit is 0.
+// The lines of the invoices aren't read. It stops at the first error returned by f.
+func (s *MySQL) SelectEach(ctx context.Context, q InvoiceQuery, f func(*Row) error) error {
+	query, args := selectQuery(q)
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
 	defer rows.Close()
 	for rows.Next() {
-		var row Row
-		var issueDate string
-		var dueDate string
-		if err := rows.Scan(&row.InvoiceID, &row.CompanyID, &issueDate, &row.Amount, &row.Fee, &row.FeeRate, &row.Tax, &row.TaxRate, &row.Total, &dueDate, &row.Status); err != nil {
-			break
-		}
-		row.IssueDate, err = time.ParseInLocation(time.DateOnly, issueDate, time.UTC)
+		row, err := scanInvoice(rows)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		row.DueDate, err = time.ParseInLocation(time.DateOnly, dueDate, time.UTC)
-		if err != nil {
-			return nil, err
+		if err := f(row); err != nil {
+			return err
 		}
-		results = append(results, row)
 	}
-	return &Rows{Rows: results}, nil
+	return rows.Err()
+}
+
+// scanInvoice scans the invoiceColumns.
+func scanInvoice(scanner rowScanner) (*Row, error) {
+	var row Row
+	var issueDate string
+	var dueDate string
+	if err := scanner.Scan(&row.InvoiceID, &row.CompanyID, &row.PartnerID, &issueDate, &row.Amount, &row.Fee, &row.FeeRate, &row.Tax, &row.TaxRate, &row.WithholdingTax, &row.Total, &dueDate, &row.Status, &row.TaxAmounts, &row.RegistrationNumber); err != nil {
+		return nil, err
+	}
+	var err error
+	row.IssueDate, err = time.ParseInLocation(time.DateOnly, issueDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	row.DueDate, err = time.ParseInLocation(time.DateOnly, dueDate, time.UTC)
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// SelectByID returns the invoice row identified by invoiceID with its lines, or nil if it doesn't exist.
+func (s *MySQL) SelectByID(ctx context.Context, invoiceID string) (*Row, error) {
+	row, err := scanInvoice(s.DB.QueryRowContext(ctx, "SELECT "+invoiceColumns+" FROM invoice WHERE invoice_id = ?;", invoiceID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.selectLines(ctx, []string{row.InvoiceID})
+	if err != nil {
+		return nil, err
+	}
+	row.Lines = lines[row.InvoiceID]
+	return row, nil
 }
 
 func (s *MySQL) Insert(ctx context.Context, companyID string, invoice *domain.Invoice) (*Row, error) {
@@ -68,36 +230,73 @@ func (s *MySQL) Insert(ctx context.Context, companyID string, invoice *domain.In
 	}
 	defer tx.Rollback() // The rollback will be ignored if the tx has been committed later in the function.
 
-	stmt, err := s.DB.PrepareContext(ctx, "INSERT INTO invoice (company_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO invoice (company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(ctx, companyID, invoice.IssueDate, invoice.Amount, invoice.Fee, invoice.FeeRate, invoice.Tax, invoice.TaxRate, invoice.Total, invoice.DueDate, invoice.Status)
+	result, err := stmt.ExecContext(ctx, companyID, invoice.PartnerID, invoice.IssueDate, invoice.Amount, invoice.Fee, invoice.FeeRate, invoice.Tax, invoice.TaxRate, invoice.WithholdingTax, invoice.Total, invoice.DueDate, invoice.Status, invoice.TaxAmounts, invoice.RegistrationNumber)
 	if err != nil {
 		return nil, err
 	}
+
+	// get auto-incremented invoice_id
+	invoiceID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	row := &Row{
+		InvoiceID:          strconv.FormatInt(invoiceID, 10),
+		CompanyID:          companyID,
+		PartnerID:          invoice.PartnerID,
+		IssueDate:          invoice.IssueDate,
+		Amount:             invoice.Amount,
+		Fee:                invoice.Fee,
+		FeeRate:            invoice.FeeRate,
+		Tax:                invoice.Tax,
+		TaxRate:            invoice.TaxRate,
+		WithholdingTax:     invoice.WithholdingTax,
+		Total:              invoice.Total,
+		DueDate:            invoice.DueDate,
+		Status:             string(invoice.Status),
+		TaxAmounts:         invoice.TaxAmounts,
+		RegistrationNumber: invoice.RegistrationNumber,
+		Lines:              invoice.Lines,
+	}
+	if err := insertLines(ctx, tx, []Row{*row}); err != nil {
+		return nil, err
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
+	return row, nil
+}
 
-	// get auto-incremented invoice_id
-	invoiceID, err := result.LastInsertId()
+// UpdateStatus moves the invoice from one status to another.
+// It reports false when the invoice is no longer in the from status, e.g. because it was updated concurrently.
+func (s *MySQL) UpdateStatus(ctx context.Context, invoiceID string, from, to domain.Status) (bool, error) {
+	result, err := s.DB.ExecContext(ctx, "UPDATE invoice SET status = ? WHERE invoice_id = ? AND status = ?;", to, invoiceID, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// SelectRates returns the rates of the company's contract effective on date, or nil if there is no such contract.
+func (s *MySQL) SelectRates(ctx context.Context, companyID string, date time.Time) (*RateRow, error) {
+	var row RateRow
+	err := s.DB.QueryRowContext(ctx, "SELECT fee_rate, tax_rate, rounding_mode FROM company_fees WHERE company_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to >= ?) ORDER BY valid_from DESC LIMIT 1;", companyID, date.Format(time.DateOnly), date.Format(time.DateOnly)).
+		Scan(&row.FeeRate, &row.TaxRate, &row.Rounding)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &Row{
-		InvoiceID: strconv.FormatInt(invoiceID, 10),
-		CompanyID: companyID,
-		IssueDate: invoice.IssueDate,
-		Amount:    invoice.Amount,
-		Fee:       invoice.Fee,
-		FeeRate:   invoice.FeeRate,
-		Tax:       invoice.Tax,
-		TaxRate:   invoice.TaxRate,
-		Total:     invoice.Total,
-		DueDate:   invoice.DueDate,
-		Status:    string(invoice.Status),
-	}, nil
+	return &row, nil
 }
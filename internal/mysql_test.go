@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"database/sql/driver"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
@@ -21,16 +22,16 @@ func TestMySQL_Select(t *testing.T) {
 	}{
 		{
 			name: "no error",
-			row:  []driver.Value{"1", "1", "2024-10-01", 10000, 400, 0.04, 40, 0.1, 0, "2024-10-31", "processing"},
+			row:  []driver.Value{"1", "1", "1", "2024-10-01", 10000, 400, 0.04, 40, 0.1, 0, 0, "2024-10-31", "processing", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002"},
 		},
 		{
 			name:    "issue_date format error",
-			row:     []driver.Value{"1", "1", "INVALID", 10000, 400, 0.04, 40, 0.1, 0, "2024-10-31", "processing"},
+			row:     []driver.Value{"1", "1", "1", "INVALID", 10000, 400, 0.04, 40, 0.1, 0, 0, "2024-10-31", "processing", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002"},
 			wantErr: &time.ParseError{Layout: "2006-01-02", Value: "INVALID", LayoutElem: "2006", ValueElem: "INVALID", Message: ""},
 		},
 		{
 			name:    "due_date format error",
-			row:     []driver.Value{"1", "1", "2024-10-01", 10000, 400, 0.04, 40, 0.1, 0, "INVALID", "processing"},
+			row:     []driver.Value{"1", "1", "1", "2024-10-01", 10000, 400, 0.04, 40, 0.1, 0, 0, "INVALID", "processing", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002"},
 			wantErr: &time.ParseError{Layout: "2006-01-02", Value: "INVALID", LayoutElem: "2006", ValueElem: "INVALID", Message: ""},
 		},
 	}
@@ -40,12 +41,136 @@ func TestMySQL_Select(t *testing.T) {
 			require.NoError(t, err)
 			defer db.Close()
 
-			mock.ExpectQuery(regexp.QuoteMeta("SELECT invoice_id, company_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status FROM invoice WHERE company_id = ? AND due_date BETWEEN ? AND ? AND status != 'paid';")).WithArgs("1", time.Now().Format(time.DateOnly), "9999-12-31").WillReturnRows(
-				sqlmock.NewRows([]string{"invoice_id", "company_id", "issue_date", "amount", "fee", "fee_rate", "tax", "tax_rate", "total", "due_date", "status"}).AddRow(tt.row...))
+			today := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number FROM invoice WHERE company_id = ? AND status IN (?, ?, ?) AND due_date >= ? AND due_date <= ? ORDER BY due_date ASC, invoice_id ASC LIMIT ?;")).
+				WithArgs("1", "unprocessed", "processing", "error", "2024-10-01", "9999-12-31", 100).WillReturnRows(
+				sqlmock.NewRows([]string{"invoice_id", "company_id", "partner_id", "issue_date", "amount", "fee", "fee_rate", "tax", "tax_rate", "withholding_tax", "total", "due_date", "status", "tax_amounts", "registration_number"}).AddRow(tt.row...))
+
+			if tt.wantErr == nil {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT invoice_id, description, quantity, unit_price, tax_category FROM invoice_lines WHERE invoice_id IN (?) ORDER BY invoice_id, line_no;")).
+					WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"invoice_id", "description", "quantity", "unit_price", "tax_category"}).AddRow("1", "お弁当", 2, 1000, "reduced"))
+			}
+
+			s := &MySQL{DB: db}
+			got, err := s.Select(context.Background(), DueByQuery("1", today, time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)))
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, []domain.InvoiceLine{{Description: "お弁当", Quantity: 2, UnitPrice: 1000, TaxCategory: domain.ReducedTax}}, got.Rows[0].Lines)
+			}
+		})
+	}
+}
+
+func TestSelectQuery(t *testing.T) {
+	ten, hundred := 10, 100
+	tests := []struct {
+		name     string
+		query    InvoiceQuery
+		wantSQL  string
+		wantArgs []any
+	}{
+		{
+			name:     "company only",
+			query:    InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "due_date"}, Limit: 100},
+			wantSQL:  "SELECT invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number FROM invoice WHERE company_id = ? ORDER BY due_date ASC, invoice_id ASC LIMIT ?;",
+			wantArgs: []any{"1", 100},
+		},
+		{
+			name:     "no limit",
+			query:    InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "amount"}},
+			wantSQL:  "SELECT invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number FROM invoice WHERE company_id = ? ORDER BY amount ASC, invoice_id ASC;",
+			wantArgs: []any{"1"},
+		},
+		{
+			name: "all filters with descending sort and cursor",
+			query: InvoiceQuery{
+				CompanyID:     "1",
+				PartnerID:     "2",
+				Statuses:      []domain.Status{domain.Paid},
+				IssueDateFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				IssueDateTo:   time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+				DueDateFrom:   time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC),
+				DueDateTo:     time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC),
+				AmountMin:     &ten,
+				AmountMax:     &hundred,
+				TotalMin:      &ten,
+				TotalMax:      &hundred,
+				Sort:          SortKey{Field: "total", Desc: true},
+				Limit:         11,
+				After:         &Cursor{Sort: "-total", Value: "50", InvoiceID: 7},
+			},
+			wantSQL: "SELECT invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number FROM invoice WHERE company_id = ? AND status IN (?) AND partner_id = ?" +
+				" AND issue_date >= ? AND issue_date <= ? AND due_date >= ? AND due_date <= ? AND amount >= ? AND amount <= ? AND total >= ? AND total <= ?" +
+				" AND (total < ? OR (total = ? AND invoice_id < ?)) ORDER BY total DESC, invoice_id DESC LIMIT ?;",
+			wantArgs: []any{"1", "paid", "2", "2024-01-01", "2024-12-31", "2024-02-01", "2025-01-31", 10, 100, 10, 100, "50", "50", int64(7), 11},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSQL, gotArgs := selectQuery(tt.query)
+			assert.Equal(t, tt.wantSQL, gotSQL)
+			assert.Equal(t, tt.wantArgs, gotArgs)
+		})
+	}
+}
+
+func TestMySQL_SelectByID(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     []driver.Value
+		want    *Row
+		wantErr error
+	}{
+		{
+			name: "no error",
+			row:  []driver.Value{"1", "1", "1", "2024-10-01", 10000, 400, 0.04, 40, 0.1, 1021, 9419, "2024-10-31", "processing", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002"},
+			want: &Row{
+				InvoiceID:          "1",
+				CompanyID:          "1",
+				PartnerID:          "1",
+				IssueDate:          time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+				Amount:             10000,
+				Fee:                400,
+				FeeRate:            domain.MustParseRate("0.04"),
+				Tax:                40,
+				TaxRate:            domain.MustParseRate("0.1"),
+				WithholdingTax:     1021,
+				Total:              9419,
+				DueDate:            time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
+				Status:             "processing",
+				TaxAmounts:         domain.TaxAmounts{{Rate: domain.MustParseRate("0.1"), Taxable: 400, Tax: 40}},
+				RegistrationNumber: "T7000012050002",
+			},
+		},
+		{
+			name: "no rows",
+		},
+		{
+			name:    "due_date format error",
+			row:     []driver.Value{"1", "1", "1", "2024-10-01", 10000, 400, 0.04, 40, 0.1, 0, 10440, "INVALID", "processing", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002"},
+			wantErr: &time.ParseError{Layout: "2006-01-02", Value: "INVALID", LayoutElem: "2006", ValueElem: "INVALID", Message: ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			rows := sqlmock.NewRows([]string{"invoice_id", "company_id", "partner_id", "issue_date", "amount", "fee", "fee_rate", "tax", "tax_rate", "withholding_tax", "total", "due_date", "status", "tax_amounts", "registration_number"})
+			if tt.row != nil {
+				rows.AddRow(tt.row...)
+			}
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number FROM invoice WHERE invoice_id = ?;")).WithArgs("1").WillReturnRows(rows)
+			if tt.want != nil {
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT invoice_id, description, quantity, unit_price, tax_category FROM invoice_lines WHERE invoice_id IN (?) ORDER BY invoice_id, line_no;")).
+					WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"invoice_id", "description", "quantity", "unit_price", "tax_category"}))
+			}
 
 			s := &MySQL{DB: db}
-			_, err = s.Select(context.Background(), "1", time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+			got, err := s.SelectByID(context.Background(), "1")
 			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
 		})
 	}
 }
@@ -58,7 +183,7 @@ func TestMySQL_Insert(t *testing.T) {
 	}{
 		{
 			name: "no error",
-			row:  []driver.Value{"1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 10000, 400, float32(0.04), 40, float32(0.1), 10440, time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC), "processing"},
+			row:  []driver.Value{"1", "1", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 10000, 400, "0.04", 40, "0.1", 0, 10440, time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC), "processing", `[{"rate":0.1,"taxable":400,"tax":40}]`, "T7000012050002"},
 		},
 	}
 	for _, tt := range tests {
@@ -68,23 +193,128 @@ func TestMySQL_Insert(t *testing.T) {
 			defer db.Close()
 
 			mock.ExpectBegin()
-			mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO invoice (company_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")).ExpectExec().WithArgs(tt.row...).WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectPrepare(regexp.QuoteMeta("INSERT INTO invoice (company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")).ExpectExec().WithArgs(tt.row...).WillReturnResult(sqlmock.NewResult(1, 1))
 			mock.ExpectCommit()
 
 			s := &MySQL{DB: db}
 			_, err = s.Insert(context.Background(), "1", &domain.Invoice{
-				IssueDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
-				Amount:    10000,
-				Fee:       400,
-				FeeRate:   0.04,
-				Tax:       40,
-				TaxRate:   0.1,
-				Total:     10440,
-				DueDate:   time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
-				Status:    domain.Processing,
+				PartnerID:          "1",
+				IssueDate:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Amount:             10000,
+				Fee:                400,
+				FeeRate:            domain.MustParseRate("0.04"),
+				Tax:                40,
+				TaxRate:            domain.MustParseRate("0.1"),
+				Total:              10440,
+				DueDate:            time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
+				Status:             domain.Processing,
+				TaxAmounts:         domain.TaxAmounts{{Rate: domain.MustParseRate("0.1"), Taxable: 400, Tax: 40}},
+				RegistrationNumber: "T7000012050002",
 			})
 			assert.Equal(t, tt.wantErr, err)
 			require.NoError(t, mock.ExpectationsWereMet())
 		})
 	}
 }
+
+func TestMySQL_SelectRates(t *testing.T) {
+	taxRate := domain.MustParseRate("0.08")
+	tests := []struct {
+		name string
+		row  []driver.Value
+		want *RateRow
+	}{
+		{
+			name: "contract with tax rate",
+			row:  []driver.Value{"0.0300", "0.0800", "half_up"},
+			want: &RateRow{FeeRate: domain.MustParseRate("0.03"), TaxRate: &taxRate, Rounding: domain.RoundHalfUp},
+		},
+		{
+			name: "contract without tax rate",
+			row:  []driver.Value{"0.0300", nil, "floor"},
+			want: &RateRow{FeeRate: domain.MustParseRate("0.03"), Rounding: domain.Floor},
+		},
+		{
+			name: "no contract",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			rows := sqlmock.NewRows([]string{"fee_rate", "tax_rate", "rounding_mode"})
+			if tt.row != nil {
+				rows.AddRow(tt.row...)
+			}
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT fee_rate, tax_rate, rounding_mode FROM company_fees WHERE company_id = ? AND valid_from <= ? AND (valid_to IS NULL OR valid_to >= ?) ORDER BY valid_from DESC LIMIT 1;")).WithArgs("1", "2024-10-01", "2024-10-01").WillReturnRows(rows)
+
+			s := &MySQL{DB: db}
+			got, err := s.SelectRates(context.Background(), "1", time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQL_UpdateStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{
+			name:     "row updated",
+			affected: 1,
+			want:     true,
+		},
+		{
+			name:     "row already changed",
+			affected: 0,
+			want:     false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE invoice SET status = ? WHERE invoice_id = ? AND status = ?;")).WithArgs(domain.Paid, "1", domain.Processing).WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			s := &MySQL{DB: db}
+			got, err := s.UpdateStatus(context.Background(), "1", domain.Processing, domain.Paid)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestMySQL_SelectEach(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	columns := []string{"invoice_id", "company_id", "partner_id", "issue_date", "amount", "fee", "fee_rate", "tax", "tax_rate", "withholding_tax", "total", "due_date", "status", "tax_amounts", "registration_number"}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, withholding_tax, total, due_date, status, tax_amounts, registration_number FROM invoice WHERE company_id = ? ORDER BY due_date ASC, invoice_id ASC;")).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("1", "1", "1", "2024-10-01", 10000, 400, "0.04", 40, "0.10", 0, 10440, "2024-10-31", "unprocessed", `[{"rate":0.1,"taxable":400,"tax":40}]`, "").
+			AddRow("2", "1", "1", "2024-10-01", 5000, 200, "0.04", 20, "0.10", 0, 5220, "2024-11-30", "paid", `[{"rate":0.1,"taxable":400,"tax":40}]`, "").
+			AddRow("3", "1", "1", "2024-10-01", 5000, 200, "0.04", 20, "0.10", 0, 5220, "2024-12-31", "paid", `[{"rate":0.1,"taxable":400,"tax":40}]`, ""))
+
+	s := &MySQL{DB: db}
+	var got []string
+	stop := errors.New("stop")
+	err = s.SelectEach(context.Background(), InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "due_date"}}, func(row *Row) error {
+		got = append(got, row.InvoiceID+":"+row.DueDate.Format(time.DateOnly))
+		if len(got) == 2 {
+			return stop
+		}
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, []string{"1:2024-10-31", "2:2024-11-30"}, got, "rows should be passed one by one until f fails")
+}
@@ -0,0 +1,360 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+type PartnerRequest struct {
+	Name       string `json:"name"`
+	Phone      string `json:"phone"`
+	PostalCode string `json:"postal_code"`
+	Address    string `json:"address"`
+	// Withholding defaults to none.
+	Withholding string `json:"withholding"`
+}
+
+type PartnerResponse struct {
+	PartnerID   string `json:"partner_id"`
+	CompanyID   string `json:"company_id"`
+	Name        string `json:"name"`
+	Phone       string `json:"phone"`
+	PostalCode  string `json:"postal_code"`
+	Address     string `json:"address"`
+	Withholding string `json:"withholding"`
+}
+
+func newPartnerResponse(partner *domain.BusinessPartner) PartnerResponse {
+	return PartnerResponse{
+		PartnerID:   partner.PartnerID,
+		CompanyID:   partner.CompanyID,
+		Name:        partner.Name,
+		Phone:       partner.Phone,
+		PostalCode:  partner.PostalCode,
+		Address:     partner.Address,
+		Withholding: string(partner.Withholding),
+	}
+}
+
+type PartnerListResponse struct {
+	Partners []PartnerResponse `json:"partners"`
+}
+
+type BankAccountRequest struct {
+	BankName       string `json:"bank_name"`
+	BranchName     string `json:"branch_name"`
+	AccountType    string `json:"account_type"`
+	AccountNumber  string `json:"account_number"`
+	HolderNameKana string `json:"holder_name_kana"`
+}
+
+type BankAccountResponse struct {
+	BankAccountID  string `json:"bank_account_id"`
+	PartnerID      string `json:"partner_id"`
+	CompanyID      string `json:"company_id"`
+	BankName       string `json:"bank_name"`
+	BranchName     string `json:"branch_name"`
+	AccountType    string `json:"account_type"`
+	AccountNumber  string `json:"account_number"`
+	HolderNameKana string `json:"holder_name_kana"`
+}
+
+func newBankAccountResponse(account *domain.BankAccount) BankAccountResponse {
+	return BankAccountResponse{
+		BankAccountID:  account.BankAccountID,
+		PartnerID:      account.PartnerID,
+		CompanyID:      account.CompanyID,
+		BankName:       account.BankName,
+		BranchName:     account.BranchName,
+		AccountType:    string(account.AccountType),
+		AccountNumber:  account.AccountNumber,
+		HolderNameKana: account.HolderNameKana,
+	}
+}
+
+type BankAccountListResponse struct {
+	BankAccounts []BankAccountResponse `json:"bank_accounts"`
+}
+
+type PartnerManager interface {
+	List(context.Context, string) ([]domain.BusinessPartner, error)
+	Get(context.Context, string, string) (*domain.BusinessPartner, error)
+	Create(context.Context, *domain.BusinessPartner) (*domain.BusinessPartner, error)
+	Update(context.Context, *domain.BusinessPartner) (*domain.BusinessPartner, error)
+	Delete(context.Context, string, string) error
+	ListBankAccounts(context.Context, string, string) ([]domain.BankAccount, error)
+	CreateBankAccount(context.Context, *domain.BankAccount) (*domain.BankAccount, error)
+	DeleteBankAccount(context.Context, string, string, string) error
+}
+
+func PartnerListHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID := r.PathValue("company_id")
+		partners, err := manager.List(r.Context(), companyID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to list business partners", "company_id", companyID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to list business partners"}`))
+			return
+		}
+		resp := make([]PartnerResponse, 0, len(partners))
+		for _, partner := range partners {
+			resp = append(resp, newPartnerResponse(&partner))
+		}
+		writeJSON(w, r, PartnerListResponse{Partners: resp}, logger)
+	}
+}
+
+func PartnerGetHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID, partnerID := r.PathValue("company_id"), r.PathValue("partner_id")
+		partner, err := manager.Get(r.Context(), companyID, partnerID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrPartnerNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Business partner not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to get business partner", "company_id", companyID, "partner_id", partnerID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to get business partner"}`))
+			return
+		}
+		writeJSON(w, r, newPartnerResponse(partner), logger)
+	}
+}
+
+func PartnerCreateHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID := r.PathValue("company_id")
+		var body PartnerRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode business partner request", "body", body, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to decode business partner request"}`))
+			return
+		}
+		partner, err := manager.Create(r.Context(), body.partner(companyID, ""))
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidPartner) {
+			writeValidationError(w, err)
+			return
+		}
+		if errors.Is(err, ErrCompanyNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Company not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to create business partner", "company_id", companyID, "body", body, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to create business partner"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, r, newPartnerResponse(partner), logger)
+	}
+}
+
+func PartnerUpdateHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID, partnerID := r.PathValue("company_id"), r.PathValue("partner_id")
+		var body PartnerRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode business partner request", "body", body, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to decode business partner request"}`))
+			return
+		}
+		partner, err := manager.Update(r.Context(), body.partner(companyID, partnerID))
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidPartner) {
+			writeValidationError(w, err)
+			return
+		}
+		if errors.Is(err, ErrPartnerNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Business partner not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to update business partner", "company_id", companyID, "partner_id", partnerID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to update business partner"}`))
+			return
+		}
+		writeJSON(w, r, newPartnerResponse(partner), logger)
+	}
+}
+
+func PartnerDeleteHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID, partnerID := r.PathValue("company_id"), r.PathValue("partner_id")
+		err := manager.Delete(r.Context(), companyID, partnerID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrPartnerNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Business partner not found"}`))
+			return
+		}
+		if errors.Is(err, ErrPartnerInUse) {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"message":"Business partner is referenced by invoices"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to delete business partner", "company_id", companyID, "partner_id", partnerID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to delete business partner"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func BankAccountListHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID, partnerID := r.PathValue("company_id"), r.PathValue("partner_id")
+		accounts, err := manager.ListBankAccounts(r.Context(), companyID, partnerID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrPartnerNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Business partner not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to list bank accounts", "company_id", companyID, "partner_id", partnerID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to list bank accounts"}`))
+			return
+		}
+		resp := make([]BankAccountResponse, 0, len(accounts))
+		for _, account := range accounts {
+			resp = append(resp, newBankAccountResponse(&account))
+		}
+		writeJSON(w, r, BankAccountListResponse{BankAccounts: resp}, logger)
+	}
+}
+
+func BankAccountCreateHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID, partnerID := r.PathValue("company_id"), r.PathValue("partner_id")
+		var body BankAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			logger.ErrorContext(r.Context(), "Failed to decode bank account request", "body", body, "err", err)
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"Failed to decode bank account request"}`))
+			return
+		}
+		account, err := manager.CreateBankAccount(r.Context(), &domain.BankAccount{
+			PartnerID:      partnerID,
+			CompanyID:      companyID,
+			BankName:       body.BankName,
+			BranchName:     body.BranchName,
+			AccountType:    domain.AccountType(body.AccountType),
+			AccountNumber:  body.AccountNumber,
+			HolderNameKana: body.HolderNameKana,
+		})
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, domain.ErrInvalidBankAccount) {
+			writeValidationError(w, err)
+			return
+		}
+		if errors.Is(err, ErrPartnerNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Business partner not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to create bank account", "company_id", companyID, "partner_id", partnerID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to create bank account"}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, r, newBankAccountResponse(account), logger)
+	}
+}
+
+func BankAccountDeleteHandler(manager PartnerManager, logger *slog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		companyID, partnerID, accountID := r.PathValue("company_id"), r.PathValue("partner_id"), r.PathValue("id")
+		err := manager.DeleteBankAccount(r.Context(), companyID, partnerID, accountID)
+		if errors.Is(err, ErrForbidden) {
+			logger.WarnContext(r.Context(), "Business partners of another company were requested", "company_id", companyID)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"Forbidden"}`))
+			return
+		}
+		if errors.Is(err, ErrBankAccountNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"Bank account not found"}`))
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Failed to delete bank account", "company_id", companyID, "partner_id", partnerID, "bank_account_id", accountID, "err", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"message":"Failed to delete bank account"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (b *PartnerRequest) partner(companyID, partnerID string) *domain.BusinessPartner {
+	withholding := domain.WithholdingCategory(b.Withholding)
+	if withholding == "" {
+		withholding = domain.NoWithholding
+	}
+	return &domain.BusinessPartner{
+		PartnerID:   partnerID,
+		CompanyID:   companyID,
+		Name:        b.Name,
+		Phone:       b.Phone,
+		PostalCode:  b.PostalCode,
+		Address:     b.Address,
+		Withholding: withholding,
+	}
+}
@@ -0,0 +1,236 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePartnerManager struct {
+	PartnerManager
+	get               func(context.Context, string, string) (*domain.BusinessPartner, error)
+	create            func(context.Context, *domain.BusinessPartner) (*domain.BusinessPartner, error)
+	delete            func(context.Context, string, string) error
+	createBankAccount func(context.Context, *domain.BankAccount) (*domain.BankAccount, error)
+}
+
+func (f *fakePartnerManager) Get(ctx context.Context, companyID, partnerID string) (*domain.BusinessPartner, error) {
+	return f.get(ctx, companyID, partnerID)
+}
+
+func (f *fakePartnerManager) Create(ctx context.Context, partner *domain.BusinessPartner) (*domain.BusinessPartner, error) {
+	return f.create(ctx, partner)
+}
+
+func (f *fakePartnerManager) Delete(ctx context.Context, companyID, partnerID string) error {
+	return f.delete(ctx, companyID, partnerID)
+}
+
+func (f *fakePartnerManager) CreateBankAccount(ctx context.Context, account *domain.BankAccount) (*domain.BankAccount, error) {
+	return f.createBankAccount(ctx, account)
+}
+
+func servePartner(t *testing.T, h http.HandlerFunc, method, body string) (int, string) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(method, "http://localhost/api/companies/1/partners/2", strings.NewReader(body))
+	r.SetPathValue("company_id", "1")
+	r.SetPathValue("partner_id", "2")
+	h(w, r)
+	b, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	return w.Code, string(b)
+}
+
+func TestPartnerCreateHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "201 created",
+			body:     `{"name":"Vendor Inc."}`,
+			wantBody: `{"partner_id":"2","company_id":"1","name":"Vendor Inc.","phone":"","postal_code":"","address":"","withholding":"none"}` + "\n",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "201 created with withholding",
+			body:     `{"name":"山田デザイン事務所","withholding":"professional"}`,
+			wantBody: `{"partner_id":"2","company_id":"1","name":"山田デザイン事務所","phone":"","postal_code":"","address":"","withholding":"professional"}` + "\n",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "400 bad request with invalid partner",
+			body:     `{}`,
+			err:      fmt.Errorf("%w: 'name' mustn't be empty", domain.ErrInvalidPartner),
+			wantBody: `{"message":"invalid business partner: 'name' mustn't be empty"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "404 not found with unknown company",
+			body:     `{"name":"Vendor Inc."}`,
+			err:      ErrCompanyNotFound,
+			wantBody: `{"message":"Company not found"}`,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakePartnerManager{create: func(_ context.Context, partner *domain.BusinessPartner) (*domain.BusinessPartner, error) {
+				assert.Equal(t, "1", partner.CompanyID)
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				partner.PartnerID = "2"
+				return partner, nil
+			}}
+			code, body := servePartner(t, PartnerCreateHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodPost, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestPartnerGetHandler(t *testing.T) {
+	manager := &fakePartnerManager{get: func(_ context.Context, companyID, partnerID string) (*domain.BusinessPartner, error) {
+		assert.Equal(t, "1", companyID)
+		assert.Equal(t, "2", partnerID)
+		return nil, ErrPartnerNotFound
+	}}
+	code, body := servePartner(t, PartnerGetHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodGet, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, `{"message":"Business partner not found"}`, body)
+}
+
+func TestPartnerDeleteHandler(t *testing.T) {
+	manager := &fakePartnerManager{delete: func(context.Context, string, string) error {
+		return ErrPartnerInUse
+	}}
+	code, body := servePartner(t, PartnerDeleteHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodDelete, "")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, `{"message":"Business partner is referenced by invoices"}`, body)
+}
+
+func TestBankAccountCreateHandler(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantBody string
+		wantCode int
+	}{
+		{
+			name:     "201 created",
+			body:     `{"bank_name":"みずほ銀行","branch_name":"東京営業部","account_type":"ordinary","account_number":"1234567","holder_name_kana":"カ）サンプル"}`,
+			wantBody: `{"bank_account_id":"3","partner_id":"2","company_id":"1","bank_name":"みずほ銀行","branch_name":"東京営業部","account_type":"ordinary","account_number":"1234567","holder_name_kana":"カ）サンプル"}` + "\n",
+			wantCode: http.StatusCreated,
+		},
+		{
+			name:     "400 bad request with invalid account",
+			body:     `{"account_number":"1"}`,
+			err:      fmt.Errorf("%w: 'account_number' must be 7 digits, but got 1", domain.ErrInvalidBankAccount),
+			wantBody: `{"message":"invalid bank account: 'account_number' must be 7 digits, but got 1"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "404 not found with partner of another company",
+			body:     `{}`,
+			err:      ErrPartnerNotFound,
+			wantBody: `{"message":"Business partner not found"}`,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &fakePartnerManager{createBankAccount: func(_ context.Context, account *domain.BankAccount) (*domain.BankAccount, error) {
+				assert.Equal(t, "1", account.CompanyID)
+				assert.Equal(t, "2", account.PartnerID)
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				account.BankAccountID = "3"
+				return account, nil
+			}}
+			code, body := servePartner(t, BankAccountCreateHandler(manager, slog.New(slog.NewTextHandler(os.Stderr, nil))), http.MethodPost, tt.body)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantBody, body)
+		})
+	}
+}
+
+func TestPartnerTenantIsolation(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	manager := &PartnerService{Repository: &fakePartnerRepository{
+		PartnerSelectorFunc: func(context.Context, string) (*PartnerRow, error) {
+			require.FailNow(t, "business partner of another company must not be looked up")
+			return nil, nil
+		},
+		selectPartners: func(context.Context, string) ([]PartnerRow, error) {
+			require.FailNow(t, "business partners of another company must not be listed")
+			return nil, nil
+		},
+		insertPartner: func(context.Context, *domain.BusinessPartner) (*PartnerRow, error) {
+			require.FailNow(t, "business partner must not be created for another company")
+			return nil, nil
+		},
+		updatePartner: func(context.Context, *domain.BusinessPartner) (bool, error) {
+			require.FailNow(t, "business partner of another company must not be updated")
+			return false, nil
+		},
+		deletePartner: func(context.Context, string, string) (bool, error) {
+			require.FailNow(t, "business partner of another company must not be deleted")
+			return false, nil
+		},
+		insertBankAccount: func(context.Context, *domain.BankAccount) (*BankAccountRow, error) {
+			require.FailNow(t, "bank account of another company must not be created")
+			return nil, nil
+		},
+		deleteBankAccount: func(context.Context, string, string, string) (bool, error) {
+			require.FailNow(t, "bank account of another company must not be deleted")
+			return false, nil
+		},
+	}}
+	partnerBody := `{"name":"Vendor Inc."}`
+	accountBody := `{"bank_name":"みずほ銀行","branch_name":"渋谷支店","account_type":"ordinary","account_number":"1234567","holder_name_kana":"カ）ベンダー"}`
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		method  string
+		body    string
+	}{
+		{name: "list business partners", handler: PartnerListHandler(manager, logger), method: http.MethodGet},
+		{name: "get a business partner", handler: PartnerGetHandler(manager, logger), method: http.MethodGet},
+		{name: "create a business partner", handler: PartnerCreateHandler(manager, logger), method: http.MethodPost, body: partnerBody},
+		{name: "update a business partner", handler: PartnerUpdateHandler(manager, logger), method: http.MethodPut, body: partnerBody},
+		{name: "delete a business partner", handler: PartnerDeleteHandler(manager, logger), method: http.MethodDelete},
+		{name: "list bank accounts", handler: BankAccountListHandler(manager, logger), method: http.MethodGet},
+		{name: "create a bank account", handler: BankAccountCreateHandler(manager, logger), method: http.MethodPost, body: accountBody},
+		{name: "delete a bank account", handler: BankAccountDeleteHandler(manager, logger), method: http.MethodDelete},
+	}
+	for _, tt := range tests {
+		t.Run("403 forbidden to "+tt.name+" of another company", func(t *testing.T) {
+			// servePartner requests company 1, which the accountant of company 2 doesn't belong to.
+			h := func(w http.ResponseWriter, r *http.Request) {
+				r = r.WithContext(WithUser(r.Context(), &domain.User{UserID: "1", CompanyID: "2", Username: "foo", Role: domain.Accountant}))
+				r.SetPathValue("id", "1")
+				tt.handler(w, r)
+			}
+			code, body := servePartner(t, h, tt.method, tt.body)
+			assert.Equal(t, http.StatusForbidden, code)
+			assert.Equal(t, `{"message":"Forbidden"}`, body)
+		})
+	}
+}
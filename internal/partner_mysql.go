@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	_ PartnerRepository   = (*MySQL)(nil)
+	_ BankAccountSelector = (*MySQL)(nil)
+)
+
+type PartnerRow struct {
+	PartnerID   string
+	CompanyID   string
+	Name        string
+	Phone       string
+	PostalCode  string
+	Address     string
+	Withholding string
+}
+
+func (r *PartnerRow) partner() *domain.BusinessPartner {
+	return &domain.BusinessPartner{
+		PartnerID:   r.PartnerID,
+		CompanyID:   r.CompanyID,
+		Name:        r.Name,
+		Phone:       r.Phone,
+		PostalCode:  r.PostalCode,
+		Address:     r.Address,
+		Withholding: domain.WithholdingCategory(r.Withholding),
+	}
+}
+
+type BankAccountRow struct {
+	BankAccountID  string
+	PartnerID      string
+	CompanyID      string
+	BankName       string
+	BranchName     string
+	AccountType    string
+	AccountNumber  string
+	HolderNameKana string
+}
+
+func (r *BankAccountRow) bankAccount() *domain.BankAccount {
+	return &domain.BankAccount{
+		BankAccountID:  r.BankAccountID,
+		PartnerID:      r.PartnerID,
+		CompanyID:      r.CompanyID,
+		BankName:       r.BankName,
+		BranchName:     r.BranchName,
+		AccountType:    domain.AccountType(r.AccountType),
+		AccountNumber:  r.AccountNumber,
+		HolderNameKana: r.HolderNameKana,
+	}
+}
+
+func (s *MySQL) SelectPartners(ctx context.Context, companyID string) ([]PartnerRow, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT partner_id, company_id, name, phone, postal_code, address, withholding FROM business_partners WHERE company_id = ? ORDER BY partner_id;", companyID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]PartnerRow, 0)
+	for rows.Next() {
+		var row PartnerRow
+		if err := rows.Scan(&row.PartnerID, &row.CompanyID, &row.Name, &row.Phone, &row.PostalCode, &row.Address, &row.Withholding); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+// SelectPartner returns the business partner identified by partnerID, or nil if it doesn't exist.
+func (s *MySQL) SelectPartner(ctx context.Context, partnerID string) (*PartnerRow, error) {
+	var row PartnerRow
+	err := s.DB.QueryRowContext(ctx, "SELECT partner_id, company_id, name, phone, postal_code, address, withholding FROM business_partners WHERE partner_id = ?;", partnerID).
+		Scan(&row.PartnerID, &row.CompanyID, &row.Name, &row.Phone, &row.PostalCode, &row.Address, &row.Withholding)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// InsertPartner returns ErrCompanyNotFound when the owning company doesn't exist.
+func (s *MySQL) InsertPartner(ctx context.Context, partner *domain.BusinessPartner) (*PartnerRow, error) {
+	result, err := s.DB.ExecContext(ctx, "INSERT INTO business_partners (company_id, name, phone, postal_code, address, withholding) VALUES (?, ?, ?, ?, ?, ?);", partner.CompanyID, partner.Name, partner.Phone, partner.PostalCode, partner.Address, partner.Withholding)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoReferencedRow {
+		return nil, ErrCompanyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	partnerID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &PartnerRow{
+		PartnerID:   strconv.FormatInt(partnerID, 10),
+		CompanyID:   partner.CompanyID,
+		Name:        partner.Name,
+		Phone:       partner.Phone,
+		PostalCode:  partner.PostalCode,
+		Address:     partner.Address,
+		Withholding: string(partner.Withholding),
+	}, nil
+}
+
+// UpdatePartner overwrites the business partner of the company and reports false if it doesn't exist.
+func (s *MySQL) UpdatePartner(ctx context.Context, partner *domain.BusinessPartner) (bool, error) {
+	// RowsAffected is 0 when nothing changed, so check existence in the same transaction instead.
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // The rollback will be ignored if the tx has been committed later in the function.
+
+	var id string
+	err = tx.QueryRowContext(ctx, "SELECT partner_id FROM business_partners WHERE company_id = ? AND partner_id = ? FOR UPDATE;", partner.CompanyID, partner.PartnerID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE business_partners SET name = ?, phone = ?, postal_code = ?, address = ?, withholding = ? WHERE partner_id = ?;", partner.Name, partner.Phone, partner.PostalCode, partner.Address, partner.Withholding, partner.PartnerID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// DeletePartner deletes the business partner of the company with its bank accounts and reports false if it doesn't exist.
+// ErrPartnerInUse is returned when invoices still reference the partner.
+func (s *MySQL) DeletePartner(ctx context.Context, companyID, partnerID string) (bool, error) {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM business_partners WHERE company_id = ? AND partner_id = ?;", companyID, partnerID)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errRowIsReferenced {
+		return false, ErrPartnerInUse
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func (s *MySQL) SelectBankAccounts(ctx context.Context, companyID, partnerID string) ([]BankAccountRow, error) {
+	rows, err := s.DB.QueryContext(ctx, "SELECT bank_account_id, partner_id, company_id, bank_name, branch_name, account_type, account_number, holder_name_kana FROM partner_bank_accounts WHERE company_id = ? AND partner_id = ? ORDER BY bank_account_id;", companyID, partnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]BankAccountRow, 0)
+	for rows.Next() {
+		var row BankAccountRow
+		if err := rows.Scan(&row.BankAccountID, &row.PartnerID, &row.CompanyID, &row.BankName, &row.BranchName, &row.AccountType, &row.AccountNumber, &row.HolderNameKana); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
+
+func (s *MySQL) InsertBankAccount(ctx context.Context, account *domain.BankAccount) (*BankAccountRow, error) {
+	result, err := s.DB.ExecContext(ctx, "INSERT INTO partner_bank_accounts (partner_id, company_id, bank_name, branch_name, account_type, account_number, holder_name_kana) VALUES (?, ?, ?, ?, ?, ?, ?);", account.PartnerID, account.CompanyID, account.BankName, account.BranchName, account.AccountType, account.AccountNumber, account.HolderNameKana)
+	if err != nil {
+		return nil, err
+	}
+	accountID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &BankAccountRow{
+		BankAccountID:  strconv.FormatInt(accountID, 10),
+		PartnerID:      account.PartnerID,
+		CompanyID:      account.CompanyID,
+		BankName:       account.BankName,
+		BranchName:     account.BranchName,
+		AccountType:    string(account.AccountType),
+		AccountNumber:  account.AccountNumber,
+		HolderNameKana: account.HolderNameKana,
+	}, nil
+}
+
+// DeleteBankAccount deletes the bank account of the company's partner and reports false if it doesn't exist.
+func (s *MySQL) DeleteBankAccount(ctx context.Context, companyID, partnerID, accountID string) (bool, error) {
+	result, err := s.DB.ExecContext(ctx, "DELETE FROM partner_bank_accounts WHERE company_id = ? AND partner_id = ? AND bank_account_id = ?;", companyID, partnerID, accountID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
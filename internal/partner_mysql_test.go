@@ -0,0 +1,116 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQL_SelectPartner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT partner_id, company_id, name, phone, postal_code, address, withholding FROM business_partners WHERE partner_id = ?;")).WithArgs("1").WillReturnRows(
+		sqlmock.NewRows([]string{"partner_id", "company_id", "name", "phone", "postal_code", "address", "withholding"}).AddRow("1", "2", "Vendor Inc.", "", "", "", "professional"))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT partner_id, company_id, name, phone, postal_code, address, withholding FROM business_partners WHERE partner_id = ?;")).WithArgs("999").WillReturnRows(
+		sqlmock.NewRows([]string{"partner_id", "company_id", "name", "phone", "postal_code", "address", "withholding"}))
+
+	s := &MySQL{DB: db}
+	got, err := s.SelectPartner(context.Background(), "1")
+	require.NoError(t, err)
+	assert.Equal(t, &PartnerRow{PartnerID: "1", CompanyID: "2", Name: "Vendor Inc.", Withholding: "professional"}, got)
+
+	got, err = s.SelectPartner(context.Background(), "999")
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestMySQL_InsertPartner(t *testing.T) {
+	tests := []struct {
+		name    string
+		execErr error
+		want    *PartnerRow
+		wantErr error
+	}{
+		{
+			name: "no error",
+			want: &PartnerRow{PartnerID: "5", CompanyID: "1", Name: "Vendor Inc.", Withholding: "none"},
+		},
+		{
+			name:    "company doesn't exist",
+			execErr: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"},
+			wantErr: ErrCompanyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO business_partners (company_id, name, phone, postal_code, address, withholding) VALUES (?, ?, ?, ?, ?, ?);")).WithArgs("1", "Vendor Inc.", "", "", "", "none")
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(5, 1))
+			}
+
+			s := &MySQL{DB: db}
+			got, err := s.InsertPartner(context.Background(), &domain.BusinessPartner{CompanyID: "1", Name: "Vendor Inc.", Withholding: domain.NoWithholding})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQL_DeletePartner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM business_partners WHERE company_id = ? AND partner_id = ?;")).WithArgs("1", "1").
+		WillReturnError(&mysql.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"})
+
+	s := &MySQL{DB: db}
+	_, err = s.DeletePartner(context.Background(), "1", "1")
+	assert.True(t, errors.Is(err, ErrPartnerInUse))
+}
+
+func TestMySQL_SelectBankAccounts(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT bank_account_id, partner_id, company_id, bank_name, branch_name, account_type, account_number, holder_name_kana FROM partner_bank_accounts WHERE company_id = ? AND partner_id = ? ORDER BY bank_account_id;")).
+		WithArgs("1", "2").WillReturnRows(
+		sqlmock.NewRows([]string{"bank_account_id", "partner_id", "company_id", "bank_name", "branch_name", "account_type", "account_number", "holder_name_kana"}).
+			AddRow("3", "2", "1", "みずほ銀行", "東京営業部", "ordinary", "1234567", "カ）サンプル"))
+
+	s := &MySQL{DB: db}
+	got, err := s.SelectBankAccounts(context.Background(), "1", "2")
+	require.NoError(t, err)
+	assert.Equal(t, []BankAccountRow{{BankAccountID: "3", PartnerID: "2", CompanyID: "1", BankName: "みずほ銀行", BranchName: "東京営業部", AccountType: "ordinary", AccountNumber: "1234567", HolderNameKana: "カ）サンプル"}}, got)
+}
+
+func TestMySQL_InsertBankAccount(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO partner_bank_accounts (partner_id, company_id, bank_name, branch_name, account_type, account_number, holder_name_kana) VALUES (?, ?, ?, ?, ?, ?, ?);")).
+		WithArgs("2", "1", "みずほ銀行", "東京営業部", domain.Ordinary, "1234567", "カ）サンプル").WillReturnResult(sqlmock.NewResult(3, 1))
+
+	s := &MySQL{DB: db}
+	got, err := s.InsertBankAccount(context.Background(), &domain.BankAccount{PartnerID: "2", CompanyID: "1", BankName: "みずほ銀行", BranchName: "東京営業部", AccountType: domain.Ordinary, AccountNumber: "1234567", HolderNameKana: "カ）サンプル"})
+	require.NoError(t, err)
+	assert.Equal(t, "3", got.BankAccountID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+var (
+	ErrPartnerNotFound     = errors.New("business partner not found")
+	ErrPartnerInUse        = errors.New("business partner is referenced by invoices")
+	ErrBankAccountNotFound = errors.New("bank account not found")
+)
+
+type PartnerSelector interface {
+	SelectPartner(context.Context, string) (*PartnerRow, error)
+}
+
+type PartnerSelectorFunc func(context.Context, string) (*PartnerRow, error)
+
+func (f PartnerSelectorFunc) SelectPartner(ctx context.Context, s string) (*PartnerRow, error) {
+	return f(ctx, s)
+}
+
+type PartnerRepository interface {
+	PartnerSelector
+	SelectPartners(context.Context, string) ([]PartnerRow, error)
+	InsertPartner(context.Context, *domain.BusinessPartner) (*PartnerRow, error)
+	UpdatePartner(context.Context, *domain.BusinessPartner) (bool, error)
+	DeletePartner(context.Context, string, string) (bool, error)
+	SelectBankAccounts(context.Context, string, string) ([]BankAccountRow, error)
+	InsertBankAccount(context.Context, *domain.BankAccount) (*BankAccountRow, error)
+	DeleteBankAccount(context.Context, string, string, string) (bool, error)
+}
+
+// PartnerService manages the business partners of a company. Every method is scoped to companyID,
+// and partners of other companies are reported as ErrPartnerNotFound. ErrForbidden is returned when
+// the authenticated user belongs to a company other than companyID.
+type PartnerService struct {
+	Repository PartnerRepository
+}
+
+func (s *PartnerService) List(ctx context.Context, companyID string) ([]domain.BusinessPartner, error) {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	rows, err := s.Repository.SelectPartners(ctx, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("partner service error: %w", err)
+	}
+	partners := make([]domain.BusinessPartner, 0, len(rows))
+	for _, row := range rows {
+		partners = append(partners, *row.partner())
+	}
+	return partners, nil
+}
+
+func (s *PartnerService) Get(ctx context.Context, companyID, partnerID string) (*domain.BusinessPartner, error) {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	row, err := s.Repository.SelectPartner(ctx, partnerID)
+	if err != nil {
+		return nil, fmt.Errorf("partner service error: %w", err)
+	}
+	if row == nil || row.CompanyID != companyID {
+		return nil, ErrPartnerNotFound
+	}
+	return row.partner(), nil
+}
+
+func (s *PartnerService) Create(ctx context.Context, partner *domain.BusinessPartner) (*domain.BusinessPartner, error) {
+	if err := authorizeCompany(ctx, partner.CompanyID); err != nil {
+		return nil, err
+	}
+	if err := partner.Validate(); err != nil {
+		return nil, err
+	}
+	row, err := s.Repository.InsertPartner(ctx, partner)
+	if errors.Is(err, ErrCompanyNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert error: %w", err)
+	}
+	return row.partner(), nil
+}
+
+func (s *PartnerService) Update(ctx context.Context, partner *domain.BusinessPartner) (*domain.BusinessPartner, error) {
+	if err := authorizeCompany(ctx, partner.CompanyID); err != nil {
+		return nil, err
+	}
+	if err := partner.Validate(); err != nil {
+		return nil, err
+	}
+	updated, err := s.Repository.UpdatePartner(ctx, partner)
+	if err != nil {
+		return nil, fmt.Errorf("update error: %w", err)
+	}
+	if !updated {
+		return nil, ErrPartnerNotFound
+	}
+	return partner, nil
+}
+
+func (s *PartnerService) Delete(ctx context.Context, companyID, partnerID string) error {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return err
+	}
+	deleted, err := s.Repository.DeletePartner(ctx, companyID, partnerID)
+	if errors.Is(err, ErrPartnerInUse) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("delete error: %w", err)
+	}
+	if !deleted {
+		return ErrPartnerNotFound
+	}
+	return nil
+}
+
+func (s *PartnerService) ListBankAccounts(ctx context.Context, companyID, partnerID string) ([]domain.BankAccount, error) {
+	if _, err := s.Get(ctx, companyID, partnerID); err != nil {
+		return nil, err
+	}
+	rows, err := s.Repository.SelectBankAccounts(ctx, companyID, partnerID)
+	if err != nil {
+		return nil, fmt.Errorf("partner service error: %w", err)
+	}
+	accounts := make([]domain.BankAccount, 0, len(rows))
+	for _, row := range rows {
+		accounts = append(accounts, *row.bankAccount())
+	}
+	return accounts, nil
+}
+
+func (s *PartnerService) CreateBankAccount(ctx context.Context, account *domain.BankAccount) (*domain.BankAccount, error) {
+	if err := authorizeCompany(ctx, account.CompanyID); err != nil {
+		return nil, err
+	}
+	if err := account.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.Get(ctx, account.CompanyID, account.PartnerID); err != nil {
+		return nil, err
+	}
+	row, err := s.Repository.InsertBankAccount(ctx, account)
+	if err != nil {
+		return nil, fmt.Errorf("insert error: %w", err)
+	}
+	return row.bankAccount(), nil
+}
+
+func (s *PartnerService) DeleteBankAccount(ctx context.Context, companyID, partnerID, accountID string) error {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return err
+	}
+	deleted, err := s.Repository.DeleteBankAccount(ctx, companyID, partnerID, accountID)
+	if err != nil {
+		return fmt.Errorf("delete error: %w", err)
+	}
+	if !deleted {
+		return ErrBankAccountNotFound
+	}
+	return nil
+}
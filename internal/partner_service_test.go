@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePartnerRepository stubs every PartnerRepository method with its own function so each test sets only what it needs.
+type fakePartnerRepository struct {
+	PartnerSelectorFunc
+	selectPartners     func(context.Context, string) ([]PartnerRow, error)
+	insertPartner      func(context.Context, *domain.BusinessPartner) (*PartnerRow, error)
+	updatePartner      func(context.Context, *domain.BusinessPartner) (bool, error)
+	deletePartner      func(context.Context, string, string) (bool, error)
+	selectBankAccounts func(context.Context, string, string) ([]BankAccountRow, error)
+	insertBankAccount  func(context.Context, *domain.BankAccount) (*BankAccountRow, error)
+	deleteBankAccount  func(context.Context, string, string, string) (bool, error)
+}
+
+func (f *fakePartnerRepository) SelectPartners(ctx context.Context, companyID string) ([]PartnerRow, error) {
+	return f.selectPartners(ctx, companyID)
+}
+
+func (f *fakePartnerRepository) InsertPartner(ctx context.Context, partner *domain.BusinessPartner) (*PartnerRow, error) {
+	return f.insertPartner(ctx, partner)
+}
+
+func (f *fakePartnerRepository) UpdatePartner(ctx context.Context, partner *domain.BusinessPartner) (bool, error) {
+	return f.updatePartner(ctx, partner)
+}
+
+func (f *fakePartnerRepository) DeletePartner(ctx context.Context, companyID, partnerID string) (bool, error) {
+	return f.deletePartner(ctx, companyID, partnerID)
+}
+
+func (f *fakePartnerRepository) SelectBankAccounts(ctx context.Context, companyID, partnerID string) ([]BankAccountRow, error) {
+	return f.selectBankAccounts(ctx, companyID, partnerID)
+}
+
+func (f *fakePartnerRepository) InsertBankAccount(ctx context.Context, account *domain.BankAccount) (*BankAccountRow, error) {
+	return f.insertBankAccount(ctx, account)
+}
+
+func (f *fakePartnerRepository) DeleteBankAccount(ctx context.Context, companyID, partnerID, accountID string) (bool, error) {
+	return f.deleteBankAccount(ctx, companyID, partnerID, accountID)
+}
+
+func TestPartnerService_Get(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     *PartnerRow
+		want    *domain.BusinessPartner
+		wantErr error
+	}{
+		{
+			name: "partner of the company",
+			row:  &PartnerRow{PartnerID: "1", CompanyID: "1", Name: "Vendor Inc."},
+			want: &domain.BusinessPartner{PartnerID: "1", CompanyID: "1", Name: "Vendor Inc."},
+		},
+		{
+			name:    "partner of another company is hidden",
+			row:     &PartnerRow{PartnerID: "1", CompanyID: "2", Name: "Vendor Inc."},
+			wantErr: ErrPartnerNotFound,
+		},
+		{
+			name:    "partner doesn't exist",
+			wantErr: ErrPartnerNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := PartnerService{Repository: &fakePartnerRepository{
+				PartnerSelectorFunc: func(context.Context, string) (*PartnerRow, error) {
+					return tt.row, nil
+				},
+			}}
+			got, err := s.Get(context.Background(), "1", "1")
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPartnerService_CreateBankAccount(t *testing.T) {
+	valid := domain.BankAccount{PartnerID: "1", CompanyID: "1", BankName: "みずほ銀行", BranchName: "東京営業部", AccountType: domain.Ordinary, AccountNumber: "1234567", HolderNameKana: "カ）サンプル"}
+	invalid := valid
+	invalid.AccountNumber = "1"
+	tests := []struct {
+		name       string
+		account    domain.BankAccount
+		partnerRow *PartnerRow
+		wantErr    error
+	}{
+		{
+			name:       "valid account of the company's partner",
+			account:    valid,
+			partnerRow: &PartnerRow{PartnerID: "1", CompanyID: "1"},
+		},
+		{
+			name:       "partner of another company",
+			account:    valid,
+			partnerRow: &PartnerRow{PartnerID: "1", CompanyID: "2"},
+			wantErr:    ErrPartnerNotFound,
+		},
+		{
+			name:       "invalid account",
+			account:    invalid,
+			partnerRow: &PartnerRow{PartnerID: "1", CompanyID: "1"},
+			wantErr:    domain.ErrInvalidBankAccount,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := PartnerService{Repository: &fakePartnerRepository{
+				PartnerSelectorFunc: func(context.Context, string) (*PartnerRow, error) {
+					return tt.partnerRow, nil
+				},
+				insertBankAccount: func(_ context.Context, account *domain.BankAccount) (*BankAccountRow, error) {
+					return &BankAccountRow{BankAccountID: "1", PartnerID: account.PartnerID, CompanyID: account.CompanyID}, nil
+				},
+			}}
+			_, err := s.CreateBankAccount(context.Background(), &tt.account)
+			assert.True(t, errors.Is(err, tt.wantErr), err)
+		})
+	}
+}
+
+func TestPartnerService_Delete(t *testing.T) {
+	tests := []struct {
+		name    string
+		deleted bool
+		err     error
+		wantErr error
+	}{
+		{name: "partner deleted", deleted: true},
+		{name: "partner doesn't exist", wantErr: ErrPartnerNotFound},
+		{name: "partner in use", err: ErrPartnerInUse, wantErr: ErrPartnerInUse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := PartnerService{Repository: &fakePartnerRepository{
+				deletePartner: func(context.Context, string, string) (bool, error) {
+					return tt.deleted, tt.err
+				},
+			}}
+			assert.Equal(t, tt.wantErr, s.Delete(context.Background(), "1", "1"))
+		})
+	}
+}
+
+func TestPartnerService_TenantIsolation(t *testing.T) {
+	ctx := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "1", Username: "foo"})
+	// The repository has no stubs, so the test panics if any method of another company reaches it.
+	s := PartnerService{Repository: &fakePartnerRepository{}}
+	account := &domain.BankAccount{PartnerID: "1", CompanyID: "2", BankName: "みずほ銀行", BranchName: "東京営業部", AccountType: domain.Ordinary, AccountNumber: "1234567", HolderNameKana: "カ）サンプル"}
+	partner := &domain.BusinessPartner{PartnerID: "1", CompanyID: "2", Name: "Vendor Inc.", Withholding: domain.NoWithholding}
+
+	_, err := s.List(ctx, "2")
+	assert.Equal(t, ErrForbidden, err)
+	_, err = s.Get(ctx, "2", "1")
+	assert.Equal(t, ErrForbidden, err)
+	_, err = s.Create(ctx, partner)
+	assert.Equal(t, ErrForbidden, err)
+	_, err = s.Update(ctx, partner)
+	assert.Equal(t, ErrForbidden, err)
+	assert.Equal(t, ErrForbidden, s.Delete(ctx, "2", "1"))
+	_, err = s.ListBankAccounts(ctx, "2", "1")
+	assert.Equal(t, ErrForbidden, err)
+	_, err = s.CreateBankAccount(ctx, account)
+	assert.Equal(t, ErrForbidden, err)
+	assert.Equal(t, ErrForbidden, s.DeleteBankAccount(ctx, "2", "1", "1"))
+}
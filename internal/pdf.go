@@ -0,0 +1,268 @@
+package internal
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-pdf/fpdf"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// defaultFont is M+ 1p, which covers the JIS level 1 and 2 kanji and the half-width katakana of bank account
+	// holder names. See fonts/LICENSE.
+	//go:embed fonts/mplus-1p-regular.ttf
+	defaultFont []byte
+
+	//go:embed templates/invoice.yaml
+	defaultPDFTemplate []byte
+)
+
+var ErrInvalidTemplate = errors.New("invalid pdf template")
+
+// PDFLabels are the captions printed next to each value of an invoice PDF.
+type PDFLabels struct {
+	InvoiceID          string `yaml:"invoice_id"`
+	IssueDate          string `yaml:"issue_date"`
+	RegistrationNumber string `yaml:"registration_number"`
+	Phone              string `yaml:"phone"`
+	Billed             string `yaml:"billed"`
+	Item               string `yaml:"item"`
+	Rate               string `yaml:"rate"`
+	Amount             string `yaml:"amount"`
+	InvoiceAmount      string `yaml:"invoice_amount"`
+	Fee                string `yaml:"fee"`
+	Tax                string `yaml:"tax"`
+	Taxable            string `yaml:"taxable"`
+	WithholdingTax     string `yaml:"withholding_tax"`
+	Total              string `yaml:"total"`
+	DueDate            string `yaml:"due_date"`
+	BankTransfer       string `yaml:"bank_transfer"`
+}
+
+// PDFTemplate is the wording and the font of invoice PDFs. The default is templates/invoice.yaml.
+type PDFTemplate struct {
+	Title     string `yaml:"title"`
+	Honorific string `yaml:"honorific"`
+	Message   string `yaml:"message"`
+	Notes     string `yaml:"notes"`
+	// FontFile is a TrueType font used instead of the embedded one.
+	FontFile     string                        `yaml:"font_file"`
+	Labels       PDFLabels                     `yaml:"labels"`
+	AccountTypes map[domain.AccountType]string `yaml:"account_types"`
+}
+
+func DefaultPDFTemplate() PDFTemplate {
+	var tmpl PDFTemplate
+	if err := yaml.Unmarshal(defaultPDFTemplate, &tmpl); err != nil {
+		panic(err)
+	}
+	return tmpl
+}
+
+// LoadPDFTemplate reads a YAML template over DefaultPDFTemplate, so the file only needs the keys to change.
+func LoadPDFTemplate(path string) (PDFTemplate, error) {
+	tmpl := DefaultPDFTemplate()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return tmpl, err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(b))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&tmpl); err != nil && !errors.Is(err, io.EOF) {
+		return tmpl, fmt.Errorf("%w: %v: %v", ErrInvalidTemplate, path, err)
+	}
+	return tmpl, nil
+}
+
+// InvoiceDocument is everything printed on an invoice PDF. The issuer is the business partner billing the
+// customer company.
+type InvoiceDocument struct {
+	Invoice      *domain.Invoice
+	Issuer       *domain.BusinessPartner
+	Customer     *domain.Company
+	BankAccounts []domain.BankAccount
+}
+
+// PDFRenderer renders invoices as A4 PDFs with a template.
+type PDFRenderer struct {
+	template PDFTemplate
+	font     []byte
+}
+
+func NewPDFRenderer(tmpl PDFTemplate) (*PDFRenderer, error) {
+	font := defaultFont
+	if tmpl.FontFile != "" {
+		b, err := os.ReadFile(tmpl.FontFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+		}
+		font = b
+	}
+	return &PDFRenderer{template: tmpl, font: font}, nil
+}
+
+const (
+	pdfFont   = "invoice"
+	pdfMargin = 20.0
+	pdfWidth  = 210.0 - 2*pdfMargin
+)
+
+// Render writes the PDF of doc to w. The output only depends on doc and the template, so that the same invoice
+// always renders the same bytes.
+func (r *PDFRenderer) Render(w io.Writer, doc *InvoiceDocument) error {
+	t := r.template
+	invoice := doc.Invoice
+	// The issue date stands for the creation date so that the output is reproducible.
+	created := invoice.IssueDate.UTC()
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetCatalogSort(true)
+	pdf.SetCreationDate(created)
+	pdf.SetModificationDate(created)
+	pdf.SetTitle(fmt.Sprintf("%s %s", t.Title, invoice.InvoiceID), true)
+	pdf.SetMargins(pdfMargin, pdfMargin, pdfMargin)
+	pdf.SetAutoPageBreak(true, pdfMargin)
+	pdf.AddUTF8FontFromBytes(pdfFont, "", r.font)
+	pdf.AddPage()
+
+	pdf.SetFont(pdfFont, "", 22)
+	pdf.CellFormat(pdfWidth, 14, t.Title, "", 1, "C", false, 0, "")
+	pdf.SetFont(pdfFont, "", 9)
+	pdf.CellFormat(pdfWidth, 5, fmt.Sprintf("%s: %s", t.Labels.InvoiceID, invoice.InvoiceID), "", 1, "R", false, 0, "")
+	pdf.CellFormat(pdfWidth, 5, fmt.Sprintf("%s: %s", t.Labels.IssueDate, formatJapaneseDate(invoice.IssueDate)), "", 1, "R", false, 0, "")
+
+	// The customer is printed on the left and the issuer on the right, side by side.
+	top := pdf.GetY() + 4
+	half := pdfWidth / 2
+	pdf.SetXY(pdfMargin, top)
+	pdf.SetFont(pdfFont, "", 14)
+	pdf.CellFormat(half-5, 8, fmt.Sprintf("%s %s", doc.Customer.Name, t.Honorific), "B", 1, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 9)
+	customerLines := addressLines(doc.Customer.PostalCode, doc.Customer.Address, "", "")
+	// The registration number is the one of the customer company registered as a qualified issuer
+	// (適格請求書発行事業者), so it's printed in the customer block, on qualified invoices only.
+	if invoice.RegistrationNumber != "" {
+		customerLines = append(customerLines, fmt.Sprintf("%s: %s", t.Labels.RegistrationNumber, invoice.RegistrationNumber))
+	}
+	for _, line := range customerLines {
+		pdf.CellFormat(half-5, 5, line, "", 1, "L", false, 0, "")
+	}
+	customerBottom := pdf.GetY()
+
+	pdf.SetXY(pdfMargin+half+5, top)
+	pdf.SetFont(pdfFont, "", 12)
+	pdf.CellFormat(half-5, 8, doc.Issuer.Name, "", 1, "L", false, 0, "")
+	pdf.SetFont(pdfFont, "", 9)
+	for _, line := range addressLines(doc.Issuer.PostalCode, doc.Issuer.Address, t.Labels.Phone, doc.Issuer.Phone) {
+		pdf.SetX(pdfMargin + half + 5)
+		pdf.CellFormat(half-5, 5, line, "", 1, "L", false, 0, "")
+	}
+	pdf.SetY(max(customerBottom, pdf.GetY()) + 6)
+
+	if t.Message != "" {
+		pdf.SetFont(pdfFont, "", 10)
+		pdf.CellFormat(pdfWidth, 6, t.Message, "", 1, "L", false, 0, "")
+	}
+	pdf.SetFont(pdfFont, "", 14)
+	pdf.CellFormat(40, 10, t.Labels.Billed, "B", 0, "L", false, 0, "")
+	pdf.CellFormat(60, 10, formatYen(invoice.Total)+"-", "B", 1, "R", false, 0, "")
+	pdf.SetFont(pdfFont, "", 10)
+	pdf.CellFormat(40, 7, t.Labels.DueDate, "", 0, "L", false, 0, "")
+	pdf.CellFormat(60, 7, formatJapaneseDate(invoice.DueDate), "", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	widths := []float64{pdfWidth - 80, 30, 50}
+	pdf.SetFillColor(230, 230, 230)
+	for i, header := range []string{t.Labels.Item, t.Labels.Rate, t.Labels.Amount} {
+		pdf.CellFormat(widths[i], 8, header, "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+	breakdown := [][]string{{t.Labels.InvoiceAmount, "", formatYen(invoice.Amount)}}
+	// Itemized invoices list their lines with the tax rates applied instead of the amount.
+	if len(invoice.Lines) > 0 {
+		breakdown = breakdown[:0]
+		rates := domain.Rates{TaxRate: invoice.TaxRate}
+		for _, line := range invoice.Lines {
+			item := fmt.Sprintf("%s (%s × %d)", line.Description, formatYen(line.UnitPrice), line.Quantity)
+			breakdown = append(breakdown, []string{item, rates.TaxRateOf(line.TaxCategory).Percent() + "%", formatYen(line.Amount())})
+		}
+	}
+	breakdown = append(breakdown, []string{t.Labels.Fee, invoice.FeeRate.Percent() + "%", formatYen(invoice.Fee)})
+	taxAmounts := invoice.TaxAmounts
+	if len(taxAmounts) == 0 {
+		taxAmounts = domain.TaxAmounts{{Rate: invoice.TaxRate, Taxable: invoice.Fee, Tax: invoice.Tax}}
+	}
+	for _, amount := range taxAmounts {
+		item := fmt.Sprintf("%s (%s %s)", t.Labels.Tax, t.Labels.Taxable, formatYen(amount.Taxable))
+		breakdown = append(breakdown, []string{item, amount.Rate.Percent() + "%", formatYen(amount.Tax)})
+	}
+	if invoice.WithholdingTax > 0 {
+		breakdown = append(breakdown, []string{t.Labels.WithholdingTax, "", formatYen(-invoice.WithholdingTax)})
+	}
+	for _, row := range breakdown {
+		pdf.CellFormat(widths[0], 8, row[0], "1", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 8, row[1], "1", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 8, row[2], "1", 1, "R", false, 0, "")
+	}
+	pdf.CellFormat(widths[0]+widths[1], 8, t.Labels.Total, "1", 0, "C", true, 0, "")
+	pdf.CellFormat(widths[2], 8, formatYen(invoice.Total), "1", 1, "R", false, 0, "")
+	pdf.Ln(6)
+
+	if len(doc.BankAccounts) > 0 {
+		pdf.SetFont(pdfFont, "", 11)
+		pdf.CellFormat(pdfWidth, 7, t.Labels.BankTransfer, "B", 1, "L", false, 0, "")
+		pdf.SetFont(pdfFont, "", 10)
+		for _, account := range doc.BankAccounts {
+			accountType, ok := t.AccountTypes[account.AccountType]
+			if !ok {
+				accountType = string(account.AccountType)
+			}
+			pdf.CellFormat(pdfWidth, 6, fmt.Sprintf("%s %s %s %s %s", account.BankName, account.BranchName, accountType, account.AccountNumber, account.HolderNameKana), "", 1, "L", false, 0, "")
+		}
+		pdf.Ln(4)
+	}
+	if t.Notes != "" {
+		pdf.SetFont(pdfFont, "", 9)
+		pdf.MultiCell(pdfWidth, 5, t.Notes, "", "L", false)
+	}
+	return pdf.Output(w)
+}
+
+// addressLines returns the lines of an address block, leaving out the empty values.
+func addressLines(postalCode, address, phoneLabel, phone string) []string {
+	lines := make([]string, 0, 3)
+	if postalCode != "" {
+		lines = append(lines, "〒"+postalCode)
+	}
+	if address != "" {
+		lines = append(lines, address)
+	}
+	if phone != "" {
+		lines = append(lines, fmt.Sprintf("%s: %s", phoneLabel, phone))
+	}
+	return lines
+}
+
+func formatJapaneseDate(t time.Time) string {
+	return t.Format("2006年1月2日")
+}
+
+// formatYen formats n with thousands separators, e.g. ¥10,440.
+func formatYen(n int) string {
+	s := strconv.Itoa(n)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return sign + "¥" + s
+}
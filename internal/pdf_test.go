@@ -0,0 +1,131 @@
+package internal
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+func testInvoiceDocument() *InvoiceDocument {
+	return &InvoiceDocument{
+		Invoice: &domain.Invoice{
+			InvoiceID: "1",
+			CompanyID: "1",
+			PartnerID: "1",
+			IssueDate: time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC),
+			Amount:    10000,
+			Fee:       400,
+			FeeRate:   domain.MustParseRate("0.04"),
+			Tax:       40,
+			TaxRate:   domain.MustParseRate("0.10"),
+			Total:     10440,
+			DueDate:   time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
+			Status:    domain.Unprocessed,
+			TaxAmounts: domain.TaxAmounts{
+				{Rate: domain.MustParseRate("0.10"), Taxable: 400, Tax: 40},
+			},
+			RegistrationNumber: "T7000012050002",
+		},
+		Issuer:   &domain.BusinessPartner{PartnerID: "1", CompanyID: "1", Name: "株式会社サンプル商事", Phone: "03-1234-5678", PostalCode: "150-0001", Address: "東京都渋谷区神宮前1-2-3"},
+		Customer: &domain.Company{CompanyID: "1", Name: "株式会社テスト", Representative: "山田太郎", PostalCode: "100-0001", Address: "東京都千代田区千代田1-1", RegistrationNumber: "T7000012050002", QualifiedIssuer: true},
+		BankAccounts: []domain.BankAccount{
+			{BankAccountID: "1", PartnerID: "1", CompanyID: "1", BankName: "みずほ銀行", BranchName: "渋谷支店", AccountType: domain.Ordinary, AccountNumber: "1234567", HolderNameKana: "ｶ)ｻﾝﾌﾟﾙｼｮｳｼﾞ"},
+		},
+	}
+}
+
+func TestPDFRenderer_Render(t *testing.T) {
+	custom, err := LoadPDFTemplate("testdata/pdf_template.yaml")
+	require.NoError(t, err)
+	withoutAccounts := testInvoiceDocument()
+	withoutAccounts.BankAccounts = nil
+	withoutAccounts.Invoice.TaxAmounts = nil
+	withoutAccounts.Invoice.RegistrationNumber = ""
+	itemized := testInvoiceDocument()
+	itemized.Invoice.Amount = 4152
+	itemized.Invoice.Fee = 166
+	itemized.Invoice.Tax = 368
+	itemized.Invoice.Total = 4686
+	itemized.Invoice.TaxAmounts = domain.TaxAmounts{
+		{Rate: domain.MustParseRate("0.10"), Taxable: 1166, Tax: 116},
+		{Rate: domain.ReducedTaxRate, Taxable: 3152, Tax: 252},
+	}
+	itemized.Invoice.Lines = []domain.InvoiceLine{
+		{Description: "お弁当", Quantity: 3, UnitPrice: 1001, TaxCategory: domain.ReducedTax},
+		{Description: "飲料", Quantity: 1, UnitPrice: 149, TaxCategory: domain.ReducedTax},
+		{Description: "配送料", Quantity: 1, UnitPrice: 1000, TaxCategory: domain.StandardTax},
+	}
+	withheld := testInvoiceDocument()
+	withheld.Invoice.WithholdingTax = 1021
+	withheld.Invoice.Total = 9419
+	tests := []struct {
+		name   string
+		tmpl   PDFTemplate
+		doc    *InvoiceDocument
+		golden string
+	}{
+		{name: "default template", tmpl: DefaultPDFTemplate(), doc: testInvoiceDocument(), golden: "invoice.pdf"},
+		{name: "custom template without bank accounts", tmpl: custom, doc: withoutAccounts, golden: "invoice_custom.pdf"},
+		{name: "itemized invoice", tmpl: DefaultPDFTemplate(), doc: itemized, golden: "invoice_lines.pdf"},
+		{name: "withholding tax", tmpl: DefaultPDFTemplate(), doc: withheld, golden: "invoice_withholding.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer, err := NewPDFRenderer(tt.tmpl)
+			require.NoError(t, err)
+			var got bytes.Buffer
+			require.NoError(t, renderer.Render(&got, tt.doc))
+			assert.True(t, bytes.HasPrefix(got.Bytes(), []byte("%PDF-")))
+
+			golden := filepath.Join("testdata", tt.golden)
+			if *update {
+				require.NoError(t, os.WriteFile(golden, got.Bytes(), 0o644))
+			}
+			want, err := os.ReadFile(golden)
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(want, got.Bytes()), "output differs from %v; run go test with -update to accept the change", golden)
+		})
+	}
+}
+
+func TestLoadPDFTemplate(t *testing.T) {
+	t.Run("keys left out keep the default", func(t *testing.T) {
+		got, err := LoadPDFTemplate("testdata/pdf_template.yaml")
+		require.NoError(t, err)
+		want := DefaultPDFTemplate()
+		assert.Equal(t, "御請求書", got.Title)
+		assert.Equal(t, "お振込期限", got.Labels.DueDate)
+		assert.Equal(t, want.Labels.Fee, got.Labels.Fee)
+		assert.Equal(t, want.AccountTypes, got.AccountTypes)
+	})
+
+	t.Run("unknown key is rejected", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "template.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("titel: 請求書\n"), 0o644))
+		_, err := LoadPDFTemplate(path)
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+	})
+
+	t.Run("missing font file is rejected", func(t *testing.T) {
+		tmpl := DefaultPDFTemplate()
+		tmpl.FontFile = filepath.Join(t.TempDir(), "missing.ttf")
+		_, err := NewPDFRenderer(tmpl)
+		assert.ErrorIs(t, err, ErrInvalidTemplate)
+	})
+}
+
+func TestFormatYen(t *testing.T) {
+	tests := map[int]string{0: "¥0", 999: "¥999", 1000: "¥1,000", 10440: "¥10,440", 1234567: "¥1,234,567", -1000: "-¥1,000"}
+	for n, want := range tests {
+		assert.Equal(t, want, formatYen(n))
+	}
+}
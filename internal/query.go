@@ -0,0 +1,263 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+var (
+	ErrInvalidQuery = errors.New("invalid query")
+	// errInvalidDueDate keeps the message GET /api/invoices has always returned for a malformed due_date.
+	errInvalidDueDate = errors.New("Can't convert duedate parameter to date")
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 500
+)
+
+// SortKey is a column invoices can be sorted by. The order is descending when Desc is set, and ties are
+// broken by invoice_id in the same direction so that cursors are stable.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// sortFields maps the sortable fields to their columns.
+var sortFields = map[string]string{
+	"due_date":   "due_date",
+	"issue_date": "issue_date",
+	"amount":     "amount",
+	"total":      "total",
+}
+
+func ParseSortKey(s string) (SortKey, error) {
+	key := SortKey{Field: strings.TrimPrefix(s, "-"), Desc: strings.HasPrefix(s, "-")}
+	if _, ok := sortFields[key.Field]; !ok {
+		return SortKey{}, fmt.Errorf("%w: 'sort' must be one of [due_date, issue_date, amount, total] optionally prefixed with '-', but got %v", ErrInvalidQuery, s)
+	}
+	return key, nil
+}
+
+func (k SortKey) String() string {
+	if k.Desc {
+		return "-" + k.Field
+	}
+	return k.Field
+}
+
+// Cursor points at the last invoice of a page. The next page starts right after it in the sort order.
+// Filter is the hash of the filters and sort of the query the cursor was issued for, as it's meaningless for others.
+type Cursor struct {
+	Sort      string `json:"s"`
+	Value     string `json:"v"`
+	InvoiceID int64  `json:"id"`
+	Filter    string `json:"f,omitempty"`
+}
+
+func (c *Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return &c, nil
+}
+
+// InvoiceQuery selects a page of the invoices of a company. Zero values of the filters mean "no filter".
+// Date ranges are inclusive on both ends.
+type InvoiceQuery struct {
+	CompanyID     string
+	PartnerID     string
+	Statuses      []domain.Status
+	IssueDateFrom time.Time
+	IssueDateTo   time.Time
+	DueDateFrom   time.Time
+	DueDateTo     time.Time
+	AmountMin     *int
+	AmountMax     *int
+	TotalMin      *int
+	TotalMax      *int
+	Sort          SortKey
+	Limit         int
+	After         *Cursor
+}
+
+// DueByQuery is the preset listing the unpaid invoices of the company due between today and dueDate.
+func DueByQuery(companyID string, today, dueDate time.Time) InvoiceQuery {
+	return InvoiceQuery{
+		CompanyID:   companyID,
+		Statuses:    []domain.Status{domain.Unprocessed, domain.Processing, domain.Error},
+		DueDateFrom: today,
+		DueDateTo:   dueDate,
+		Sort:        SortKey{Field: "due_date"},
+		Limit:       defaultLimit,
+	}
+}
+
+// cursorAfter returns the cursor pointing at invoice in the sort order of the query.
+func (q *InvoiceQuery) cursorAfter(invoice *domain.Invoice) (*Cursor, error) {
+	id, err := strconv.ParseInt(invoice.InvoiceID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	var value string
+	switch q.Sort.Field {
+	case "due_date":
+		value = invoice.DueDate.Format(time.DateOnly)
+	case "issue_date":
+		value = invoice.IssueDate.Format(time.DateOnly)
+	case "amount":
+		value = strconv.Itoa(invoice.Amount)
+	case "total":
+		value = strconv.Itoa(invoice.Total)
+	}
+	return &Cursor{Sort: q.Sort.String(), Value: value, InvoiceID: id, Filter: q.filterHash()}, nil
+}
+
+// filterHash identifies the filters and sort of the query regardless of the order of the statuses and the limit.
+func (q *InvoiceQuery) filterHash() string {
+	statuses := make([]string, 0, len(q.Statuses))
+	for _, status := range q.Statuses {
+		statuses = append(statuses, string(status))
+	}
+	slices.Sort(statuses)
+	optional := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+	h := sha256.New()
+	for _, v := range []string{
+		q.CompanyID,
+		q.PartnerID,
+		strings.Join(statuses, ","),
+		q.IssueDateFrom.Format(time.DateOnly),
+		q.IssueDateTo.Format(time.DateOnly),
+		q.DueDateFrom.Format(time.DateOnly),
+		q.DueDateTo.Format(time.DateOnly),
+		optional(q.AmountMin),
+		optional(q.AmountMax),
+		optional(q.TotalMin),
+		optional(q.TotalMax),
+		q.Sort.String(),
+	} {
+		fmt.Fprintf(h, "%s\n", v)
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:12])
+}
+
+// ParseInvoiceQuery builds the query of GET /api/invoices from its query parameters.
+// due_date selects the DueByQuery preset as of today, and the other filters narrow it down further.
+func ParseInvoiceQuery(values url.Values, companyID string, today time.Time) (InvoiceQuery, error) {
+	q := InvoiceQuery{CompanyID: companyID, Sort: SortKey{Field: "due_date"}, Limit: defaultLimit}
+	if values.Has("due_date") {
+		dueDate, err := time.Parse(time.DateOnly, values.Get("due_date"))
+		if err != nil {
+			return InvoiceQuery{}, fmt.Errorf("%w: %w", ErrInvalidQuery, errInvalidDueDate)
+		}
+		q = DueByQuery(companyID, today, dueDate)
+	}
+
+	if statuses := values["status"]; len(statuses) > 0 {
+		q.Statuses = nil
+		for _, v := range statuses {
+			for _, s := range strings.Split(v, ",") {
+				status := domain.Status(s)
+				if !status.Valid() {
+					return InvoiceQuery{}, fmt.Errorf("%w: 'status' must be one of [unprocessed, processing, paid, error], but got %v", ErrInvalidQuery, s)
+				}
+				q.Statuses = append(q.Statuses, status)
+			}
+		}
+	}
+	q.PartnerID = values.Get("partner_id")
+
+	dates := []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"issue_date_from", &q.IssueDateFrom},
+		{"issue_date_to", &q.IssueDateTo},
+		{"due_date_from", &q.DueDateFrom},
+		{"due_date_to", &q.DueDateTo},
+	}
+	for _, d := range dates {
+		if !values.Has(d.name) {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, values.Get(d.name))
+		if err != nil {
+			return InvoiceQuery{}, fmt.Errorf("%w: '%v' must be formatted as YYYY-MM-DD, but got %v", ErrInvalidQuery, d.name, values.Get(d.name))
+		}
+		*d.dst = t
+	}
+
+	amounts := []struct {
+		name string
+		dst  **int
+	}{
+		{"amount_min", &q.AmountMin},
+		{"amount_max", &q.AmountMax},
+		{"total_min", &q.TotalMin},
+		{"total_max", &q.TotalMax},
+	}
+	for _, a := range amounts {
+		if !values.Has(a.name) {
+			continue
+		}
+		n, err := strconv.Atoi(values.Get(a.name))
+		if err != nil {
+			return InvoiceQuery{}, fmt.Errorf("%w: '%v' must be an integer, but got %v", ErrInvalidQuery, a.name, values.Get(a.name))
+		}
+		*a.dst = &n
+	}
+
+	if values.Has("sort") {
+		sort, err := ParseSortKey(values.Get("sort"))
+		if err != nil {
+			return InvoiceQuery{}, err
+		}
+		q.Sort = sort
+	}
+	if values.Has("limit") {
+		limit, err := strconv.Atoi(values.Get("limit"))
+		if err != nil || limit < 1 || limit > maxLimit {
+			return InvoiceQuery{}, fmt.Errorf("%w: 'limit' must be an integer between 1 and %d, but got %v", ErrInvalidQuery, maxLimit, values.Get("limit"))
+		}
+		q.Limit = limit
+	}
+	if values.Has("cursor") {
+		cursor, err := DecodeCursor(values.Get("cursor"))
+		if err != nil {
+			return InvoiceQuery{}, err
+		}
+		if cursor.Sort != q.Sort.String() {
+			return InvoiceQuery{}, fmt.Errorf("%w: cursor was issued for sort=%v, but got sort=%v", ErrInvalidQuery, cursor.Sort, q.Sort)
+		}
+		if cursor.Filter != q.filterHash() {
+			return InvoiceQuery{}, fmt.Errorf("%w: cursor was issued for other filters", ErrInvalidQuery)
+		}
+		q.After = cursor
+	}
+	return q, nil
+}
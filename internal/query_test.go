@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvoiceQuery(t *testing.T) {
+	today := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	thousand := 1000
+	filtered := InvoiceQuery{
+		CompanyID:     "1",
+		Statuses:      []domain.Status{domain.Paid, domain.Error, domain.Processing},
+		IssueDateFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		IssueDateTo:   time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		AmountMin:     &thousand,
+		TotalMax:      &thousand,
+		Sort:          SortKey{Field: "amount", Desc: true},
+		Limit:         100,
+	}
+	after := &Cursor{Sort: "-amount", Value: "5000", InvoiceID: 3, Filter: filtered.filterHash()}
+	cursor := after.Encode()
+	tests := []struct {
+		name    string
+		query   string
+		want    InvoiceQuery
+		wantErr bool
+	}{
+		{
+			name:  "no parameters",
+			query: "",
+			want:  InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "due_date"}, Limit: 100},
+		},
+		{
+			name:  "due_date preset",
+			query: "due_date=2024-10-31",
+			want:  DueByQuery("1", today, time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC)),
+		},
+		{
+			name:  "due_date preset narrowed by partner",
+			query: "due_date=2024-10-31&partner_id=2&limit=10",
+			want: InvoiceQuery{
+				CompanyID:   "1",
+				PartnerID:   "2",
+				Statuses:    []domain.Status{domain.Unprocessed, domain.Processing, domain.Error},
+				DueDateFrom: today,
+				DueDateTo:   time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC),
+				Sort:        SortKey{Field: "due_date"},
+				Limit:       10,
+			},
+		},
+		{
+			name:  "filters",
+			query: "status=paid,error&status=processing&issue_date_from=2024-01-01&issue_date_to=2024-06-30&amount_min=1000&total_max=1000&sort=-amount&cursor=" + cursor,
+			want: InvoiceQuery{
+				CompanyID:     "1",
+				Statuses:      []domain.Status{domain.Paid, domain.Error, domain.Processing},
+				IssueDateFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				IssueDateTo:   time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+				AmountMin:     &thousand,
+				TotalMax:      &thousand,
+				Sort:          SortKey{Field: "amount", Desc: true},
+				Limit:         100,
+				After:         after,
+			},
+		},
+		{name: "invalid due_date", query: "due_date=INVALID", wantErr: true},
+		{name: "invalid status", query: "status=unknown", wantErr: true},
+		{name: "invalid date range", query: "due_date_from=2024/01/01", wantErr: true},
+		{name: "invalid amount", query: "amount_max=many", wantErr: true},
+		{name: "invalid sort", query: "sort=status", wantErr: true},
+		{name: "limit too small", query: "limit=0", wantErr: true},
+		{name: "limit too large", query: "limit=501", wantErr: true},
+		{name: "malformed cursor", query: "cursor=INVALID", wantErr: true},
+		{name: "cursor of another sort", query: "sort=amount&cursor=" + cursor, wantErr: true},
+		{name: "cursor of other filters", query: "status=paid&sort=-amount&cursor=" + cursor, wantErr: true},
+		{name: "cursor without filters", query: "cursor=" + (&Cursor{Sort: "due_date", Value: "2024-10-31", InvoiceID: 3}).Encode(), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			require.NoError(t, err)
+			got, err := ParseInvoiceQuery(values, "1", today)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidQuery)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseInvoiceQuery_InvalidDueDate(t *testing.T) {
+	_, err := ParseInvoiceQuery(url.Values{"due_date": {"INVALID"}}, "1", time.Now())
+	assert.ErrorIs(t, err, errInvalidDueDate)
+}
+
+func TestInvoiceQuery_FilterHash(t *testing.T) {
+	thousand := 1000
+	q := InvoiceQuery{CompanyID: "1", Statuses: []domain.Status{domain.Paid, domain.Error}, AmountMin: &thousand, Sort: SortKey{Field: "total"}, Limit: 100}
+	hash := q.filterHash()
+
+	same := q
+	same.Statuses = []domain.Status{domain.Error, domain.Paid}
+	same.Limit = 10
+	same.After = &Cursor{Sort: "total", Value: "10440", InvoiceID: 1}
+	assert.Equal(t, hash, same.filterHash(), "the order of the statuses, the limit and the cursor shouldn't matter")
+
+	others := []func(q *InvoiceQuery){
+		func(q *InvoiceQuery) { q.CompanyID = "2" },
+		func(q *InvoiceQuery) { q.PartnerID = "1" },
+		func(q *InvoiceQuery) { q.Statuses = []domain.Status{domain.Paid} },
+		func(q *InvoiceQuery) { q.DueDateTo = time.Date(2024, 10, 31, 0, 0, 0, 0, time.UTC) },
+		func(q *InvoiceQuery) { q.AmountMin = nil },
+		func(q *InvoiceQuery) { q.AmountMax = &thousand },
+		func(q *InvoiceQuery) { q.Sort.Desc = true },
+	}
+	for i, change := range others {
+		other := q
+		change(&other)
+		assert.NotEqual(t, hash, other.filterHash(), "change %d should make other filters", i)
+	}
+}
+
+func TestCursor(t *testing.T) {
+	c := &Cursor{Sort: "due_date", Value: "2024-10-31", InvoiceID: 42, Filter: "FILTER"}
+	got, err := DecodeCursor(c.Encode())
+	require.NoError(t, err)
+	assert.Equal(t, c, got)
+
+	_, err = DecodeCursor("bm90IGpzb24")
+	assert.ErrorIs(t, err, ErrInvalidQuery)
+}
@@ -0,0 +1,39 @@
+package internal
+
+import (
+	"context"
+	"time"
+)
+
+var _ Aggregator = (*MySQL)(nil)
+
+// Aggregate sums up the invoices by the grouping of q. The grouping must be validated beforehand.
+func (s *MySQL) Aggregate(ctx context.Context, q ReportQuery) ([]ReportRow, error) {
+	key := reportGroups[q.GroupBy]
+	query := "SELECT " + key + " AS report_key, COUNT(*), SUM(amount), SUM(fee), SUM(tax), SUM(withholding_tax), SUM(total) FROM invoice WHERE company_id = ?"
+	args := []any{q.CompanyID}
+	if !q.From.IsZero() {
+		query += " AND issue_date >= ?"
+		args = append(args, q.From.Format(time.DateOnly))
+	}
+	if !q.To.IsZero() {
+		query += " AND issue_date <= ?"
+		args = append(args, q.To.Format(time.DateOnly))
+	}
+	query += " GROUP BY report_key ORDER BY report_key;"
+
+	rows, err := s.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := make([]ReportRow, 0)
+	for rows.Next() {
+		var row ReportRow
+		if err := rows.Scan(&row.Key, &row.Count, &row.Amount, &row.Fee, &row.Tax, &row.WithholdingTax, &row.Total); err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+	return results, rows.Err()
+}
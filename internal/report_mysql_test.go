@@ -0,0 +1,52 @@
+package internal
+
+import (
+	"context"
+	"database/sql/driver"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQL_Aggregate(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    ReportQuery
+		wantSQL  string
+		wantArgs []driver.Value
+	}{
+		{
+			name:     "by month",
+			query:    ReportQuery{CompanyID: "1", GroupBy: "month"},
+			wantSQL:  "SELECT DATE_FORMAT(issue_date, '%Y-%m') AS report_key, COUNT(*), SUM(amount), SUM(fee), SUM(tax), SUM(withholding_tax), SUM(total) FROM invoice WHERE company_id = ? GROUP BY report_key ORDER BY report_key;",
+			wantArgs: []driver.Value{"1"},
+		},
+		{
+			name:     "by status within range",
+			query:    ReportQuery{CompanyID: "1", GroupBy: "status", From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)},
+			wantSQL:  "SELECT status AS report_key, COUNT(*), SUM(amount), SUM(fee), SUM(tax), SUM(withholding_tax), SUM(total) FROM invoice WHERE company_id = ? AND issue_date >= ? AND issue_date <= ? GROUP BY report_key ORDER BY report_key;",
+			wantArgs: []driver.Value{"1", "2024-01-01", "2024-12-31"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(tt.wantSQL)).WithArgs(tt.wantArgs...).WillReturnRows(
+				sqlmock.NewRows([]string{"report_key", "count", "amount", "fee", "tax", "withholding_tax", "total"}).
+					AddRow("2024-10", 2, "15000", "600", "60", "0", "15660"))
+
+			s := &MySQL{DB: db}
+			got, err := s.Aggregate(context.Background(), tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, []ReportRow{{Key: "2024-10", Count: 2, Amount: 15000, Fee: 600, Tax: 60, Total: 15660}}, got)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReportQuery aggregates the invoices of a company issued between From and To, both inclusive.
+// GroupBy is one of month (of issue_date), status and partner.
+type ReportQuery struct {
+	CompanyID string
+	From      time.Time
+	To        time.Time
+	GroupBy   string
+}
+
+// ReportRow is the sum of the invoices sharing Key.
+type ReportRow struct {
+	Key            string
+	Count          int
+	Amount         int
+	Fee            int
+	Tax            int
+	WithholdingTax int
+	Total          int
+}
+
+// reportGroups maps the groupings to the columns they aggregate by.
+var reportGroups = map[string]string{
+	"month":   "DATE_FORMAT(issue_date, '%Y-%m')",
+	"status":  "status",
+	"partner": "partner_id",
+}
+
+type Aggregator interface {
+	Aggregate(context.Context, ReportQuery) ([]ReportRow, error)
+}
+
+type AggregatorFunc func(context.Context, ReportQuery) ([]ReportRow, error)
+
+func (f AggregatorFunc) Aggregate(ctx context.Context, q ReportQuery) ([]ReportRow, error) {
+	return f(ctx, q)
+}
+
+type ReportService struct {
+	Aggregator Aggregator
+}
+
+// Report returns the sums of the invoices matching q ordered by the key.
+// ErrForbidden is returned when the authenticated user belongs to a company other than q.CompanyID.
+func (s *ReportService) Report(ctx context.Context, q ReportQuery) ([]ReportRow, error) {
+	if err := authorizeCompany(ctx, q.CompanyID); err != nil {
+		return nil, err
+	}
+	if _, ok := reportGroups[q.GroupBy]; !ok {
+		return nil, fmt.Errorf("%w: 'group_by' must be one of [month, status, partner], but got %v", ErrInvalidQuery, q.GroupBy)
+	}
+	if !q.From.IsZero() && !q.To.IsZero() && q.From.After(q.To) {
+		return nil, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidQuery)
+	}
+	rows, err := s.Aggregator.Aggregate(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("report service error: %w", err)
+	}
+	return rows, nil
+}
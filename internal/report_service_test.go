@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportService_Report(t *testing.T) {
+	rows := []ReportRow{{Key: "2024-10", Count: 2, Amount: 15000, Fee: 600, Tax: 60, Total: 15660}}
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		query   ReportQuery
+		err     error
+		want    []ReportRow
+		wantErr error
+	}{
+		{
+			name:  "no error",
+			ctx:   context.Background(),
+			query: ReportQuery{CompanyID: "1", GroupBy: "month"},
+			want:  rows,
+		},
+		{
+			name:    "unknown grouping",
+			ctx:     context.Background(),
+			query:   ReportQuery{CompanyID: "1", GroupBy: "year"},
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "reversed range",
+			ctx:     context.Background(),
+			query:   ReportQuery{CompanyID: "1", GroupBy: "status", From: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			wantErr: ErrInvalidQuery,
+		},
+		{
+			name:    "another company",
+			ctx:     WithUser(context.Background(), &domain.User{CompanyID: "2"}),
+			query:   ReportQuery{CompanyID: "1", GroupBy: "month"},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "aggregator error",
+			ctx:     context.Background(),
+			query:   ReportQuery{CompanyID: "1", GroupBy: "partner"},
+			err:     errors.New("this is test"),
+			wantErr: errors.New("report service error: this is test"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := ReportService{Aggregator: AggregatorFunc(func(context.Context, ReportQuery) ([]ReportRow, error) {
+				return rows, tt.err
+			})}
+			got, err := s.Report(tt.ctx, tt.query)
+			if tt.wantErr != nil {
+				if errors.Is(err, tt.wantErr) {
+					return
+				}
+				assert.EqualError(t, err, tt.wantErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
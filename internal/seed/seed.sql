@@ -0,0 +1,19 @@
+-- Sample data for development. Rows have fixed IDs and existing ones are kept, so seeding twice is harmless.
+INSERT IGNORE INTO companies (company_id, name, representative, phone, postal_code, address, registration_number, qualified_issuer) VALUES (1, "株式会社サンプル", "山田太郎", "03-1234-5678", "100-0001", "東京都千代田区千代田1-1", "T7000012050002", TRUE);
+INSERT IGNORE INTO companies (company_id, name, representative, phone, postal_code, address) VALUES (2, "合同会社テスト", "佐藤花子", "06-1234-5678", "530-0001", "大阪府大阪市北区梅田1-1");
+
+-- The password of foo is "password".
+INSERT IGNORE INTO users (user_id, company_id, username, password_hash, role) VALUES (1, 1, "foo", "$2a$10$rNf1hRKWH5kR/J9.dGGUoeIzSm2Nt4kh9zEd6D81.8EmVmLGoD2Tq", "admin");
+
+INSERT IGNORE INTO business_partners (partner_id, company_id, name, phone, postal_code, address) VALUES (1, 1, "株式会社ベンダー", "03-9876-5432", "150-0002", "東京都渋谷区渋谷2-2");
+INSERT IGNORE INTO business_partners (partner_id, company_id, name, phone, postal_code, address) VALUES (2, 2, "有限会社サプライ", "06-9876-5432", "542-0081", "大阪府大阪市中央区南船場3-3");
+
+INSERT IGNORE INTO partner_bank_accounts (bank_account_id, partner_id, company_id, bank_name, branch_name, account_type, account_number, holder_name_kana) VALUES (1, 1, 1, "みずほ銀行", "渋谷支店", "ordinary", "1234567", "カ）ベンダー");
+INSERT IGNORE INTO partner_bank_accounts (bank_account_id, partner_id, company_id, bank_name, branch_name, account_type, account_number, holder_name_kana) VALUES (2, 2, 2, "三井住友銀行", "心斎橋支店", "checking", "7654321", "ユ）サプライ");
+
+INSERT IGNORE INTO company_fees (company_fee_id, company_id, fee_rate, tax_rate, rounding_mode, valid_from, valid_to) VALUES (1, 2, 0.03, NULL, "half_up", "2024-01-01", NULL);
+
+INSERT IGNORE INTO invoice (invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status, tax_amounts, registration_number) VALUES (1, 1, 1, "2024-11-01", 10000, 400, 0.04, 40, 0.10, 10440, "2024-12-01", "unprocessed", '[{"rate": 0.10, "taxable": 400, "tax": 40}]', "T7000012050002");
+INSERT IGNORE INTO invoice (invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status, tax_amounts, registration_number) VALUES (2, 1, 1, "2024-10-01", 5000, 200, 0.04, 20, 0.10, 5220, "2024-11-01", "processing", '[{"rate": 0.10, "taxable": 200, "tax": 20}]', "T7000012050002");
+INSERT IGNORE INTO invoice (invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status, tax_amounts, registration_number) VALUES (3, 1, 1, "2024-07-01", 20000, 800, 0.04, 80, 0.10, 20880, "2024-08-01", "paid", '[{"rate": 0.10, "taxable": 800, "tax": 80}]', "T7000012050002");
+INSERT IGNORE INTO invoice (invoice_id, company_id, partner_id, issue_date, amount, fee, fee_rate, tax, tax_rate, total, due_date, status, tax_amounts, registration_number) VALUES (4, 2, 2, "2024-04-01", 5000, 200, 0.04, 20, 0.10, 5220, "2024-11-01", "error", '[{"rate": 0.10, "taxable": 200, "tax": 20}]', "");
@@ -2,50 +2,126 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"time"
 
+	"github.com/Ryuheeeei/super-invoicer/internal/calendar"
 	"github.com/Ryuheeeei/super-invoicer/internal/domain"
 )
 
+var (
+	ErrNotFound  = errors.New("invoice not found")
+	ErrForbidden = errors.New("invoice belongs to another company")
+	ErrConflict  = errors.New("invoice was modified concurrently")
+)
+
 type Selector interface {
-	Select(context.Context, string, time.Time) (*Rows, error)
+	Select(context.Context, InvoiceQuery) (*Rows, error)
 }
 
-type SelectorFunc func(context.Context, string, time.Time) (*Rows, error)
+type SelectorFunc func(context.Context, InvoiceQuery) (*Rows, error)
 
-func (f SelectorFunc) Select(ctx context.Context, s string, t time.Time) (*Rows, error) {
-	return f(ctx, s, t)
+func (f SelectorFunc) Select(ctx context.Context, q InvoiceQuery) (*Rows, error) {
+	return f(ctx, q)
+}
+
+type EachSelector interface {
+	SelectEach(context.Context, InvoiceQuery, func(*Row) error) error
+}
+
+type EachSelectorFunc func(context.Context, InvoiceQuery, func(*Row) error) error
+
+func (f EachSelectorFunc) SelectEach(ctx context.Context, q InvoiceQuery, fn func(*Row) error) error {
+	return f(ctx, q, fn)
 }
 
 type FindService struct {
-	Selector Selector
+	Selector     Selector
+	IDSelector   IDSelector
+	EachSelector EachSelector
 }
 
-func (s *FindService) Find(ctx context.Context, companyID string, dueDate time.Time) ([]domain.Invoice, error) {
-	rows, err := s.Selector.Select(ctx, companyID, dueDate)
+// InvoicePage is a page of invoices. NextCursor is nil on the last page.
+type InvoicePage struct {
+	Invoices   []domain.Invoice
+	NextCursor *Cursor
+}
+
+// Find returns the page of the invoices matching q.
+// ErrForbidden is returned when the authenticated user belongs to a company other than q.CompanyID.
+func (s *FindService) Find(ctx context.Context, q InvoiceQuery) (*InvoicePage, error) {
+	if err := authorizeCompany(ctx, q.CompanyID); err != nil {
+		return nil, err
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultLimit
+	}
+	// Fetch one more row than requested to tell whether there's a next page.
+	limit := q.Limit
+	q.Limit++
+	rows, err := s.Selector.Select(ctx, q)
 	if err != nil {
 		return nil, fmt.Errorf("find service error: %w", err)
 	}
-	invoices := make([]domain.Invoice, 0)
-	for _, row := range rows.Rows {
-		invoices = append(invoices,
-			domain.Invoice{
-				InvoiceID: row.InvoiceID,
-				CompanyID: row.CompanyID,
-				IssueDate: row.IssueDate,
-				Amount:    row.Amount,
-				Fee:       row.Fee,
-				FeeRate:   row.FeeRate,
-				Tax:       row.Tax,
-				TaxRate:   row.TaxRate,
-				Total:     row.Total,
-				DueDate:   row.DueDate,
-				Status:    domain.Status(row.Status),
-			},
-		)
-	}
-	return invoices, nil
+	page := &InvoicePage{Invoices: make([]domain.Invoice, 0)}
+	for i, row := range rows.Rows {
+		if i == limit {
+			page.NextCursor, err = q.cursorAfter(&page.Invoices[limit-1])
+			if err != nil {
+				return nil, fmt.Errorf("cursor error: %w", err)
+			}
+			break
+		}
+		page.Invoices = append(page.Invoices, *row.invoice())
+	}
+	return page, nil
+}
+
+// Export calls f with each invoice matching q in its sort order as it's read, instead of returning a page.
+// Every matching invoice is exported when q.Limit is 0. ErrForbidden is returned like Find before f is called.
+func (s *FindService) Export(ctx context.Context, q InvoiceQuery, f func(*domain.Invoice) error) error {
+	if err := authorizeCompany(ctx, q.CompanyID); err != nil {
+		return err
+	}
+	err := s.EachSelector.SelectEach(ctx, q, func(row *Row) error {
+		return f(row.invoice())
+	})
+	if err != nil {
+		return fmt.Errorf("export service error: %w", err)
+	}
+	return nil
+}
+
+type IDSelector interface {
+	SelectByID(context.Context, string) (*Row, error)
+}
+
+type IDSelectorFunc func(context.Context, string) (*Row, error)
+
+func (f IDSelectorFunc) SelectByID(ctx context.Context, s string) (*Row, error) {
+	return f(ctx, s)
+}
+
+// FindByID returns the invoice identified by invoiceID.
+// ErrNotFound is returned when no such invoice exists, and ErrForbidden when it belongs to a company other than companyID
+// or companyID isn't the company of the authenticated user.
+func (s *FindService) FindByID(ctx context.Context, companyID, invoiceID string) (*domain.Invoice, error) {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	row, err := s.IDSelector.SelectByID(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("find service error: %w", err)
+	}
+	if row == nil {
+		return nil, ErrNotFound
+	}
+	if row.CompanyID != companyID {
+		return nil, ErrForbidden
+	}
+	return row.invoice(), nil
 }
 
 type Inserter interface {
@@ -58,27 +134,203 @@ func (f InserterFunc) Insert(ctx context.Context, companyID string, invoice *dom
 	return f(ctx, companyID, invoice)
 }
 
+type RateSelector interface {
+	SelectRates(context.Context, string, time.Time) (*RateRow, error)
+}
+
+type RateSelectorFunc func(context.Context, string, time.Time) (*RateRow, error)
+
+func (f RateSelectorFunc) SelectRates(ctx context.Context, s string, t time.Time) (*RateRow, error) {
+	return f(ctx, s, t)
+}
+
 type RegisterService struct {
-	Inserter Inserter
+	Inserter        Inserter
+	BatchInserter   BatchInserter
+	RateSelector    RateSelector
+	CompanySelector CompanySelector
+	PartnerSelector PartnerSelector
+	// Calendar tells the bank holidays the due dates are moved from. Nil means calendar.Default.
+	Calendar *calendar.Calendar
+	// Logger warns of the due dates out of the years of the Calendar. Nil means slog.Default.
+	Logger *slog.Logger
 }
 
-func (s *RegisterService) Register(ctx context.Context, companyID string, issueDate time.Time, amount int, dueDate time.Time, status string) (*domain.Invoice, error) {
-	invoice := domain.NewInvoice(issueDate, dueDate, amount, status)
+// adjustDueDate moves dueDate falling on a bank holiday to a business day with the policy of the company,
+// because the invoice can't be paid by bank transfer on it. The holidays of the years out of the calendar are
+// unknown, so only weekends and the year-end holidays are skipped then, with a warning to update the holidays.
+func (s *RegisterService) adjustDueDate(ctx context.Context, dueDate time.Time, policy domain.DueDatePolicy) time.Time {
+	c := s.Calendar
+	if c == nil {
+		c = calendar.Default()
+	}
+	adjusted := c.BusinessDayOnOrAfter(dueDate)
+	if policy == domain.PreviousBusinessDay {
+		adjusted = c.BusinessDayOnOrBefore(dueDate)
+	}
+	if !c.Covers(dueDate) || !c.Covers(adjusted) {
+		logger := s.Logger
+		if logger == nil {
+			logger = slog.Default()
+		}
+		first, last := c.Years()
+		logger.WarnContext(ctx, "Due date is out of the years of the holidays, so only weekends and the year-end holidays are skipped. Update the holidays",
+			"due_date", dueDate.Format(time.DateOnly), "adjusted_due_date", adjusted.Format(time.DateOnly), "first_year", first, "last_year", last)
+	}
+	return adjusted
+}
+
+// rates resolves the rates contracted by the company on date, falling back to domain.DefaultRates.
+// The resolved rates are stored with the invoice, so later contract changes don't affect it.
+func (s *RegisterService) rates(ctx context.Context, companyID string, date time.Time) (domain.Rates, error) {
+	row, err := s.RateSelector.SelectRates(ctx, companyID, date)
+	if err != nil {
+		return domain.Rates{}, err
+	}
+	rates := domain.DefaultRates
+	if row == nil {
+		return rates, nil
+	}
+	rates.FeeRate = row.FeeRate
+	rates.Rounding = row.Rounding
+	if row.TaxRate != nil {
+		rates.TaxRate = *row.TaxRate
+	}
+	return rates, nil
+}
+
+// Register creates an invoice for the company to pay the business partner. The amount of an itemized invoice is
+// derived from its lines, and amount is ignored. The due date is moved to a business day by the DueDatePolicy of
+// the company.
+// ErrForbidden is returned when the authenticated user belongs to another company, ErrCompanyNotFound when the
+// company doesn't exist, and ErrPartnerNotFound when the partner doesn't exist or belongs to another company.
+// The invoices of a company that is a qualified issuer are qualified invoices, and domain.ErrNonQualifiedInvoice
+// is returned when the invoice can't be one.
+func (s *RegisterService) Register(ctx context.Context, companyID, partnerID string, issueDate time.Time, amount int, lines []domain.InvoiceLine, dueDate time.Time, status string) (*domain.Invoice, error) {
+	invoice, err := s.prepare(ctx, &invoiceLookup{}, companyID, partnerID, issueDate, amount, lines, dueDate, status)
+	if err != nil {
+		return nil, err
+	}
 	row, err := s.Inserter.Insert(ctx, companyID, invoice)
 	if err != nil {
 		return nil, fmt.Errorf("insert error: %w", err)
 	}
-	return &domain.Invoice{
-		InvoiceID: row.InvoiceID,
-		CompanyID: row.CompanyID,
-		IssueDate: row.IssueDate,
-		Amount:    row.Amount,
-		Fee:       row.Fee,
-		FeeRate:   row.FeeRate,
-		Tax:       row.Tax,
-		TaxRate:   row.TaxRate,
-		DueDate:   row.DueDate,
-		Total:     row.Total,
-		Status:    domain.Status(row.Status),
-	}, nil
+	return row.invoice(), nil
+}
+
+// invoiceLookup memoizes the rows read while preparing invoices, so that a batch reads each company,
+// partner and contract once.
+type invoiceLookup struct {
+	companies map[string]*CompanyRow
+	partners  map[string]*PartnerRow
+	rates     map[string]domain.Rates
+}
+
+// prepare checks the company and the partner and computes the invoice with the contracted rates.
+// It returns the same errors as Register.
+func (s *RegisterService) prepare(ctx context.Context, lookup *invoiceLookup, companyID, partnerID string, issueDate time.Time, amount int, lines []domain.InvoiceLine, dueDate time.Time, status string) (*domain.Invoice, error) {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	company, ok := lookup.companies[companyID]
+	if !ok {
+		var err error
+		company, err = s.CompanySelector.SelectCompany(ctx, companyID)
+		if err != nil {
+			return nil, fmt.Errorf("company error: %w", err)
+		}
+		if lookup.companies == nil {
+			lookup.companies = make(map[string]*CompanyRow)
+		}
+		lookup.companies[companyID] = company
+	}
+	if company == nil {
+		return nil, ErrCompanyNotFound
+	}
+	partner, ok := lookup.partners[partnerID]
+	if !ok {
+		var err error
+		partner, err = s.PartnerSelector.SelectPartner(ctx, partnerID)
+		if err != nil {
+			return nil, fmt.Errorf("partner error: %w", err)
+		}
+		if lookup.partners == nil {
+			lookup.partners = make(map[string]*PartnerRow)
+		}
+		lookup.partners[partnerID] = partner
+	}
+	if partner == nil || partner.CompanyID != companyID {
+		return nil, ErrPartnerNotFound
+	}
+	ratesKey := companyID + "/" + issueDate.Format(time.DateOnly)
+	rates, ok := lookup.rates[ratesKey]
+	if !ok {
+		var err error
+		rates, err = s.rates(ctx, companyID, issueDate)
+		if err != nil {
+			return nil, fmt.Errorf("rate error: %w", err)
+		}
+		if lookup.rates == nil {
+			lookup.rates = make(map[string]domain.Rates)
+		}
+		lookup.rates[ratesKey] = rates
+	}
+	dueDate = s.adjustDueDate(ctx, dueDate, domain.DueDatePolicy(company.DueDatePolicy))
+	invoice := domain.NewInvoice(issueDate, dueDate, amount, lines, status, rates)
+	invoice.PartnerID = partnerID
+	invoice.Withhold(domain.WithholdingCategory(partner.Withholding))
+	if company.QualifiedIssuer {
+		if err := invoice.Qualify(company.RegistrationNumber); err != nil {
+			return nil, err
+		}
+	}
+	return invoice, nil
+}
+
+type StatusUpdater interface {
+	UpdateStatus(context.Context, string, domain.Status, domain.Status) (bool, error)
+}
+
+type StatusUpdaterFunc func(context.Context, string, domain.Status, domain.Status) (bool, error)
+
+func (f StatusUpdaterFunc) UpdateStatus(ctx context.Context, s string, from, to domain.Status) (bool, error) {
+	return f(ctx, s, from, to)
+}
+
+type StatusService struct {
+	IDSelector    IDSelector
+	StatusUpdater StatusUpdater
+}
+
+// ChangeStatus moves the invoice to status if the domain state machine allows it.
+// An error wrapping domain.ErrInvalidTransition is returned for illegal transitions, and ErrConflict when
+// the invoice changed between reading and updating it.
+func (s *StatusService) ChangeStatus(ctx context.Context, companyID, invoiceID string, status domain.Status) (*domain.Invoice, error) {
+	if err := authorizeCompany(ctx, companyID); err != nil {
+		return nil, err
+	}
+	row, err := s.IDSelector.SelectByID(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("find service error: %w", err)
+	}
+	if row == nil {
+		return nil, ErrNotFound
+	}
+	if row.CompanyID != companyID {
+		return nil, ErrForbidden
+	}
+	invoice := row.invoice()
+	next, err := invoice.Status.TransitionTo(status)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.StatusUpdater.UpdateStatus(ctx, invoiceID, invoice.Status, next)
+	if err != nil {
+		return nil, fmt.Errorf("update error: %w", err)
+	}
+	if !updated {
+		return nil, ErrConflict
+	}
+	invoice.Status = next
+	return invoice, nil
 }
@@ -1,14 +1,17 @@
 package internal
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/Ryuheeeei/super-invoicer/internal/domain"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFindService_Find(t *testing.T) {
@@ -26,9 +29,9 @@ func TestFindService_Find(t *testing.T) {
 					IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 					Amount:    10000,
 					Fee:       400,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       40,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					Total:     10440,
 					DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 					Status:    "unprocessed",
@@ -37,9 +40,9 @@ func TestFindService_Find(t *testing.T) {
 					IssueDate: time.Date(1970, 1, 2, 9, 0, 0, 0, time.UTC),
 					Amount:    5000,
 					Fee:       200,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       20,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					Total:     5220,
 					DueDate:   time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
 					Status:    "processing",
@@ -50,9 +53,9 @@ func TestFindService_Find(t *testing.T) {
 					IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 					Amount:    10000,
 					Fee:       400,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       40,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					Total:     10440,
 					DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 					Status:    domain.Unprocessed,
@@ -61,9 +64,9 @@ func TestFindService_Find(t *testing.T) {
 					IssueDate: time.Date(1970, 1, 2, 9, 0, 0, 0, time.UTC),
 					Amount:    5000,
 					Fee:       200,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       20,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					Total:     5220,
 					DueDate:   time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
 					Status:    domain.Processing,
@@ -78,17 +81,146 @@ func TestFindService_Find(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			selector := SelectorFunc(func(context.Context, string, time.Time) (*Rows, error) {
+			selector := SelectorFunc(func(_ context.Context, q InvoiceQuery) (*Rows, error) {
+				assert.Equal(t, 101, q.Limit)
 				return tt.rows, tt.err
 			})
 			s := FindService{Selector: selector}
-			got, err := s.Find(context.Background(), "1", time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC))
+			got, err := s.Find(context.Background(), DueByQuery("1", time.Now(), time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)))
+			assert.Equal(t, tt.wantErr, err)
+			if tt.wantErr == nil {
+				assert.Equal(t, tt.want, got.Invoices)
+				assert.Nil(t, got.NextCursor)
+			}
+		})
+	}
+}
+
+func TestFindService_FindPage(t *testing.T) {
+	rows := &Rows{Rows: []Row{
+		{InvoiceID: "1", Total: 10440, DueDate: time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC)},
+		{InvoiceID: "2", Total: 5220, DueDate: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{InvoiceID: "3", Total: 5220, DueDate: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}}
+	tests := []struct {
+		name       string
+		query      InvoiceQuery
+		wantIDs    []string
+		wantCursor *Cursor
+	}{
+		{
+			name:       "next page exists",
+			query:      InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "due_date"}, Limit: 2},
+			wantIDs:    []string{"1", "2"},
+			wantCursor: &Cursor{Sort: "due_date", Value: "2024-11-01", InvoiceID: 2},
+		},
+		{
+			name:       "cursor holds the value of the sort field",
+			query:      InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "total", Desc: true}, Limit: 2},
+			wantIDs:    []string{"1", "2"},
+			wantCursor: &Cursor{Sort: "-total", Value: "5220", InvoiceID: 2},
+		},
+		{
+			name:    "last page",
+			query:   InvoiceQuery{CompanyID: "1", Sort: SortKey{Field: "due_date"}, Limit: 3},
+			wantIDs: []string{"1", "2", "3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := SelectorFunc(func(_ context.Context, q InvoiceQuery) (*Rows, error) {
+				return &Rows{Rows: rows.Rows[:min(q.Limit, len(rows.Rows))]}, nil
+			})
+			s := FindService{Selector: selector}
+			got, err := s.Find(context.Background(), tt.query)
+			assert.NoError(t, err)
+			ids := make([]string, 0)
+			for _, invoice := range got.Invoices {
+				ids = append(ids, invoice.InvoiceID)
+			}
+			assert.Equal(t, tt.wantIDs, ids)
+			if tt.wantCursor != nil {
+				tt.wantCursor.Filter = tt.query.filterHash()
+			}
+			assert.Equal(t, tt.wantCursor, got.NextCursor)
+		})
+	}
+}
+
+func TestFindService_FindByID(t *testing.T) {
+	tests := []struct {
+		name    string
+		row     *Row
+		err     error
+		want    *domain.Invoice
+		wantErr error
+	}{
+		{
+			name: "selector returns row",
+			row: &Row{
+				InvoiceID: "1",
+				CompanyID: "1",
+				IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
+				Amount:    10000,
+				Fee:       400,
+				FeeRate:   domain.MustParseRate("0.04"),
+				Tax:       40,
+				TaxRate:   domain.MustParseRate("0.10"),
+				Total:     10440,
+				DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
+				Status:    "unprocessed",
+			},
+			want: &domain.Invoice{
+				InvoiceID: "1",
+				CompanyID: "1",
+				IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
+				Amount:    10000,
+				Fee:       400,
+				FeeRate:   domain.MustParseRate("0.04"),
+				Tax:       40,
+				TaxRate:   domain.MustParseRate("0.10"),
+				Total:     10440,
+				DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
+				Status:    domain.Unprocessed,
+			},
+		},
+		{
+			name:    "selector returns no row",
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "row belongs to another company",
+			row:     &Row{InvoiceID: "1", CompanyID: "2"},
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "selector returns error",
+			err:     errors.New("this is test"),
+			wantErr: fmt.Errorf("find service error: %w", errors.New("this is test")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selector := IDSelectorFunc(func(context.Context, string) (*Row, error) {
+				return tt.row, tt.err
+			})
+			s := FindService{IDSelector: selector}
+			got, err := s.FindByID(context.Background(), "1", "1")
 			assert.Equal(t, tt.wantErr, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
 
+var (
+	existingCompany = CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+		return &CompanyRow{CompanyID: companyID}, nil
+	})
+	partnerOfCompany1 = PartnerSelectorFunc(func(_ context.Context, partnerID string) (*PartnerRow, error) {
+		return &PartnerRow{PartnerID: partnerID, CompanyID: "1"}, nil
+	})
+)
+
 func TestRegisterService_Insert(t *testing.T) {
 	type args struct {
 		companyID string
@@ -98,11 +230,13 @@ func TestRegisterService_Insert(t *testing.T) {
 		status    string
 	}
 	tests := []struct {
-		name     string
-		args     args
-		inserter Inserter
-		want     *domain.Invoice
-		wantErr  error
+		name            string
+		args            args
+		inserter        Inserter
+		companySelector CompanySelector
+		partnerSelector PartnerSelector
+		want            *domain.Invoice
+		wantErr         error
 	}{
 		{
 			name: "inserter returns inserted row",
@@ -110,23 +244,27 @@ func TestRegisterService_Insert(t *testing.T) {
 			inserter: InserterFunc(func(ctx context.Context, companyID string, invoice *domain.Invoice) (*Row, error) {
 				assert.Equal(t, "1", companyID)
 				assert.Equal(t, &domain.Invoice{
+					PartnerID: "1",
 					IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 					Amount:    10000,
 					Fee:       400,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       40,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					Total:     10440,
 					DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 					Status:    domain.Unprocessed,
+					TaxAmounts: domain.TaxAmounts{
+						{Rate: domain.MustParseRate("0.10"), Taxable: 400, Tax: 40},
+					},
 				}, invoice)
 				return &Row{
 					IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 					Amount:    10000,
 					Fee:       400,
-					FeeRate:   0.04,
+					FeeRate:   domain.MustParseRate("0.04"),
 					Tax:       40,
-					TaxRate:   0.10,
+					TaxRate:   domain.MustParseRate("0.10"),
 					Total:     10440,
 					DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 					Status:    "unprocessed",
@@ -136,14 +274,44 @@ func TestRegisterService_Insert(t *testing.T) {
 				IssueDate: time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC),
 				Amount:    10000,
 				Fee:       400,
-				FeeRate:   0.04,
+				FeeRate:   domain.MustParseRate("0.04"),
 				Tax:       40,
-				TaxRate:   0.10,
+				TaxRate:   domain.MustParseRate("0.10"),
 				Total:     10440,
 				DueDate:   time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC),
 				Status:    domain.Unprocessed,
 			},
 		},
+		{
+			name: "qualified issuer stamps its registration number",
+			inserter: InserterFunc(func(ctx context.Context, companyID string, invoice *domain.Invoice) (*Row, error) {
+				assert.Equal(t, "T7000012050002", invoice.RegistrationNumber)
+				return &Row{RegistrationNumber: invoice.RegistrationNumber}, nil
+			}),
+			companySelector: CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+				return &CompanyRow{CompanyID: companyID, RegistrationNumber: "T7000012050002", QualifiedIssuer: true}, nil
+			}),
+			want: &domain.Invoice{RegistrationNumber: "T7000012050002"},
+		},
+		{
+			name: "income tax is withheld from sole proprietors",
+			inserter: InserterFunc(func(ctx context.Context, companyID string, invoice *domain.Invoice) (*Row, error) {
+				assert.Equal(t, 1021, invoice.WithholdingTax)
+				assert.Equal(t, 9419, invoice.Total)
+				return &Row{WithholdingTax: invoice.WithholdingTax, Total: invoice.Total}, nil
+			}),
+			partnerSelector: PartnerSelectorFunc(func(_ context.Context, partnerID string) (*PartnerRow, error) {
+				return &PartnerRow{PartnerID: partnerID, CompanyID: "1", Withholding: "professional"}, nil
+			}),
+			want: &domain.Invoice{WithholdingTax: 1021, Total: 9419},
+		},
+		{
+			name: "qualified issuer with invalid registration number",
+			companySelector: CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+				return &CompanyRow{CompanyID: companyID, RegistrationNumber: "T1234567890123", QualifiedIssuer: true}, nil
+			}),
+			wantErr: fmt.Errorf("%w: %v", domain.ErrNonQualifiedInvoice, fmt.Errorf("%w: check digit of T1234567890123 doesn't match", domain.ErrInvalidRegistrationNumber)),
+		},
 		{
 			name: "inserter returns error",
 			inserter: InserterFunc(func(ctx context.Context, s string, i *domain.Invoice) (*Row, error) {
@@ -151,13 +319,276 @@ func TestRegisterService_Insert(t *testing.T) {
 			}),
 			wantErr: fmt.Errorf("insert error: %w", errors.New("this is test")),
 		},
+		{
+			name: "company doesn't exist",
+			companySelector: CompanySelectorFunc(func(context.Context, string) (*CompanyRow, error) {
+				return nil, nil
+			}),
+			wantErr: ErrCompanyNotFound,
+		},
+		{
+			name: "company selector returns error",
+			companySelector: CompanySelectorFunc(func(context.Context, string) (*CompanyRow, error) {
+				return nil, errors.New("this is test")
+			}),
+			wantErr: fmt.Errorf("company error: %w", errors.New("this is test")),
+		},
+		{
+			name: "partner doesn't exist",
+			partnerSelector: PartnerSelectorFunc(func(context.Context, string) (*PartnerRow, error) {
+				return nil, nil
+			}),
+			wantErr: ErrPartnerNotFound,
+		},
+		{
+			name: "partner belongs to another company",
+			partnerSelector: PartnerSelectorFunc(func(_ context.Context, partnerID string) (*PartnerRow, error) {
+				return &PartnerRow{PartnerID: partnerID, CompanyID: "2"}, nil
+			}),
+			wantErr: ErrPartnerNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			companySelector := tt.companySelector
+			if companySelector == nil {
+				companySelector = existingCompany
+			}
+			partnerSelector := tt.partnerSelector
+			if partnerSelector == nil {
+				partnerSelector = partnerOfCompany1
+			}
+			s := RegisterService{Inserter: tt.inserter, CompanySelector: companySelector, PartnerSelector: partnerSelector, RateSelector: RateSelectorFunc(func(context.Context, string, time.Time) (*RateRow, error) {
+				return nil, nil
+			})}
+			got, err := s.Register(context.Background(), "1", "1", time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC), 10000, nil, time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC), "unprocessed")
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRegisterService_Rates(t *testing.T) {
+	taxRate := domain.MustParseRate("0.08")
+	tests := []struct {
+		name    string
+		row     *RateRow
+		err     error
+		want    domain.Rates
+		wantErr error
+	}{
+		{
+			name: "no contract falls back to default rates",
+			want: domain.DefaultRates,
+		},
+		{
+			name: "contract overrides fee rate only",
+			row:  &RateRow{FeeRate: domain.MustParseRate("0.03"), Rounding: domain.Floor},
+			want: domain.Rates{FeeRate: domain.MustParseRate("0.03"), TaxRate: domain.DefaultRates.TaxRate, Rounding: domain.Floor},
+		},
+		{
+			name: "contract overrides both rates",
+			row:  &RateRow{FeeRate: domain.MustParseRate("0.03"), TaxRate: &taxRate, Rounding: domain.Ceil},
+			want: domain.Rates{FeeRate: domain.MustParseRate("0.03"), TaxRate: domain.MustParseRate("0.08"), Rounding: domain.Ceil},
+		},
+		{
+			name:    "selector returns error",
+			err:     errors.New("this is test"),
+			wantErr: fmt.Errorf("rate error: %w", errors.New("this is test")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := RegisterService{
+				CompanySelector: existingCompany,
+				PartnerSelector: partnerOfCompany1,
+				RateSelector: RateSelectorFunc(func(_ context.Context, companyID string, date time.Time) (*RateRow, error) {
+					assert.Equal(t, "1", companyID)
+					assert.Equal(t, time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), date)
+					return tt.row, tt.err
+				}),
+				Inserter: InserterFunc(func(_ context.Context, _ string, invoice *domain.Invoice) (*Row, error) {
+					assert.Equal(t, tt.want.FeeRate, invoice.FeeRate)
+					assert.Equal(t, tt.want.TaxRate, invoice.TaxRate)
+					return &Row{}, nil
+				}),
+			}
+			_, err := s.Register(context.Background(), "1", "1", time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), 10000, nil, time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC), "unprocessed")
+			assert.Equal(t, tt.wantErr, err)
+		})
+	}
+}
+
+func TestRegisterService_DueDate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		dueDate time.Time
+		want    time.Time
+	}{
+		{name: "business day", policy: "next", dueDate: time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC)},
+		{name: "next business day of holiday", policy: "next", dueDate: time.Date(2024, 11, 4, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 11, 5, 0, 0, 0, 0, time.UTC)},
+		{name: "previous business day of holiday", policy: "previous", dueDate: time.Date(2024, 11, 4, 0, 0, 0, 0, time.UTC), want: time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "company without policy moves to next business day", dueDate: time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC), want: time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := RegisterService{
+				CompanySelector: CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+					return &CompanyRow{CompanyID: companyID, DueDatePolicy: tt.policy}, nil
+				}),
+				PartnerSelector: partnerOfCompany1,
+				RateSelector: RateSelectorFunc(func(context.Context, string, time.Time) (*RateRow, error) {
+					return nil, nil
+				}),
+				Inserter: InserterFunc(func(_ context.Context, _ string, invoice *domain.Invoice) (*Row, error) {
+					return &Row{DueDate: invoice.DueDate}, nil
+				}),
+			}
+			got, err := s.Register(context.Background(), "1", "1", time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), 10000, nil, tt.dueDate, "unprocessed")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.DueDate)
+		})
+	}
+}
+
+func TestRegisterService_DueDateOutOfRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		dueDate  time.Time
+		want     time.Time
+		wantWarn bool
+	}{
+		{name: "in the years of the holidays", dueDate: time.Date(2027, 12, 30, 0, 0, 0, 0, time.UTC), want: time.Date(2027, 12, 30, 0, 0, 0, 0, time.UTC)},
+		{name: "moved out of the years of the holidays", dueDate: time.Date(2027, 12, 31, 0, 0, 0, 0, time.UTC), want: time.Date(2028, 1, 4, 0, 0, 0, 0, time.UTC), wantWarn: true},
+		{name: "unknown holiday is a business day", dueDate: time.Date(2028, 1, 10, 0, 0, 0, 0, time.UTC), want: time.Date(2028, 1, 10, 0, 0, 0, 0, time.UTC), wantWarn: true},
+		{name: "weekend is skipped", dueDate: time.Date(2099, 10, 31, 0, 0, 0, 0, time.UTC), want: time.Date(2099, 11, 2, 0, 0, 0, 0, time.UTC), wantWarn: true},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s := RegisterService{Inserter: tt.inserter}
-			got, err := s.Register(context.Background(), "1", time.Date(1970, 1, 1, 9, 0, 0, 0, time.UTC), 10000, time.Date(2024, 10, 30, 0, 0, 0, 0, time.UTC), "unprocessed")
+			var logs bytes.Buffer
+			s := RegisterService{
+				CompanySelector: CompanySelectorFunc(func(_ context.Context, companyID string) (*CompanyRow, error) {
+					return &CompanyRow{CompanyID: companyID}, nil
+				}),
+				PartnerSelector: partnerOfCompany1,
+				RateSelector: RateSelectorFunc(func(context.Context, string, time.Time) (*RateRow, error) {
+					return nil, nil
+				}),
+				Inserter: InserterFunc(func(_ context.Context, _ string, invoice *domain.Invoice) (*Row, error) {
+					return &Row{DueDate: invoice.DueDate}, nil
+				}),
+				Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+			}
+			got, err := s.Register(context.Background(), "1", "1", time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC), 10000, nil, tt.dueDate, "unprocessed")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.DueDate)
+			if tt.wantWarn {
+				assert.Contains(t, logs.String(), "level=WARN")
+				assert.Contains(t, logs.String(), "first_year=2024 last_year=2027")
+			} else {
+				assert.Empty(t, logs.String())
+			}
+		})
+	}
+}
+
+func TestStatusService_ChangeStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		row        *Row
+		status     domain.Status
+		updated    bool
+		updaterErr error
+		want       *domain.Invoice
+		wantErr    error
+	}{
+		{
+			name:    "allowed transition",
+			row:     &Row{InvoiceID: "1", CompanyID: "1", Status: "processing"},
+			status:  domain.Paid,
+			updated: true,
+			want:    &domain.Invoice{InvoiceID: "1", CompanyID: "1", Status: domain.Paid},
+		},
+		{
+			name:    "illegal transition",
+			row:     &Row{InvoiceID: "1", CompanyID: "1", Status: "paid"},
+			status:  domain.Processing,
+			wantErr: fmt.Errorf("%w: from paid to processing", domain.ErrInvalidTransition),
+		},
+		{
+			name:    "invoice not found",
+			status:  domain.Paid,
+			wantErr: ErrNotFound,
+		},
+		{
+			name:    "invoice of another company",
+			row:     &Row{InvoiceID: "1", CompanyID: "2", Status: "processing"},
+			status:  domain.Paid,
+			wantErr: ErrForbidden,
+		},
+		{
+			name:    "invoice changed concurrently",
+			row:     &Row{InvoiceID: "1", CompanyID: "1", Status: "processing"},
+			status:  domain.Paid,
+			updated: false,
+			wantErr: ErrConflict,
+		},
+		{
+			name:       "updater returns error",
+			row:        &Row{InvoiceID: "1", CompanyID: "1", Status: "processing"},
+			status:     domain.Paid,
+			updaterErr: errors.New("this is test"),
+			wantErr:    fmt.Errorf("update error: %w", errors.New("this is test")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := StatusService{
+				IDSelector: IDSelectorFunc(func(context.Context, string) (*Row, error) {
+					return tt.row, nil
+				}),
+				StatusUpdater: StatusUpdaterFunc(func(_ context.Context, _ string, from, to domain.Status) (bool, error) {
+					assert.Equal(t, domain.Status(tt.row.Status), from)
+					assert.Equal(t, tt.status, to)
+					return tt.updated, tt.updaterErr
+				}),
+			}
+			got, err := s.ChangeStatus(context.Background(), "1", "1", tt.status)
 			assert.Equal(t, tt.wantErr, err)
 			assert.Equal(t, tt.want, got)
 		})
 	}
 }
+
+func TestFindService_Export(t *testing.T) {
+	var got []string
+	s := &FindService{EachSelector: EachSelectorFunc(func(_ context.Context, q InvoiceQuery, f func(*Row) error) error {
+		assert.Zero(t, q.Limit)
+		for _, id := range []string{"1", "2"} {
+			if err := f(&Row{InvoiceID: id, CompanyID: q.CompanyID, Status: "paid"}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})}
+	err := s.Export(context.Background(), InvoiceQuery{CompanyID: "1"}, func(invoice *domain.Invoice) error {
+		got = append(got, invoice.InvoiceID)
+		assert.Equal(t, domain.Paid, invoice.Status)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, got)
+
+	ctx := WithUser(context.Background(), &domain.User{UserID: "1", CompanyID: "2"})
+	err = s.Export(ctx, InvoiceQuery{CompanyID: "1"}, func(*domain.Invoice) error {
+		t.Error("invoices of another company mustn't be exported")
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	err = s.Export(context.Background(), InvoiceQuery{CompanyID: "1"}, func(*domain.Invoice) error {
+		return errors.New("this is test")
+	})
+	assert.Equal(t, fmt.Errorf("export service error: %w", errors.New("this is test")), err)
+}
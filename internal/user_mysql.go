@@ -0,0 +1,74 @@
+package internal
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+)
+
+var (
+	_ UserSelector = (*MySQL)(nil)
+	_ UserInserter = (*MySQL)(nil)
+)
+
+type UserRow struct {
+	UserID       string
+	CompanyID    string
+	Username     string
+	PasswordHash []byte
+	Role         string
+}
+
+func (r *UserRow) user() *domain.User {
+	return &domain.User{
+		UserID:       r.UserID,
+		CompanyID:    r.CompanyID,
+		Username:     r.Username,
+		PasswordHash: r.PasswordHash,
+		Role:         domain.Role(r.Role),
+	}
+}
+
+// SelectUser returns the user identified by username, or nil if it doesn't exist.
+func (s *MySQL) SelectUser(ctx context.Context, username string) (*UserRow, error) {
+	var row UserRow
+	err := s.DB.QueryRowContext(ctx, "SELECT user_id, company_id, username, password_hash, role FROM users WHERE username = ?;", username).
+		Scan(&row.UserID, &row.CompanyID, &row.Username, &row.PasswordHash, &row.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
+
+// InsertUser returns ErrUserExists when the username is taken, and ErrCompanyNotFound when the company doesn't exist.
+func (s *MySQL) InsertUser(ctx context.Context, user *domain.User) (*UserRow, error) {
+	result, err := s.DB.ExecContext(ctx, "INSERT INTO users (company_id, username, password_hash, role) VALUES (?, ?, ?, ?);", user.CompanyID, user.Username, user.PasswordHash, user.Role)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errDupEntry {
+		return nil, ErrUserExists
+	}
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == errNoReferencedRow {
+		return nil, ErrCompanyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &UserRow{
+		UserID:       strconv.FormatInt(userID, 10),
+		CompanyID:    user.CompanyID,
+		Username:     user.Username,
+		PasswordHash: user.PasswordHash,
+		Role:         string(user.Role),
+	}, nil
+}
@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQL_SelectUser(t *testing.T) {
+	tests := []struct {
+		name string
+		rows *sqlmock.Rows
+		want *UserRow
+	}{
+		{
+			name: "user exists",
+			rows: sqlmock.NewRows([]string{"user_id", "company_id", "username", "password_hash", "role"}).AddRow("1", "2", "foo", []byte("HASH"), "admin"),
+			want: &UserRow{UserID: "1", CompanyID: "2", Username: "foo", PasswordHash: []byte("HASH"), Role: "admin"},
+		},
+		{
+			name: "user doesn't exist",
+			rows: sqlmock.NewRows([]string{"user_id", "company_id", "username", "password_hash", "role"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT user_id, company_id, username, password_hash, role FROM users WHERE username = ?;")).WithArgs("foo").WillReturnRows(tt.rows)
+
+			s := &MySQL{DB: db}
+			got, err := s.SelectUser(context.Background(), "foo")
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMySQL_InsertUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		execErr error
+		want    *UserRow
+		wantErr error
+	}{
+		{
+			name: "no error",
+			want: &UserRow{UserID: "3", CompanyID: "1", Username: "foo", PasswordHash: []byte("HASH"), Role: "accountant"},
+		},
+		{
+			name:    "username is taken",
+			execErr: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"},
+			wantErr: ErrUserExists,
+		},
+		{
+			name:    "company doesn't exist",
+			execErr: &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row"},
+			wantErr: ErrCompanyNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			exec := mock.ExpectExec(regexp.QuoteMeta("INSERT INTO users (company_id, username, password_hash, role) VALUES (?, ?, ?, ?);")).WithArgs("1", "foo", []byte("HASH"), domain.Accountant)
+			if tt.execErr != nil {
+				exec.WillReturnError(tt.execErr)
+			} else {
+				exec.WillReturnResult(sqlmock.NewResult(3, 1))
+			}
+
+			s := &MySQL{DB: db}
+			got, err := s.InsertUser(context.Background(), &domain.User{CompanyID: "1", Username: "foo", PasswordHash: []byte("HASH"), Role: domain.Accountant})
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
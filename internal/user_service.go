@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrUserExists   = errors.New("user already exists")
+)
+
+type UserSelector interface {
+	SelectUser(context.Context, string) (*UserRow, error)
+}
+
+type UserSelectorFunc func(context.Context, string) (*UserRow, error)
+
+func (f UserSelectorFunc) SelectUser(ctx context.Context, s string) (*UserRow, error) {
+	return f(ctx, s)
+}
+
+// dummyUser is compared against when the username doesn't exist, so that unknown usernames take as long to reject
+// as wrong passwords and can't be enumerated by timing.
+var dummyUser = &domain.User{PasswordHash: []byte("$2a$10$Qj3WkgfhyZSspJn8gkcI0./Gu7RUZ.Qj6ovUY4sLxseFqEwFjdxda")}
+
+type AuthService struct {
+	UserSelector UserSelector
+}
+
+// Authenticate returns the user whose credentials match, or ErrUnauthorized.
+func (s *AuthService) Authenticate(ctx context.Context, username, password string) (*domain.User, error) {
+	row, err := s.UserSelector.SelectUser(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("auth service error: %w", err)
+	}
+	if row == nil {
+		dummyUser.Authenticate(password)
+		return nil, ErrUnauthorized
+	}
+	user := row.user()
+	if !user.Authenticate(password) {
+		return nil, ErrUnauthorized
+	}
+	return user, nil
+}
+
+type UserInserter interface {
+	InsertUser(context.Context, *domain.User) (*UserRow, error)
+}
+
+type UserInserterFunc func(context.Context, *domain.User) (*UserRow, error)
+
+func (f UserInserterFunc) InsertUser(ctx context.Context, user *domain.User) (*UserRow, error) {
+	return f(ctx, user)
+}
+
+type UserService struct {
+	Inserter UserInserter
+}
+
+// Add creates a user of the company with role. The password is stored only as a bcrypt hash.
+func (s *UserService) Add(ctx context.Context, companyID, username, password string, role domain.Role) (*domain.User, error) {
+	user, err := domain.NewUser(companyID, username, password, role)
+	if err != nil {
+		return nil, err
+	}
+	row, err := s.Inserter.InsertUser(ctx, user)
+	if errors.Is(err, ErrUserExists) || errors.Is(err, ErrCompanyNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("insert error: %w", err)
+	}
+	return row.user(), nil
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthService_Authenticate(t *testing.T) {
+	user, err := domain.NewUser("2", "foo", "password", domain.Admin)
+	require.NoError(t, err)
+	selector := UserSelectorFunc(func(_ context.Context, username string) (*UserRow, error) {
+		switch username {
+		case "foo":
+			return &UserRow{UserID: "1", CompanyID: "2", Username: "foo", PasswordHash: user.PasswordHash, Role: "admin"}, nil
+		case "broken":
+			return nil, errors.New("this is test")
+		}
+		return nil, nil
+	})
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     *domain.User
+		wantErr  error
+	}{
+		{
+			name:     "valid credentials",
+			username: "foo",
+			password: "password",
+			want:     &domain.User{UserID: "1", CompanyID: "2", Username: "foo", PasswordHash: user.PasswordHash, Role: domain.Admin},
+		},
+		{
+			name:     "wrong password",
+			username: "foo",
+			password: "wrong-password",
+			wantErr:  ErrUnauthorized,
+		},
+		{
+			name:     "unknown username",
+			username: "bar",
+			password: "password",
+			wantErr:  ErrUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &AuthService{UserSelector: selector}
+			got, err := s.Authenticate(context.Background(), tt.username, tt.password)
+			assert.Equal(t, tt.wantErr, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("selector error", func(t *testing.T) {
+		s := &AuthService{UserSelector: selector}
+		_, err := s.Authenticate(context.Background(), "broken", "password")
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrUnauthorized)
+	})
+}
+
+func TestUserService_Add(t *testing.T) {
+	tests := []struct {
+		name      string
+		password  string
+		insertErr error
+		wantErr   error
+	}{
+		{
+			name:     "no error",
+			password: "password",
+		},
+		{
+			name:     "too short password",
+			password: "short",
+			wantErr:  domain.ErrInvalidUser,
+		},
+		{
+			name:      "username is taken",
+			password:  "password",
+			insertErr: ErrUserExists,
+			wantErr:   ErrUserExists,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &UserService{Inserter: UserInserterFunc(func(_ context.Context, user *domain.User) (*UserRow, error) {
+				if tt.insertErr != nil {
+					return nil, tt.insertErr
+				}
+				assert.NotEqual(t, tt.password, string(user.PasswordHash))
+				return &UserRow{UserID: "1", CompanyID: user.CompanyID, Username: user.Username, PasswordHash: user.PasswordHash, Role: string(user.Role)}, nil
+			})}
+			got, err := s.Add(context.Background(), "2", "foo", tt.password, domain.Accountant)
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+			assert.Equal(t, "1", got.UserID)
+			assert.True(t, got.Authenticate(tt.password))
+		})
+	}
+}
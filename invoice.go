@@ -0,0 +1,323 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	invoiceCompanyID string
+	invoiceRequest   internal.InvoiceRequest
+	invoiceID        string
+	importFile       string
+	importOptions    internal.ImportOptions
+	importMode       string
+	importDryRun     bool
+	exportFile       string
+	exportColumns    string
+	exportBOM        bool
+)
+
+// invoiceListParams are the flags of invoice list. They are passed as is to internal.ParseInvoiceQuery as
+// the query parameters of GET /api/invoices, with dashes replaced by underscores.
+var invoiceListParams = []struct {
+	name  string
+	usage string
+}{
+	{"due-date", "List the unpaid invoices due between today and the date (YYYY-MM-DD)"},
+	{"status", "Comma separated statuses, any of [unprocessed, processing, paid, error]"},
+	{"partner-id", "ID of the business partner"},
+	{"issue-date-from", "Earliest issue date (YYYY-MM-DD)"},
+	{"issue-date-to", "Latest issue date (YYYY-MM-DD)"},
+	{"due-date-from", "Earliest due date (YYYY-MM-DD)"},
+	{"due-date-to", "Latest due date (YYYY-MM-DD)"},
+	{"amount-min", "Minimum amount"},
+	{"amount-max", "Maximum amount"},
+	{"total-min", "Minimum total"},
+	{"total-max", "Maximum total"},
+	{"sort", "Sort key, one of [due_date, issue_date, amount, total] optionally prefixed with '-' (default due_date)"},
+	{"limit", "Maximum number of invoices between 1 and 500 (default 100)"},
+	{"cursor", "Cursor printed by the previous page"},
+}
+
+func init() {
+	invoiceCmd.PersistentFlags().StringVar(&invoiceCompanyID, "company-id", "", "ID of the company the invoices belong to")
+	invoiceCmd.MarkPersistentFlagRequired("company-id")
+	addOutputFlag(invoiceCmd)
+
+	invoiceCreateCmd.Flags().StringVar(&invoiceRequest.PartnerID, "partner-id", "", "ID of the business partner to pay")
+	invoiceCreateCmd.Flags().IntVar(&invoiceRequest.Amount, "amount", 0, "Amount to pay")
+	invoiceCreateCmd.Flags().StringVar(&invoiceRequest.IssueDate, "issue-date", "", "Issue date (YYYY-MM-DD)")
+	invoiceCreateCmd.Flags().StringVar(&invoiceRequest.DueDate, "due-date", "", "Due date (YYYY-MM-DD, end_of_month, end_of_next_month or end_of_month_after_next), moved to a business day by the policy of the company")
+	invoiceCreateCmd.Flags().StringVar(&invoiceRequest.Status, "status", string(domain.Unprocessed), "Status, one of [unprocessed, processing, paid, error]")
+	invoiceCreateCmd.MarkFlagRequired("partner-id")
+	invoiceCreateCmd.MarkFlagRequired("amount")
+	invoiceCreateCmd.MarkFlagRequired("issue-date")
+	invoiceCreateCmd.MarkFlagRequired("due-date")
+
+	for _, param := range invoiceListParams {
+		invoiceListCmd.Flags().String(param.name, "", param.usage)
+	}
+
+	for _, param := range invoiceListParams {
+		usage := param.usage
+		if param.name == "limit" {
+			usage = "Maximum number of invoices between 1 and 500 (default all)"
+		}
+		invoiceExportCmd.Flags().String(param.name, "", usage)
+	}
+	invoiceExportCmd.Flags().StringVar(&exportFile, "file", "", "File to write the CSV to (default stdout)")
+	invoiceExportCmd.Flags().StringVar(&exportColumns, "columns", "", "Comma separated columns, any of ["+strings.Join(internal.ExportColumns, ", ")+"] (default all)")
+	invoiceExportCmd.Flags().BoolVar(&exportBOM, "bom", false, "Prefix the CSV with the UTF-8 BOM for Excel")
+
+	invoiceShowCmd.Flags().StringVar(&invoiceID, "id", "", "ID of the invoice to show")
+	invoiceShowCmd.MarkFlagRequired("id")
+
+	invoiceImportCmd.Flags().StringVar(&importFile, "file", "", "CSV file of the invoices whose first line is the header")
+	invoiceImportCmd.Flags().StringToStringVar(&importOptions.Mapping, "map", nil, "Mapping of the CSV headers to the fields, e.g. 取引先ID=partner_id,金額=amount. Fields are "+strings.Join(internal.ImportFields, ", "))
+	invoiceImportCmd.Flags().StringVar(&importOptions.Encoding, "encoding", "auto", "Encoding of the file, one of [auto, utf-8, shift_jis]")
+	invoiceImportCmd.Flags().StringVar(&importMode, "mode", string(internal.AllOrNothing), "Import mode, one of [all_or_nothing, best_effort]")
+	invoiceImportCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Validate the rows without creating the invoices")
+	invoiceImportCmd.MarkFlagRequired("file")
+
+	invoiceCmd.AddCommand(invoiceCreateCmd, invoiceListCmd, invoiceShowCmd, invoiceImportCmd, invoiceExportCmd)
+	app.AddCommand(invoiceCmd)
+}
+
+var invoiceCmd = &cobra.Command{
+	Use:   "invoice",
+	Short: "Create and find invoices",
+}
+
+var invoiceHeader = []string{"invoice_id", "partner_id", "issue_date", "amount", "fee", "fee_rate", "tax", "tax_rate", "withholding_tax", "total", "due_date", "status"}
+
+func invoiceRow(invoice *domain.Invoice) []string {
+	return []string{
+		invoice.InvoiceID,
+		invoice.PartnerID,
+		invoice.IssueDate.Format(time.DateOnly),
+		strconv.Itoa(invoice.Amount),
+		strconv.Itoa(invoice.Fee),
+		invoice.FeeRate.String(),
+		strconv.Itoa(invoice.Tax),
+		invoice.TaxRate.String(),
+		strconv.Itoa(invoice.WithholdingTax),
+		strconv.Itoa(invoice.Total),
+		invoice.DueDate.Format(time.DateOnly),
+		string(invoice.Status),
+	}
+}
+
+// invoiceListValues returns the query parameters of GET /api/invoices given as the flags of cmd.
+func invoiceListValues(cmd *cobra.Command) url.Values {
+	values := url.Values{}
+	for _, param := range invoiceListParams {
+		if f := cmd.Flags().Lookup(param.name); f.Changed {
+			values.Set(strings.ReplaceAll(f.Name, "-", "_"), f.Value.String())
+		}
+	}
+	return values
+}
+
+var invoiceCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create an invoice with the rates contracted by the company",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		invoiceRequest.CompanyID = invoiceCompanyID
+		issueDate, dueDate, err := invoiceRequest.Validate()
+		if err != nil {
+			return err
+		}
+		cal, err := loadCalendar()
+		if err != nil {
+			return err
+		}
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.RegisterService{Inserter: mysqlClient, RateSelector: mysqlClient, CompanySelector: mysqlClient, PartnerSelector: mysqlClient, Calendar: cal}
+			invoice, err := s.Register(cmd.Context(), invoiceRequest.CompanyID, invoiceRequest.PartnerID, issueDate, invoiceRequest.Amount, invoiceRequest.Lines, dueDate, invoiceRequest.Status)
+			if errors.Is(err, internal.ErrCompanyNotFound) {
+				return fmt.Errorf("company %v doesn't exist", invoiceRequest.CompanyID)
+			}
+			if errors.Is(err, internal.ErrPartnerNotFound) {
+				return fmt.Errorf("business partner %v doesn't exist in company %v", invoiceRequest.PartnerID, invoiceRequest.CompanyID)
+			}
+			if err != nil {
+				return err
+			}
+			return printResult(os.Stdout, result{header: invoiceHeader, rows: [][]string{invoiceRow(invoice)}, value: internal.NewInvoiceResponse(invoice)})
+		})
+	},
+}
+
+var invoiceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List invoices of the company with the filters of GET /api/invoices",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clock, err := loadClock()
+		if err != nil {
+			return err
+		}
+		q, err := internal.ParseInvoiceQuery(invoiceListValues(cmd), invoiceCompanyID, internal.Today(clock))
+		if err != nil {
+			return err
+		}
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.FindService{Selector: mysqlClient, IDSelector: mysqlClient}
+			page, err := s.Find(cmd.Context(), q)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(page.Invoices))
+			for _, invoice := range page.Invoices {
+				rows = append(rows, invoiceRow(&invoice))
+			}
+			if err := printResult(os.Stdout, result{header: invoiceHeader, rows: rows, value: internal.NewListResponse(page)}); err != nil {
+				return err
+			}
+			if page.NextCursor != nil && outputFormat != "json" {
+				fmt.Fprintf(os.Stderr, "More invoices exist. Continue with --cursor=%v\n", page.NextCursor.Encode())
+			}
+			return nil
+		})
+	},
+}
+
+var invoiceShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show an invoice",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.FindService{Selector: mysqlClient, IDSelector: mysqlClient}
+			invoice, err := s.FindByID(cmd.Context(), invoiceCompanyID, invoiceID)
+			if errors.Is(err, internal.ErrNotFound) || errors.Is(err, internal.ErrForbidden) {
+				return fmt.Errorf("invoice %v doesn't exist in company %v", invoiceID, invoiceCompanyID)
+			}
+			if err != nil {
+				return err
+			}
+			return printResult(os.Stdout, result{header: invoiceHeader, rows: [][]string{invoiceRow(invoice)}, value: internal.NewInvoiceResponse(invoice)})
+		})
+	},
+}
+
+var invoiceImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Create invoices from a CSV file with the validation of POST /api/invoices",
+	Long: `Create invoices from a CSV file with the validation of POST /api/invoices.
+
+The columns are company_id (default --company-id), partner_id, issue_date, amount, due_date and status
+(default unprocessed). Headers of other names can be mapped to them with --map. The file is read as UTF-8,
+or as Shift_JIS unless it's valid UTF-8. The valid rows are created in a single transaction, and nothing is
+created in all_or_nothing mode when a row is invalid.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode := internal.BatchMode(importMode)
+		if !mode.Valid() {
+			return fmt.Errorf("--mode must be one of [all_or_nothing, best_effort], but got %v", importMode)
+		}
+		f, err := os.Open(importFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		importOptions.CompanyID = invoiceCompanyID
+		rows, err := internal.ReadInvoiceCSV(f, importOptions)
+		if err != nil {
+			return err
+		}
+		cal, err := loadCalendar()
+		if err != nil {
+			return err
+		}
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			if !importDryRun {
+				if err := mysqlClient.CheckBatchInsert(cmd.Context()); err != nil {
+					return err
+				}
+			}
+			s := &internal.RegisterService{Inserter: mysqlClient, BatchInserter: mysqlClient, RateSelector: mysqlClient, CompanySelector: mysqlClient, PartnerSelector: mysqlClient, Calendar: cal}
+			results, err := s.Import(cmd.Context(), rows, mode, importDryRun)
+			if err != nil {
+				return err
+			}
+			failed := 0
+			table := make([][]string, 0, len(results))
+			for _, r := range results {
+				invoiceID := ""
+				if r.Invoice != nil {
+					invoiceID = r.Invoice.InvoiceID
+				}
+				if r.Status == "failed" {
+					failed++
+				}
+				table = append(table, []string{strconv.Itoa(r.Line), r.Status, invoiceID, r.Message})
+			}
+			if err := printResult(os.Stdout, result{header: []string{"line", "status", "invoice_id", "message"}, rows: table, value: results}); err != nil {
+				return err
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d of %d rows are invalid", failed, len(results))
+			}
+			return nil
+		})
+	},
+}
+
+var invoiceExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export invoices of the company as CSV with the filters of GET /api/invoices",
+	Long: `Export invoices of the company as CSV with the filters of GET /api/invoices.
+
+Every matching invoice is exported unless --limit is given. The invoices are written as they are read from
+the database, so large exports don't hold them in memory.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		values := invoiceListValues(cmd)
+		clock, err := loadClock()
+		if err != nil {
+			return err
+		}
+		q, err := internal.ParseInvoiceQuery(values, invoiceCompanyID, internal.Today(clock))
+		if err != nil {
+			return err
+		}
+		if !values.Has("limit") {
+			q.Limit = 0
+		}
+		columns, err := internal.ParseExportColumns(exportColumns)
+		if err != nil {
+			return err
+		}
+		out := os.Stdout
+		if exportFile != "" {
+			out, err = os.Create(exportFile)
+			if err != nil {
+				return err
+			}
+			defer out.Close()
+		}
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.FindService{EachSelector: mysqlClient}
+			w := internal.NewInvoiceCSVWriter(out, columns, exportBOM)
+			if err := s.Export(cmd.Context(), q, w.Write); err != nil {
+				return err
+			}
+			if err := w.Start(); err != nil {
+				return err
+			}
+			return w.Flush()
+		})
+	},
+}
@@ -2,69 +2,122 @@ package main
 
 import (
 	"database/sql"
+	"fmt"
 	"log"
 	"log/slog"
-	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/Ryuheeeei/super-invoicer/internal/calendar"
 	"github.com/go-sql-driver/mysql"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	basicAuthEnable   bool
-	basicAuthUsername string
-	basicAuthPassword string
+	configFile   string
+	holidaysFile string
+	timezone     string
+
+	// dbFlags holds the --mysql.* flags. Only the flags set explicitly are applied by openDB so that they
+	// take precedence over the environment variables and the config file, and the defaults only show in the help.
+	dbFlags = pflag.NewFlagSet("mysql", pflag.ContinueOnError)
 )
 
 func init() {
-	app.Flags().BoolVar(&basicAuthEnable, "basic-auth.enable", false, "Enable basic authentication or not")
-	app.Flags().StringVar(&basicAuthUsername, "basic-auth.username", "", "Username for basic authentication")
-	app.Flags().StringVar(&basicAuthPassword, "basic-auth.password", "", "Password for basic authentication")
+	app.PersistentFlags().StringVar(&configFile, "config", "", "YAML or TOML file containing the mysql settings")
+	app.PersistentFlags().StringVar(&holidaysFile, "holidays", "", "CSV of the national holidays replacing the embedded ones, in the format of syukujitsu.csv of the Cabinet Office converted to UTF-8")
+	app.PersistentFlags().StringVar(&timezone, "timezone", internal.DefaultTimezone, "Business timezone where the dates like today of --due-date are reckoned, independent of the timezone of the server")
+	defaults := internal.DefaultDBConfig()
+	flags := dbFlags
+	flags.String("mysql.host", defaults.Host, "MySQL host (env MYSQL_HOST)")
+	flags.Int("mysql.port", defaults.Port, "MySQL port (env MYSQL_PORT)")
+	flags.String("mysql.user", defaults.User, "MySQL user (env MYSQL_USERNAME)")
+	flags.String("mysql.password-file", defaults.PasswordFile, "File containing the MySQL password (env MYSQL_PASSWORD_FILE). The password itself is only read from MYSQL_PASSWORD or the config file")
+	flags.String("mysql.database", defaults.Database, "MySQL database name (env MYSQL_DATABASE)")
+	flags.String("mysql.tls", defaults.TLS, "TLS mode, one of [false, true, skip-verify, preferred, custom] (env MYSQL_TLS)")
+	flags.String("mysql.tls-ca-file", defaults.TLSCAFile, "CA certificate verifying the server in custom TLS mode (env MYSQL_TLS_CA_FILE)")
+	flags.Duration("mysql.connect-timeout", defaults.ConnectTimeout, "Timeout for establishing connections (env MYSQL_CONNECT_TIMEOUT)")
+	flags.Duration("mysql.read-timeout", defaults.ReadTimeout, "I/O read timeout (env MYSQL_READ_TIMEOUT)")
+	flags.Duration("mysql.write-timeout", defaults.WriteTimeout, "I/O write timeout (env MYSQL_WRITE_TIMEOUT)")
+	flags.Int("mysql.max-open-conns", defaults.MaxOpenConns, "Maximum number of open connections (env MYSQL_MAX_OPEN_CONNS)")
+	flags.Int("mysql.max-idle-conns", defaults.MaxIdleConns, "Maximum number of idle connections (env MYSQL_MAX_IDLE_CONNS)")
+	flags.Duration("mysql.conn-max-lifetime", defaults.ConnMaxLifetime, "Maximum lifetime of a connection (env MYSQL_CONN_MAX_LIFETIME)")
+	flags.Duration("mysql.conn-max-idle-time", defaults.ConnMaxIdleTime, "Maximum idle time of a connection, 0 means no limit (env MYSQL_CONN_MAX_IDLE_TIME)")
+	app.PersistentFlags().AddFlagSet(dbFlags)
+}
+
+// openDB resolves the MySQL settings from, in increasing order of precedence, the defaults, the --config file,
+// the MYSQL_* environment variables and the --mysql.* flags.
+func openDB() (*sql.DB, error) {
+	c := internal.DefaultDBConfig()
+	if configFile != "" {
+		if err := c.LoadFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.LoadEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	var err error
+	dbFlags.VisitAll(func(f *pflag.Flag) {
+		if key, ok := strings.CutPrefix(f.Name, "mysql."); ok && f.Changed && err == nil {
+			err = c.Set(strings.ReplaceAll(key, "-", "_"), f.Value.String())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	mc, err := c.MySQLConfig()
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Connecting to mysql", "config", c)
+	connector, err := mysql.NewConnector(mc)
+	if err != nil {
+		return nil, err
+	}
+	db := sql.OpenDB(connector)
+	db.SetConnMaxLifetime(c.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	db.SetMaxOpenConns(c.MaxOpenConns)
+	db.SetMaxIdleConns(c.MaxIdleConns)
+	return db, nil
 }
 
 var app = &cobra.Command{
+	Use:   "super-invoicer",
 	Short: "Super Invoicer",
 	Long:  "App for creating and getting invoices.",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
-		c := mysql.Config{
-			User:                 os.Getenv("MYSQL_USERNAME"),
-			Passwd:               os.Getenv("MYSQL_PASSWORD"),
-			Net:                  "tcp",
-			Addr:                 "upsider-db-1:3306",
-			DBName:               "invoice_db",
-			AllowNativePasswords: true,
-		}
-		slog.Info("Connecting to mysql", "dataSoruceName", c.FormatDSN())
-		db, err := sql.Open("mysql", c.FormatDSN())
-		if err != nil {
-			return err
-		}
-		defer db.Close()
-		// See https://github.com/go-sql-driver/mysql?tab=readme-ov-file#important-settings.
-		db.SetConnMaxLifetime(time.Minute * 3)
-		db.SetMaxOpenConns(10)
-		db.SetMaxIdleConns(10)
-		mysqlClient := &internal.MySQL{DB: db}
+}
 
-		var listHandler http.HandlerFunc = internal.ListHandler(&internal.FindService{Selector: mysqlClient}, logger)
-		var createHandler http.HandlerFunc = internal.CreateHandler(&internal.RegisterService{Inserter: mysqlClient}, logger)
-		if basicAuthEnable {
-			slog.InfoContext(cmd.Context(), "Enable Basic Authentication")
-			listHandler = internal.BasicAuthMiddleware(basicAuthUsername, basicAuthPassword, listHandler)
-			createHandler = internal.BasicAuthMiddleware(basicAuthUsername, basicAuthPassword, createHandler)
-		}
+// withMySQL runs f with a client of the database opened by openDB.
+func withMySQL(f func(*internal.MySQL) error) error {
+	db, err := openDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return f(&internal.MySQL{DB: db})
+}
 
-		http.HandleFunc("GET /api/invoices", listHandler)
-		http.HandleFunc("POST /api/invoices", createHandler)
-		if err := http.ListenAndServe(":8080", nil); err != http.ErrServerClosed {
-			return err
-		}
-		return nil
-	},
+// loadCalendar returns the calendar of the --holidays file, or the embedded one without the flag.
+func loadCalendar() (*calendar.Calendar, error) {
+	if holidaysFile == "" {
+		return calendar.Default(), nil
+	}
+	return calendar.Load(holidaysFile)
+}
+
+// loadClock returns the system clock telling the time in the --timezone.
+func loadClock() (internal.Clock, error) {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %w", err)
+	}
+	return internal.SystemClock(loc), nil
 }
 
 func main() {
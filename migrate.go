@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateSteps       int
+	migrateSeed        bool
+	migrateLockTimeout time.Duration
+)
+
+func init() {
+	migrateCmd.PersistentFlags().DurationVar(&migrateLockTimeout, "lock-timeout", time.Minute, "How long to wait for another instance migrating the database")
+	migrateUpCmd.Flags().BoolVar(&migrateSeed, "seed", false, "Insert the sample data after migrating")
+	migrateDownCmd.Flags().IntVar(&migrateSteps, "steps", 1, "Number of migrations to revert")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd, migrateSeedCmd)
+	app.AddCommand(migrateCmd)
+}
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Migrate the database schema",
+}
+
+// withMigrator runs f with a migrator of the migrations embedded in the binary.
+func withMigrator(f func(*internal.Migrator) error) error {
+	migrations, err := internal.EmbeddedMigrations()
+	if err != nil {
+		return err
+	}
+	clock, err := loadClock()
+	if err != nil {
+		return err
+	}
+	return withMySQL(func(mysqlClient *internal.MySQL) error {
+		return f(&internal.Migrator{DB: mysqlClient.DB, Migrations: migrations, LockTimeout: migrateLockTimeout, Clock: clock})
+	})
+}
+
+func printMigrations(verb string, migrations []internal.Migration) {
+	for _, migration := range migrations {
+		fmt.Fprintf(os.Stdout, "%v %04d_%v\n", verb, migration.Version, migration.Name)
+	}
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *internal.Migrator) error {
+			done, err := m.Up(cmd.Context())
+			printMigrations("Applied", done)
+			if err != nil {
+				return err
+			}
+			if len(done) == 0 {
+				fmt.Fprintln(os.Stdout, "Schema is up to date")
+			}
+			if !migrateSeed {
+				return nil
+			}
+			if err := m.Seed(cmd.Context()); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stdout, "Inserted the sample data")
+			return nil
+		})
+	},
+}
+
+var migrateSeedCmd = &cobra.Command{
+	Use:   "seed",
+	Short: "Insert the sample data for development into the up-to-date schema",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *internal.Migrator) error {
+			if err := m.Seed(cmd.Context()); err != nil {
+				return err
+			}
+			fmt.Fprintln(os.Stdout, "Inserted the sample data")
+			return nil
+		})
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the last applied migrations",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if migrateSteps < 1 {
+			return fmt.Errorf("--steps must be positive, but got %d", migrateSteps)
+		}
+		return withMigrator(func(m *internal.Migrator) error {
+			done, err := m.Down(cmd.Context(), migrateSteps)
+			printMigrations("Reverted", done)
+			return err
+		})
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which migrations have been applied",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withMigrator(func(m *internal.Migrator) error {
+			statuses, err := m.Status(cmd.Context())
+			if err != nil {
+				return err
+			}
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+			for _, status := range statuses {
+				appliedAt := "pending"
+				if status.AppliedAt != nil {
+					appliedAt = status.AppliedAt.Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v\n", strconv.Itoa(status.Version), status.Name, appliedAt)
+			}
+			return w.Flush()
+		})
+	},
+}
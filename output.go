@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var outputFormat string
+
+// addOutputFlag lets the subcommands of cmd print their results in the format selected by --output.
+func addOutputFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "table", "Output format, one of [table, json, csv]")
+}
+
+// result is what a command prints. header and rows are printed as a table or CSV, and value as JSON
+// so that the JSON output is the same as the response of the API.
+type result struct {
+	header []string
+	rows   [][]string
+	value  any
+}
+
+func printResult(w io.Writer, r result) error {
+	switch outputFormat {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		header := make([]string, 0, len(r.header))
+		for _, h := range r.header {
+			header = append(header, strings.ToUpper(strings.ReplaceAll(h, "_", " ")))
+		}
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, row := range r.rows {
+			fmt.Fprintln(tw, strings.Join(row, "\t"))
+		}
+		return tw.Flush()
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.value)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write(r.header)
+		cw.WriteAll(r.rows)
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown output format %q", outputFormat)
+}
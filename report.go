@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportCompanyID string
+	reportFrom      string
+	reportTo        string
+	reportGroupBy   string
+)
+
+func init() {
+	reportCmd.Flags().StringVar(&reportCompanyID, "company-id", "", "ID of the company to report")
+	reportCmd.Flags().StringVar(&reportFrom, "from", "", "Earliest issue date of the invoices (YYYY-MM-DD)")
+	reportCmd.Flags().StringVar(&reportTo, "to", "", "Latest issue date of the invoices (YYYY-MM-DD)")
+	reportCmd.Flags().StringVar(&reportGroupBy, "group-by", "month", "Grouping of the invoices, one of [month, status, partner]")
+	reportCmd.MarkFlagRequired("company-id")
+	addOutputFlag(reportCmd)
+	app.AddCommand(reportCmd)
+}
+
+// ReportResponse is the JSON output of report.
+type ReportResponse struct {
+	Key            string `json:"key"`
+	Count          int    `json:"count"`
+	Amount         int    `json:"amount"`
+	Fee            int    `json:"fee"`
+	Tax            int    `json:"tax"`
+	WithholdingTax int    `json:"withholding_tax"`
+	Total          int    `json:"total"`
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Sum up the invoices of the company by month, status or business partner",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		q := internal.ReportQuery{CompanyID: reportCompanyID, GroupBy: reportGroupBy}
+		var err error
+		if reportFrom != "" {
+			if q.From, err = time.Parse(time.DateOnly, reportFrom); err != nil {
+				return fmt.Errorf("--from must be formatted as YYYY-MM-DD, but got %v", reportFrom)
+			}
+		}
+		if reportTo != "" {
+			if q.To, err = time.Parse(time.DateOnly, reportTo); err != nil {
+				return fmt.Errorf("--to must be formatted as YYYY-MM-DD, but got %v", reportTo)
+			}
+		}
+		return withMySQL(func(mysqlClient *internal.MySQL) error {
+			s := &internal.ReportService{Aggregator: mysqlClient}
+			report, err := s.Report(cmd.Context(), q)
+			if err != nil {
+				return err
+			}
+			rows := make([][]string, 0, len(report))
+			resp := make([]ReportResponse, 0, len(report))
+			for _, row := range report {
+				rows = append(rows, []string{row.Key, strconv.Itoa(row.Count), strconv.Itoa(row.Amount), strconv.Itoa(row.Fee), strconv.Itoa(row.Tax), strconv.Itoa(row.WithholdingTax), strconv.Itoa(row.Total)})
+				resp = append(resp, ReportResponse(row))
+			}
+			return printResult(os.Stdout, result{header: []string{reportGroupBy, "count", "amount", "fee", "tax", "withholding_tax", "total"}, rows: rows, value: resp})
+		})
+	},
+}
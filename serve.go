@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	authMode         string
+	basicAuthEnable  bool
+	jwtSecretFile    string
+	jwtJWKSFile      string
+	jwtIssuer        string
+	jwtAudience      string
+	serveMigrate     bool
+	idempotencyTTL   time.Duration
+	idempotencyLease time.Duration
+	batchMaxItems    int
+	pdfTemplate      string
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&authMode, "auth.mode", "none", "Authentication mode, one of [none, basic, jwt]")
+	serveCmd.Flags().BoolVar(&basicAuthEnable, "basic-auth.enable", false, "Enable basic authentication against the users table or not")
+	serveCmd.Flags().MarkDeprecated("basic-auth.enable", "use --auth.mode=basic instead")
+	serveCmd.Flags().StringVar(&jwtSecretFile, "auth.jwt.secret-file", "", "File containing the shared secret verifying HS256 tokens")
+	serveCmd.Flags().StringVar(&jwtJWKSFile, "auth.jwt.jwks-file", "", "JWKS file containing the keys verifying RS256 or HS256 tokens")
+	serveCmd.Flags().StringVar(&jwtIssuer, "auth.jwt.issuer", "", "Expected iss claim of the tokens")
+	serveCmd.Flags().StringVar(&jwtAudience, "auth.jwt.audience", "", "Expected aud claim of the tokens")
+	serveCmd.Flags().BoolVar(&serveMigrate, "migrate", false, "Apply the pending migrations before serving")
+	serveCmd.Flags().IntVar(&batchMaxItems, "batch.max-items", 500, "Maximum number of invoices created by a request to POST /api/invoices:batch")
+	serveCmd.Flags().DurationVar(&idempotencyTTL, "idempotency.ttl", 24*time.Hour, "How long the responses to requests with an Idempotency-Key header are replayed")
+	serveCmd.Flags().DurationVar(&idempotencyLease, "idempotency.lease", time.Minute, "How long a request with an Idempotency-Key header holds the key while being processed")
+	serveCmd.Flags().StringVar(&pdfTemplate, "pdf.template", "", "YAML template of the invoice PDFs overriding the default wording and font")
+	app.AddCommand(serveCmd)
+}
+
+// newAuthMiddleware returns the middleware authenticating requests in the mode selected by --auth.mode,
+// or nil when authentication is disabled.
+func newAuthMiddleware(mysqlClient *internal.MySQL, clock internal.Clock, logger *slog.Logger) (func(http.Handler) http.HandlerFunc, error) {
+	if basicAuthEnable {
+		authMode = "basic"
+	}
+	switch authMode {
+	case "none":
+		return nil, nil
+	case "basic":
+		authService := &internal.AuthService{UserSelector: mysqlClient}
+		return func(next http.Handler) http.HandlerFunc {
+			return internal.BasicAuthMiddleware(authService, next)
+		}, nil
+	case "jwt":
+		verifier, err := newJWTVerifier()
+		if err != nil {
+			return nil, err
+		}
+		verifier.Clock = clock
+		return func(next http.Handler) http.HandlerFunc {
+			return internal.BearerAuthMiddleware(verifier, logger, next)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown auth mode %q", authMode)
+}
+
+func newJWTVerifier() (*internal.JWTVerifier, error) {
+	switch {
+	case jwtJWKSFile != "" && jwtSecretFile != "":
+		return nil, errors.New("--auth.jwt.jwks-file and --auth.jwt.secret-file are mutually exclusive")
+	case jwtJWKSFile != "":
+		jwks, err := os.ReadFile(jwtJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		return internal.NewJWKSVerifier(jwks, jwtIssuer, jwtAudience)
+	case jwtSecretFile != "":
+		secret, err := os.ReadFile(jwtSecretFile)
+		if err != nil {
+			return nil, err
+		}
+		return internal.NewSecretVerifier(bytes.TrimSpace(secret), jwtIssuer, jwtAudience)
+	}
+	return nil, errors.New("--auth.jwt.jwks-file or --auth.jwt.secret-file is required in jwt mode")
+}
+
+// purgeIdempotencyKeys deletes the expired idempotency keys every hour. Expired keys are ignored anyway, so this
+// only keeps the table small.
+func purgeIdempotencyKeys(ctx context.Context, s *internal.IdempotencyService) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.Purge(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Failed to purge expired idempotency keys", "err", err)
+				continue
+			}
+			slog.InfoContext(ctx, "Purged expired idempotency keys", "deleted", n)
+		}
+	}
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Start the HTTP API server",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		mysqlClient := &internal.MySQL{DB: db}
+		clock, err := loadClock()
+		if err != nil {
+			return err
+		}
+		if err := mysqlClient.CheckBatchInsert(cmd.Context()); err != nil {
+			return err
+		}
+		if serveMigrate {
+			migrations, err := internal.EmbeddedMigrations()
+			if err != nil {
+				return err
+			}
+			migrator := &internal.Migrator{DB: db, Migrations: migrations, LockTimeout: time.Minute, Clock: clock}
+			done, err := migrator.Up(cmd.Context())
+			if err != nil {
+				return err
+			}
+			slog.InfoContext(cmd.Context(), "Migrated database", "applied", len(done))
+		}
+
+		findService := &internal.FindService{Selector: mysqlClient, IDSelector: mysqlClient, EachSelector: mysqlClient}
+		cal, err := loadCalendar()
+		if err != nil {
+			return err
+		}
+		registerService := &internal.RegisterService{Inserter: mysqlClient, BatchInserter: mysqlClient, RateSelector: mysqlClient, CompanySelector: mysqlClient, PartnerSelector: mysqlClient, Calendar: cal, Logger: logger}
+		statusService := &internal.StatusService{IDSelector: mysqlClient, StatusUpdater: mysqlClient}
+		companyService := &internal.CompanyService{Repository: mysqlClient}
+		partnerService := &internal.PartnerService{Repository: mysqlClient}
+		idempotencyService := &internal.IdempotencyService{Repository: mysqlClient, TTL: idempotencyTTL, Lease: idempotencyLease, Clock: clock}
+		go purgeIdempotencyKeys(cmd.Context(), idempotencyService)
+		documentService := &internal.DocumentService{IDFinder: findService, CompanySelector: mysqlClient, PartnerSelector: mysqlClient, BankAccountSelector: mysqlClient}
+		tmpl := internal.DefaultPDFTemplate()
+		if pdfTemplate != "" {
+			tmpl, err = internal.LoadPDFTemplate(pdfTemplate)
+			if err != nil {
+				return err
+			}
+		}
+		pdfRenderer, err := internal.NewPDFRenderer(tmpl)
+		if err != nil {
+			return err
+		}
+		// apiKey tells whether machine clients may call the route with an API key whatever the auth mode is.
+		type route struct {
+			handler    http.HandlerFunc
+			permission domain.Permission
+			apiKey     bool
+		}
+		routes := map[string]route{
+			"GET /api/invoices":               {internal.ExportHandler(findService, clock, logger, internal.ListHandler(findService, clock, logger)), domain.InvoicesRead, true},
+			"GET /api/invoices/{id}":          {internal.GetHandler(findService, logger), domain.InvoicesRead, true},
+			"GET /api/invoices/{id}/pdf":      {internal.PDFHandler(documentService, pdfRenderer, logger), domain.InvoicesRead, true},
+			"POST /api/invoices":              {internal.IdempotencyMiddleware(idempotencyService, logger, internal.CreateHandler(registerService, logger)), domain.InvoicesCreate, true},
+			"POST /api/invoices:batch":        {internal.IdempotencyMiddleware(idempotencyService, logger, internal.BatchCreateHandler(registerService, batchMaxItems, logger)), domain.InvoicesCreate, true},
+			"PATCH /api/invoices/{id}/status": {internal.StatusHandler(statusService, logger), domain.InvoicesChangeStatus, true},
+
+			"GET /api/companies":         {internal.CompanyListHandler(companyService, logger), domain.CompaniesRead, false},
+			"POST /api/companies":        {internal.CompanyCreateHandler(companyService, logger), domain.CompaniesCreate, false},
+			"GET /api/companies/{id}":    {internal.CompanyGetHandler(companyService, logger), domain.CompaniesRead, false},
+			"PUT /api/companies/{id}":    {internal.CompanyUpdateHandler(companyService, logger), domain.CompaniesManage, false},
+			"DELETE /api/companies/{id}": {internal.CompanyDeleteHandler(companyService, logger), domain.CompaniesManage, false},
+
+			"GET /api/companies/{company_id}/partners":                                    {internal.PartnerListHandler(partnerService, logger), domain.PartnersRead, false},
+			"POST /api/companies/{company_id}/partners":                                   {internal.PartnerCreateHandler(partnerService, logger), domain.PartnersManage, false},
+			"GET /api/companies/{company_id}/partners/{partner_id}":                       {internal.PartnerGetHandler(partnerService, logger), domain.PartnersRead, false},
+			"PUT /api/companies/{company_id}/partners/{partner_id}":                       {internal.PartnerUpdateHandler(partnerService, logger), domain.PartnersManage, false},
+			"DELETE /api/companies/{company_id}/partners/{partner_id}":                    {internal.PartnerDeleteHandler(partnerService, logger), domain.PartnersManage, false},
+			"GET /api/companies/{company_id}/partners/{partner_id}/bank-accounts":         {internal.BankAccountListHandler(partnerService, logger), domain.PartnersRead, false},
+			"POST /api/companies/{company_id}/partners/{partner_id}/bank-accounts":        {internal.BankAccountCreateHandler(partnerService, logger), domain.PartnersManage, false},
+			"DELETE /api/companies/{company_id}/partners/{partner_id}/bank-accounts/{id}": {internal.BankAccountDeleteHandler(partnerService, logger), domain.PartnersManage, false},
+		}
+		authenticate, err := newAuthMiddleware(mysqlClient, clock, logger)
+		if err != nil {
+			return err
+		}
+		if authenticate != nil {
+			slog.InfoContext(cmd.Context(), "Enable authentication", "mode", authMode)
+		} else {
+			authenticate = func(next http.Handler) http.HandlerFunc { return next.ServeHTTP }
+		}
+		apiKeyService := &internal.APIKeyService{Repository: mysqlClient, Clock: clock}
+		for pattern, route := range routes {
+			authorized := internal.PolicyMiddleware(route.permission, logger, route.handler)
+			handler := authenticate(authorized)
+			if route.apiKey {
+				handler = internal.APIKeyMiddleware(apiKeyService, logger, authorized, handler)
+			}
+			http.HandleFunc(pattern, handler)
+		}
+		if err := http.ListenAndServe(":8080", nil); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	},
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ryuheeeei/super-invoicer/internal"
+	"github.com/Ryuheeeei/super-invoicer/internal/domain"
+	"github.com/spf13/cobra"
+)
+
+var (
+	userCompanyID string
+	userUsername  string
+	userPassword  string
+	userRole      string
+)
+
+func init() {
+	userAddCmd.Flags().StringVar(&userCompanyID, "company-id", "", "ID of the company the user belongs to")
+	userAddCmd.Flags().StringVar(&userUsername, "username", "", "Username used for basic authentication")
+	userAddCmd.Flags().StringVar(&userPassword, "password", "", "Password of the user. Read from stdin when omitted")
+	userAddCmd.Flags().StringVar(&userRole, "role", string(domain.Viewer), "Role of the user, one of [viewer, accountant, approver, admin]")
+	userAddCmd.MarkFlagRequired("company-id")
+	userAddCmd.MarkFlagRequired("username")
+	userCmd.AddCommand(userAddCmd)
+	app.AddCommand(userCmd)
+}
+
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+}
+
+var userAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add a user who can log in with basic authentication",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		password := userPassword
+		if password == "" {
+			line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+			if err != nil && line == "" {
+				return fmt.Errorf("failed to read password from stdin: %w", err)
+			}
+			password = strings.TrimRight(line, "\r\n")
+		}
+
+		db, err := openDB()
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+
+		userService := &internal.UserService{Inserter: &internal.MySQL{DB: db}}
+		user, err := userService.Add(cmd.Context(), userCompanyID, userUsername, password, domain.Role(userRole))
+		if errors.Is(err, internal.ErrUserExists) {
+			return fmt.Errorf("user %v already exists", userUsername)
+		}
+		if errors.Is(err, internal.ErrCompanyNotFound) {
+			return fmt.Errorf("company %v doesn't exist", userCompanyID)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Added user %v (id: %v) to company %v as %v\n", user.Username, user.UserID, user.CompanyID, user.Role)
+		return nil
+	},
+}